@@ -0,0 +1,227 @@
+package main
+
+import (
+	"backend/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type CollectionPayload struct {
+	Name string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CollectionMoviesPayload struct {
+	MovieIDs []int `json:"movie_ids"`
+}
+
+// collectionの操作で返すエラーのステータスコード
+func collectionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrDuplicateCollection), errors.Is(err, models.ErrMovieInCollection):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (app *application) getAllCollections(w http.ResponseWriter, r *http.Request) {
+	collections, err := app.models.DB.Collections()
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, collections, "collections")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// collectionとそのmovieを順番どおりに返す
+func (app *application) getOneCollection(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	collection, err := app.models.DB.GetCollection(id)
+	if err != nil {
+		app.errorJSON(w, err, collectionErrorStatus(err))
+		return
+	}
+
+	err = app.prepareMovies(r, collection.Movies...)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, collection, "collection")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) createCollection(w http.ResponseWriter, r *http.Request) {
+	var payload CollectionPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	collection := models.Collection{
+		Name: payload.Name,
+		Description: payload.Description,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	id, err := app.models.DB.InsertCollection(collection)
+	if err != nil {
+		app.errorJSON(w, err, collectionErrorStatus(err))
+		return
+	}
+
+	created, err := app.models.DB.GetCollection(id)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, created, "collection")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) updateCollection(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload CollectionPayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	collection := models.Collection{
+		ID: id,
+		Name: payload.Name,
+		Description: payload.Description,
+		UpdatedAt: time.Now(),
+	}
+
+	err = app.models.DB.UpdateCollection(collection)
+	if err != nil {
+		app.errorJSON(w, err, collectionErrorStatus(err))
+		return
+	}
+
+	ok := jsonResp{
+		OK: true,
+		ID: id,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// collectionを削除する(movieは削除しない)
+func (app *application) deleteCollection(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	err = app.models.DB.DeleteCollection(id)
+	if err != nil {
+		app.errorJSON(w, err, collectionErrorStatus(err))
+		return
+	}
+
+	ok := jsonResp{
+		OK: true,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// collectionのmovieをmovie_idsの順で置き換える
+func (app *application) setCollectionMovies(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload CollectionMoviesPayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	if payload.MovieIDs == nil {
+		app.errorJSON(w, errors.New("movie_ids is required"))
+		return
+	}
+
+	err = app.models.DB.SetCollectionMovies(id, payload.MovieIDs)
+	if err != nil {
+		app.errorJSON(w, err, collectionErrorStatus(err))
+		return
+	}
+
+	collection, err := app.models.DB.GetCollection(id)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.prepareMovies(r, collection.Movies...)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, collection, "collection")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"testing"
+)
+
+func TestCollections(t *testing.T) {
+	app, _ := newTestApplication(t)
+	token := testToken(t, 10)
+
+	status, resp := doRequest(t, app, http.MethodPost, "/v1/admin/collections", `{"name":" Favourites ","description":"Picked by staff"}`, token)
+	if status != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d", status, http.StatusCreated)
+	}
+	var collection models.Collection
+	decode(t, resp["collection"], &collection)
+	if collection.ID == 0 || collection.Name != "Favourites" || collection.MovieCount != 0 {
+		t.Errorf("created collection = %+v", collection)
+	}
+	id := itoa(collection.ID)
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/collections", `{"name":"favourites"}`, token)
+	if status != http.StatusConflict {
+		t.Errorf("duplicate name: status = %d, want %d", status, http.StatusConflict)
+	}
+
+	status, resp = doRequest(t, app, http.MethodPut, "/v1/admin/collections/"+id+"/movies", `{"movie_ids":[3,1,2]}`, token)
+	if status != http.StatusOK {
+		t.Fatalf("set movies: status = %d, want %d", status, http.StatusOK)
+	}
+	decode(t, resp["collection"], &collection)
+	if len(collection.Movies) != 3 || collection.Movies[0].ID != 3 || collection.Movies[2].ID != 2 {
+		t.Errorf("collection movies = %+v", collection.Movies)
+	}
+
+	bad := map[string]int{
+		`{"movie_ids":[1,1]}`: http.StatusBadRequest,
+		`{"movie_ids":[999]}`: http.StatusBadRequest,
+		`{}`: http.StatusBadRequest,
+	}
+	for body, want := range bad {
+		status, _ := doRequest(t, app, http.MethodPut, "/v1/admin/collections/"+id+"/movies", body, token)
+		if status != want {
+			t.Errorf("%s: status = %d, want %d", body, status, want)
+		}
+	}
+
+	// 別のcollectionに入っているmovieは追加できない
+	status, resp = doRequest(t, app, http.MethodPost, "/v1/admin/collections", `{"name":"Others"}`, token)
+	if status != http.StatusCreated {
+		t.Fatalf("create second: status = %d, want %d", status, http.StatusCreated)
+	}
+	var other models.Collection
+	decode(t, resp["collection"], &other)
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/collections/"+itoa(other.ID)+"/movies", `{"movie_ids":[1]}`, token)
+	if status != http.StatusConflict {
+		t.Errorf("movie in another collection: status = %d, want %d", status, http.StatusConflict)
+	}
+
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/movie/1", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("get movie: status = %d, want %d", status, http.StatusOK)
+	}
+	var movie models.Movie
+	decode(t, resp["movie"], &movie)
+	c := movie.Collection
+	if c == nil || c.ID != collection.ID || c.Position != 2 || c.Previous == nil || c.Previous.ID != 3 || c.Next == nil || c.Next.ID != 2 {
+		t.Errorf("collection of movie 1 = %+v", c)
+	}
+
+	// ゴミ箱のmovieは飛ばして前後をつなぐ
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/admin/deletemovie/1", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("delete movie: status = %d, want %d", status, http.StatusOK)
+	}
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/movie/3", "", "")
+	movie = models.Movie{}
+	decode(t, resp["movie"], &movie)
+	c = movie.Collection
+	if c == nil || c.Position != 1 || c.Previous != nil || c.Next == nil || c.Next.ID != 2 {
+		t.Errorf("collection of movie 3 after delete = %+v", c)
+	}
+
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/collections", "", "")
+	var collections []models.Collection
+	decode(t, resp["collections"], &collections)
+	if len(collections) != 2 || collections[0].Name != "Favourites" || collections[0].MovieCount != 2 {
+		t.Errorf("collections = %+v", collections)
+	}
+
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/admin/collections/"+id, "", token)
+	if status != http.StatusOK {
+		t.Errorf("delete: status = %d, want %d", status, http.StatusOK)
+	}
+	status, _ = doRequest(t, app, http.MethodGet, "/v1/collections/"+id, "", "")
+	if status != http.StatusNotFound {
+		t.Errorf("get deleted: status = %d, want %d", status, http.StatusNotFound)
+	}
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/movie/3", "", "")
+	movie = models.Movie{}
+	decode(t, resp["movie"], &movie)
+	if movie.Collection != nil {
+		t.Errorf("collection of movie 3 after deleting the collection = %+v", movie.Collection)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"backend/models"
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 出力する列(先頭はインポートと同じ列なので、出力したCSVをそのままインポートできる)
+var exportColumns = append(append([]string{}, importColumns...), "year", "review_count", "review_score", "poster_url", "created_at", "updated_at")
+
+// Content-Typeと拡張子
+var exportContentTypes = map[string]string{
+	"csv": "text/csv; charset=utf-8",
+	"json": "application/json",
+	"ndjson": "application/x-ndjson",
+}
+
+// JSONとNDJSONの1件(importRecordで読み込める形にする)
+type exportRecord struct {
+	ID int `json:"id"`
+	Title string `json:"title"`
+	Description string `json:"description"`
+	ReleaseDate string `json:"release_date"`
+	Runtime int `json:"runtime"`
+	Rating int `json:"rating"`
+	MPAARating string `json:"mpaa_rating"`
+	Genres []string `json:"genres"`
+	Year int `json:"year"`
+	ReviewCount int `json:"review_count"`
+	ReviewScore float64 `json:"review_score"`
+	PosterURL string `json:"poster_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newExportRecord(movie *models.Movie) exportRecord {
+	genres := []string{}
+	for _, name := range movie.MovieGenre {
+		genres = append(genres, name)
+	}
+	sort.Strings(genres)
+
+	return exportRecord{
+		ID: movie.ID,
+		Title: movie.Title,
+		Description: movie.Description,
+		ReleaseDate: movie.ReleaseDate.Format("2006-01-02"),
+		Runtime: movie.Runtime,
+		Rating: movie.Rating,
+		MPAARating: movie.MPAARating,
+		Genres: genres,
+		Year: movie.Year,
+		ReviewCount: movie.ReviewCount,
+		ReviewScore: movie.ReviewScore,
+		PosterURL: posterURL(movie.Poster),
+		CreatedAt: movie.CreatedAt,
+		UpdatedAt: movie.UpdatedAt,
+	}
+}
+
+// CSVの1行(exportColumnsの順)
+func (rec exportRecord) csv() []string {
+	return []string{
+		strconv.Itoa(rec.ID),
+		rec.Title,
+		rec.Description,
+		rec.ReleaseDate,
+		strconv.Itoa(rec.Runtime),
+		strconv.Itoa(rec.Rating),
+		rec.MPAARating,
+		strings.Join(rec.Genres, "|"),
+		strconv.Itoa(rec.Year),
+		strconv.Itoa(rec.ReviewCount),
+		strconv.FormatFloat(rec.ReviewScore, 'f', -1, 64),
+		rec.PosterURL,
+		rec.CreatedAt.UTC().Format(time.RFC3339),
+		rec.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// 形式ごとにmovieを書き込む
+type exportWriter interface {
+	Write(rec exportRecord) error
+	Close() error
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (e *csvExportWriter) Write(rec exportRecord) error {
+	return e.w.Write(rec.csv())
+}
+
+func (e *csvExportWriter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonなら配列、ndjsonなら1行に1件
+type jsonExportWriter struct {
+	w io.Writer
+	array bool
+	n int
+}
+
+func (e *jsonExportWriter) Write(rec exportRecord) error {
+	js, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	if e.array {
+		sep := ",\n"
+		if e.n == 0 {
+			sep = "[\n"
+		}
+		_, err = io.WriteString(e.w, sep)
+		if err != nil {
+			return err
+		}
+	} else {
+		js = append(js, '\n')
+	}
+	e.n++
+
+	_, err = e.w.Write(js)
+	return err
+}
+
+func (e *jsonExportWriter) Close() error {
+	if !e.array {
+		return nil
+	}
+
+	end := "\n]\n"
+	if e.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+func newExportWriter(w io.Writer, format string) (exportWriter, error) {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		err := cw.Write(exportColumns)
+		if err != nil {
+			return nil, err
+		}
+		return &csvExportWriter{w: cw}, nil
+	case "json":
+		return &jsonExportWriter{w: w, array: true}, nil
+	}
+	return &jsonExportWriter{w: w}, nil
+}
+
+// クライアントがgzipを受け付けるか
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		enc = strings.TrimSpace(strings.SplitN(enc, ";", 2)[0])
+		if enc == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// 一覧と同じ絞り込み・並び替えのmovieをすべて、読み込みながら書き出す(?format=csv|json|ndjson)
+func (app *application) exportMovies(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		app.errorJSON(w, fmt.Errorf("format must be one of %s", strings.Join(importFormats, ", ")))
+		return
+	}
+
+	filter, err := app.readMovieFilter(r, "format")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	if r.URL.Query().Get("limit") != "" || filter.Cursor != "" {
+		app.errorJSON(w, fmt.Errorf("export returns every matching movie; limit and cursor are not supported"))
+		return
+	}
+
+	// 件数が多いとサーバーのWriteTimeoutを超えるので、このリクエストだけ書き込みの期限をなくす
+	err = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="movies.%s"`, format))
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	var out io.Writer = w
+	var gz *gzip.Writer
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz = gzip.NewWriter(w)
+		out = gz
+	}
+
+	// 小さな書き込みをまとめる(メモリはバッファの分だけ)
+	buf := bufio.NewWriterSize(out, 32<<10)
+
+	err = app.writeExport(r, buf, format, filter)
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		// ヘッダーは送信済みなのでステータスコードは変えられない
+		// 途中で切れたファイルが正常に見えないように、レスポンスを終わらせずに接続を切る
+		app.logger.Println("export:", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// 絞り込んだmovieをformatの形式でwに書き出す
+func (app *application) writeExport(r *http.Request, w io.Writer, format string, filter models.MovieFilter) error {
+	ew, err := newExportWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	err = app.models.DB.ExportMovies(r.Context(), filter, func(movie *models.Movie) error {
+		return ew.Write(newExportRecord(movie))
+	})
+	if err != nil {
+		return err
+	}
+
+	return ew.Close()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"backend/models"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// エクスポートのリクエストを送ってレスポンスを返す
+func exportRequest(t *testing.T, app *application, url string, gzipped bool) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Authorization", "Bearer "+testToken(t, 1))
+	if gzipped {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestExportMovies(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	rr := exportRequest(t, app, "/v1/admin/export?format=csv&genres=2&sort=year", false)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("csv: status = %d: %s", rr.Code, rr.Body.String())
+	}
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ヘッダーとCrimeのmovie3件
+	if len(records) != 4 || strings.Join(records[0], ",") != strings.Join(exportColumns, ",") {
+		t.Fatalf("csv = %v", records)
+	}
+	if records[1][1] != "The Godfather" || records[1][7] != "Crime|Drama" {
+		t.Errorf("first row = %v", records[1])
+	}
+
+	rr = exportRequest(t, app, "/v1/admin/export?format=json", false)
+	var movies []exportRecord
+	err = json.Unmarshal(rr.Body.Bytes(), &movies)
+	if err != nil {
+		t.Fatalf("json: %v: %s", err, rr.Body.String())
+	}
+	if len(movies) != 4 || movies[0].Title != "American Psycho" {
+		t.Errorf("json = %+v", movies)
+	}
+
+	rr = exportRequest(t, app, "/v1/admin/export?format=json&year_min=2050", false)
+	if strings.TrimSpace(rr.Body.String()) != "[]" {
+		t.Errorf("empty json = %q", rr.Body.String())
+	}
+
+	rr = exportRequest(t, app, "/v1/admin/export?format=ndjson", true)
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q", rr.Header().Get("Content-Encoding"))
+	}
+	gz, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(body)), "\n"); len(lines) != 4 {
+		t.Errorf("ndjson lines = %d, want 4", len(lines))
+	}
+
+	rr = exportRequest(t, app, "/v1/admin/export?format=xml", false)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: status = %d, want 400", rr.Code)
+	}
+	rr = exportRequest(t, app, "/v1/admin/export?format=csv&limit=2", false)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("limit: status = %d, want 400", rr.Code)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	app, _ := newTestApplication(t)
+	token := testToken(t, 1)
+
+	rr := exportRequest(t, app, "/v1/admin/export?format=csv", false)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("export: status = %d", rr.Code)
+	}
+
+	status, resp := doRequest(t, app, http.MethodPost, "/v1/admin/import?format=csv&dry_run=1", rr.Body.String(), token)
+	if status != http.StatusOK {
+		t.Fatalf("import: status = %d", status)
+	}
+	var report importReport
+	decode(t, resp["import"], &report)
+	if report.Updated != 4 || report.Created != 0 || report.Failed != 0 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+// エクスポートの途中で失敗したり、遅れたりするstore
+type slowExportStore struct {
+	models.MovieStore
+	delay time.Duration
+	failAfter int
+}
+
+func (s slowExportStore) ExportMovies(ctx context.Context, filter models.MovieFilter, fn func(*models.Movie) error) error {
+	n := 0
+	return s.MovieStore.ExportMovies(ctx, filter, func(movie *models.Movie) error {
+		if s.failAfter > 0 && n == s.failAfter {
+			return errors.New("connection reset")
+		}
+		n++
+		time.Sleep(s.delay)
+		return fn(movie)
+	})
+}
+
+// 本物のサーバーでエクスポートのリクエストを送る
+func exportOverHTTP(t *testing.T, app *application, writeTimeout time.Duration) (*http.Response, []byte, error) {
+	t.Helper()
+
+	ts := httptest.NewUnstartedServer(app.routes())
+	ts.Config.WriteTimeout = writeTimeout
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/admin/export?format=ndjson", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken(t, 1))
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+func TestExportIgnoresWriteTimeout(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.models.DB = slowExportStore{MovieStore: app.models.DB, delay: 20 * time.Millisecond}
+
+	resp, body, err := exportOverHTTP(t, app, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if lines := strings.Count(string(body), "\n"); lines != 4 {
+		t.Errorf("got %d lines, want 4", lines)
+	}
+}
+
+func TestExportAbortsOnError(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.models.DB = slowExportStore{MovieStore: app.models.DB, failAfter: 2}
+
+	_, body, err := exportOverHTTP(t, app, 0)
+	if err == nil {
+		t.Fatalf("export completed with a truncated body %q", body)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"backend/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 一覧で受け付けるクエリパラメータ
+var movieFilterParams = []string{
+	"limit",
+	"cursor",
+	"sort",
+	"genres",
+	"genre_match",
+	"year_min",
+	"year_max",
+	"released_after",
+	"released_before",
+	"runtime_min",
+	"runtime_max",
+	"rating_min",
+	"mpaa_rating",
+}
+
+// クエリパラメータから絞り込み・並び替え・ページ指定を読み込む(extraはハンドラーごとに追加で受け付けるパラメータ)
+func (app *application) readMovieFilter(r *http.Request, extra ...string) (models.MovieFilter, error) {
+	qs := r.URL.Query()
+
+	filter := models.MovieFilter{
+		Limit: models.DefaultPageLimit,
+		Cursor: qs.Get("cursor"),
+		Sort: qs.Get("sort"),
+		GenreMatch: qs.Get("genre_match"),
+	}
+
+	// 知らないパラメータはエラーにする
+	for key := range qs {
+		if !containsParam(movieFilterParams, key) && !containsParam(extra, key) {
+			allowed := append(append([]string{}, movieFilterParams...), extra...)
+			return filter, fmt.Errorf("unknown query parameter %q (allowed: %s)", key, strings.Join(allowed, ", "))
+		}
+	}
+
+	if qs.Get("limit") != "" {
+		limit, err := strconv.Atoi(qs.Get("limit"))
+		if err != nil || limit < 1 || limit > models.MaxPageLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", models.MaxPageLimit)
+		}
+		filter.Limit = limit
+	}
+
+	ints := []struct {
+		name string
+		dst *int
+	}{
+		{"year_min", &filter.YearMin},
+		{"year_max", &filter.YearMax},
+		{"runtime_min", &filter.RuntimeMin},
+		{"runtime_max", &filter.RuntimeMax},
+		{"rating_min", &filter.RatingMin},
+	}
+	for _, p := range ints {
+		v := qs.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("%s must be a non-negative integer", p.name)
+		}
+		*p.dst = n
+	}
+
+	dates := []struct {
+		name string
+		dst *time.Time
+	}{
+		{"released_after", &filter.ReleasedAfter},
+		{"released_before", &filter.ReleasedBefore},
+	}
+	for _, p := range dates {
+		v := qs.Get(p.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be a date in YYYY-MM-DD format", p.name)
+		}
+		*p.dst = d
+	}
+
+	for _, v := range splitParam(qs.Get("genres")) {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, fmt.Errorf("genres must be a comma separated list of genre ids")
+		}
+		filter.GenreIDs = append(filter.GenreIDs, id)
+	}
+
+	filter.MPAARatings = splitParam(qs.Get("mpaa_rating"))
+
+	err := filter.Validate()
+	if err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// "a, b,,c" -> ["a", "b", "c"]
+func splitParam(v string) []string {
+	var values []string
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func containsParam(params []string, key string) bool {
+	for _, p := range params {
+		if p == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"backend/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type GenrePayload struct {
+	GenreName string `json:"genre_name"`
+}
+
+// genreの操作で返すエラーのステータスコード
+func genreErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrDuplicateGenre), errors.Is(err, models.ErrGenreInUse):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (app *application) createGenre(w http.ResponseWriter, r *http.Request) {
+	var payload GenrePayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	genre := models.Genre{
+		GenreName: strings.TrimSpace(payload.GenreName),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	genre.ID, err = app.models.DB.InsertGenre(genre)
+	if err != nil {
+		app.errorJSON(w, err, genreErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, genre, "genre")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) updateGenre(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload GenrePayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	genre := models.Genre{
+		ID: id,
+		GenreName: strings.TrimSpace(payload.GenreName),
+		UpdatedAt: time.Now(),
+	}
+
+	err = app.models.DB.UpdateGenre(genre)
+	if err != nil {
+		app.errorJSON(w, err, genreErrorStatus(err))
+		return
+	}
+
+	ok := jsonResp{
+		OK: true,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// 使用中のgenreは?reassign_to=<genre id>を指定した場合のみ削除する
+func (app *application) deleteGenre(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	reassignTo := 0
+	if v := r.URL.Query().Get("reassign_to"); v != "" {
+		reassignTo, err = strconv.Atoi(v)
+		if err != nil {
+			app.errorJSON(w, errors.New("invalid reassign_to parameter"))
+			return
+		}
+	}
+
+	err = app.models.DB.DeleteGenre(id, reassignTo)
+	if err != nil {
+		app.errorJSON(w, err, genreErrorStatus(err))
+		return
+	}
+	app.invalidateSimilar()
+
+	ok := jsonResp{
+		OK: true,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"testing"
+)
+
+func TestGenreAdmin(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	status, resp := doRequest(t, app, http.MethodPost, "/v1/admin/genres", `{"genre_name":" Horror "}`, token)
+	if status != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d", status, http.StatusCreated)
+	}
+	var genre models.Genre
+	decode(t, resp["genre"], &genre)
+	if genre.ID == 0 || genre.GenreName != "Horror" {
+		t.Errorf("created genre = %+v", genre)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/genres", `{"genre_name":"drama"}`, token)
+	if status != http.StatusConflict {
+		t.Errorf("duplicate create: status = %d, want %d", status, http.StatusConflict)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/genres/1", `{"genre_name":"Crime"}`, token)
+	if status != http.StatusConflict {
+		t.Errorf("duplicate rename: status = %d, want %d", status, http.StatusConflict)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/genres/1", `{"genre_name":" Dramas "}`, token)
+	if status != http.StatusOK {
+		t.Errorf("rename: status = %d, want %d", status, http.StatusOK)
+	}
+	genres, _ := store.GenresAll()
+	for _, g := range genres {
+		if g.ID == 1 && g.GenreName != "Dramas" {
+			t.Errorf("renamed genre = %q, want %q", g.GenreName, "Dramas")
+		}
+	}
+
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/genres/999", `{"genre_name":"Nothing"}`, token)
+	if status != http.StatusNotFound {
+		t.Errorf("rename missing: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	// 1 = Drama(使用中)
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/admin/genres/1", "", token)
+	if status != http.StatusConflict {
+		t.Errorf("delete in use: status = %d, want %d", status, http.StatusConflict)
+	}
+
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/admin/genres/1?reassign_to=1", "", token)
+	if status != http.StatusBadRequest {
+		t.Errorf("reassign to itself: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	// Dramaの3本を2 = Crimeに付け替える(うち2本はすでにCrime)
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/admin/genres/1?reassign_to=2", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("delete with reassign: status = %d, want %d", status, http.StatusOK)
+	}
+
+	movies, err := store.All(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movies) != 4 {
+		t.Errorf("got %d crime movies after reassign, want 4", len(movies))
+	}
+
+	movie, err := store.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movie.MovieGenre) != 1 {
+		t.Errorf("genres of movie 1 = %v, want only Crime", movie.MovieGenre)
+	}
+
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/admin/genres/"+itoa(genre.ID), "", token)
+	if status != http.StatusOK {
+		t.Errorf("delete unused: status = %d, want %d", status, http.StatusOK)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"backend/models"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,13 +26,21 @@ var fields = graphql.Fields{
 			"id": &graphql.ArgumentConfig{
 				Type: graphql.Int,
 			},
+			"lang": &graphql.ArgumentConfig{
+				Type: graphql.String,
+				Description: "Locale of title, description and genres (e.g. ja); defaults to Accept-Language",
+			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			id, ok := p.Args["id"].(int)
 			if ok {
 				for _, movie := range movies {
 					if movie.ID == id {
-						return movie, nil
+						localized, err := localizeResult(p, movie)
+						if err != nil {
+							return nil, err
+						}
+						return localized[0], nil
 					}
 				}
 			}
@@ -41,8 +50,14 @@ var fields = graphql.Fields{
 	"list": &graphql.Field{
 		Type: graphql.NewList(movieType),
 		Description: "Get all movies",
+		Args: graphql.FieldConfigArgument{
+			"lang": &graphql.ArgumentConfig{
+				Type: graphql.String,
+				Description: "Locale of title, description and genres (e.g. ja); defaults to Accept-Language",
+			},
+		},
 		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
-			return movies, nil
+			return localizeResult(params, movies...)
 		},
 	},
 	"search": &graphql.Field{
@@ -52,15 +67,24 @@ var fields = graphql.Fields{
 			"titleContains": &graphql.ArgumentConfig{
 				Type: graphql.String,
 			},
+			"lang": &graphql.ArgumentConfig{
+				Type: graphql.String,
+				Description: "Locale of title, description and genres (e.g. ja); defaults to Accept-Language",
+			},
 		},
 		Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 			var theList []*models.Movie
 			search, ok := params.Args["titleContains"].(string)
 			if ok {
-				for _, currentMovie := range movies {
-					if strings.Contains(currentMovie.Title, search) {
+				// 元のタイトルと翻訳したタイトルのどちらでも検索できる
+				localized, err := localizeResult(params, movies...)
+				if err != nil {
+					return nil, err
+				}
+				for i, currentMovie := range movies {
+					if strings.Contains(currentMovie.Title, search) || strings.Contains(localized[i].Title, search) {
 						log.Println("Found one")
-						theList = append(theList, currentMovie)
+						theList = append(theList, localized[i])
 					}
 				}
 			}
@@ -69,6 +93,21 @@ var fields = graphql.Fields{
 	},
 }
 
+// リゾルバーからmovieを翻訳するための関数(リクエストごとにcontextで渡す)
+type graphqlLocalizer func(lang string, movies []*models.Movie) ([]*models.Movie, error)
+
+const localizerContextKey = contextKey("localizer")
+
+// lang引数(なければAccept-Language)の言語に翻訳したmovieのコピーを返す
+func localizeResult(p graphql.ResolveParams, movies ...*models.Movie) ([]*models.Movie, error) {
+	localize, ok := p.Context.Value(localizerContextKey).(graphqlLocalizer)
+	if !ok {
+		return movies, nil
+	}
+	lang, _ := p.Args["lang"].(string)
+	return localize(lang, movies)
+}
+
 var movieType = graphql.NewObject(
 	graphql.ObjectConfig{
 		Name: "Movie",
@@ -82,6 +121,9 @@ var movieType = graphql.NewObject(
 			"description": &graphql.Field{
 				Type: graphql.String,
 			},
+			"locale": &graphql.Field{
+				Type: graphql.String,
+			},
 			"year": &graphql.Field{
 				Type: graphql.Int,
 			},
@@ -97,15 +139,21 @@ var movieType = graphql.NewObject(
 			"mpaa_rating": &graphql.Field{
 				Type: graphql.String,
 			},
+			"review_count": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"review_score": &graphql.Field{
+				Type: graphql.Float,
+			},
 			"created_at": &graphql.Field{
 				Type: graphql.DateTime,
 			},
 			"updated_at": &graphql.Field{
 				Type: graphql.DateTime,
 			},
-			// "poster": &graphql.Field{
-			// 	Type: graphql.String,
-			// },
+			"poster_url": &graphql.Field{
+				Type: graphql.String,
+			},
 		},
 	},
 )
@@ -113,6 +161,9 @@ var movieType = graphql.NewObject(
 func (app *application) moviesGraphQL(w http.ResponseWriter, r * http.Request) {
 	// DBから全データを取得する
 	movies, _ = app.models.DB.All()
+	for _, movie := range movies {
+		movie.PosterURL = posterURL(movie.Poster)
+	}
 
 	// リクエストボディを読み込んでクエリをつくる
 	q, _ := io.ReadAll(r.Body)
@@ -130,7 +181,33 @@ func (app *application) moviesGraphQL(w http.ResponseWriter, r * http.Request) {
 		return
 	}
 	
-	params := graphql.Params{Schema: schema, RequestString: query}
+	// 共有しているmoviesを書き換えないように、コピーを翻訳する
+	var localize graphqlLocalizer = func(lang string, list []*models.Movie) ([]*models.Movie, error) {
+		locales := parseAcceptLanguage(r.Header.Get("Accept-Language"))
+		if lang != "" {
+			var err error
+			locales, err = parseLang(lang)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		copies := make([]*models.Movie, len(list))
+		for i, movie := range list {
+			c := *movie
+			c.MovieGenre = make(map[int]string)
+			for k, v := range movie.MovieGenre {
+				c.MovieGenre[k] = v
+			}
+			copies[i] = &c
+		}
+
+		err := app.models.DB.LocalizeMovies(copies, locales)
+		return copies, err
+	}
+	ctx := context.WithValue(r.Context(), localizerContextKey, localize)
+
+	params := graphql.Params{Schema: schema, RequestString: query, Context: ctx}
 	resp := graphql.Do(params)
 	if len(resp.Errors) > 0 {
 		app.errorJSON(w, fmt.Errorf("failed: %+v", resp.Errors))
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// インポートで受け付けるリクエストボディの最大サイズ
+const maxImportBytes = 32 << 20
+
+// Content-Typeとインポートの形式
+var importContentTypes = map[string]string{
+	"text/csv": "csv",
+	"application/json": "json",
+	"application/x-ndjson": "ndjson",
+	"application/ndjson": "ndjson",
+}
+
+// ボディのCSV・JSON・NDJSONのmovieをまとめて追加・更新する(?format=で形式、?dry_run=trueで確認のみ)
+func (app *application) importMovies(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	format := qs.Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		format = importContentTypes[mediaType]
+	}
+	if !containsParam(importFormats, format) {
+		app.errorJSON(w, fmt.Errorf("pass ?format= or a Content-Type for one of %s", strings.Join(importFormats, ", ")), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	dryRun := false
+	if qs.Get("dry_run") != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(qs.Get("dry_run"))
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("dry_run must be true or false"))
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	rows, failed, err := parseImport(r.Body, format)
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			app.errorJSON(w, fmt.Errorf("imports must not be larger than %d bytes", maxImportBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		app.errorJSON(w, err)
+		return
+	}
+
+	report := runImportRows(app.models.DB, rows, failed, models.ImportOptions{DryRun: dryRun, UserID: userIDFromContext(r)})
+	if !dryRun {
+		app.invalidateSimilar()
+	}
+
+	err = app.writeJSON(w, http.StatusOK, report, "import")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"testing"
+)
+
+func TestImportMoviesCSV(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	body := "title,release_date,runtime,rating,mpaa_rating,genres\n" +
+		"Inception,2010-07-16,148,5,PG-13,Action|sci-fi\n" +
+		"The Godfather,1972-03-24,177,5,R,\n" +
+		"Memento,2000-09-05,abc,4,R,\n" +
+		"Heat,1995-12-15,170,4,R,Heist\n" +
+		"inception,2010-07-16,149,5,PG-13,\n"
+
+	// dry runでは書き込まない
+	status, resp := doRequest(t, app, http.MethodPost, "/v1/admin/import?format=csv&dry_run=true", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("dry run: status = %d", status)
+	}
+	var report importReport
+	decode(t, resp["import"], &report)
+	if !report.DryRun || report.Created != 1 || report.Updated != 2 || report.Failed != 2 {
+		t.Errorf("dry run report = %+v", report)
+	}
+	if _, err := store.Get(5); err == nil {
+		t.Fatal("dry run created a movie")
+	}
+
+	status, resp = doRequest(t, app, http.MethodPost, "/v1/admin/import?format=csv", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("import: status = %d", status)
+	}
+	decode(t, resp["import"], &report)
+	if report.Created != 1 || report.Updated != 2 || report.Failed != 2 || len(report.Rows) != 5 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	want := []string{models.ImportCreated, models.ImportUpdated, models.ImportFailed, models.ImportFailed, models.ImportUpdated}
+	for i, res := range report.Rows {
+		if res.Line != i+2 || res.Status != want[i] {
+			t.Errorf("row %d = %+v, want line %d %s", i, res, i+2, want[i])
+		}
+	}
+
+	inception, err := store.Get(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 後の行はgenresが空なので紐づけを変更しない
+	if inception.Runtime != 149 || len(inception.GenreIDs) != 2 {
+		t.Errorf("inception = %+v", inception)
+	}
+
+	godfather, _ := store.Get(2)
+	if godfather.Runtime != 177 || len(godfather.GenreIDs) != 2 {
+		t.Errorf("godfather = %+v", godfather)
+	}
+}
+
+func TestImportMoviesJSON(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	body := `[
+		{"title":"Inception","release_date":"2010-07-16","runtime":148,"genres":["Action"]},
+		{"title":"Memento","release_date":"2000-09-05","runtime":"113"},
+		{"id":999,"title":"Missing","release_date":"2000-01-01"},
+		{"id":1,"title":"The Shawshank Redemption","release_date":"1994-09-23","rating":5}
+	]`
+
+	status, resp := doRequest(t, app, http.MethodPost, "/v1/admin/import?format=json", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("import: status = %d", status)
+	}
+	var report importReport
+	decode(t, resp["import"], &report)
+	if report.Created != 1 || report.Updated != 1 || report.Failed != 2 {
+		t.Errorf("report = %+v", report)
+	}
+
+	shawshank, _ := store.Get(1)
+	if shawshank.ReleaseDate.Format("2006-01-02") != "1994-09-23" {
+		t.Errorf("release_date = %v", shawshank.ReleaseDate)
+	}
+
+	ndjson := "{\"title\":\"Heat\",\"release_date\":\"1995-12-15\"}\n\n{not json}\n"
+	status, resp = doRequest(t, app, http.MethodPost, "/v1/admin/import?format=ndjson", ndjson, token)
+	if status != http.StatusOK {
+		t.Fatalf("ndjson import: status = %d", status)
+	}
+	decode(t, resp["import"], &report)
+	if report.Created != 1 || report.Failed != 1 || report.Rows[1].Line != 3 {
+		t.Errorf("ndjson report = %+v", report)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/import?format=json", `{"title":"x"}`, token)
+	if status != http.StatusBadRequest {
+		t.Errorf("json object: status = %d, want 400", status)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/import", body, token)
+	if status != http.StatusUnsupportedMediaType {
+		t.Errorf("no format: status = %d, want 415", status)
+	}
+}
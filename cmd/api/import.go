@@ -0,0 +1,375 @@
+package main
+
+import (
+	"backend/models"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const importUsage = "usage: api [flags] import [-dry-run] [-format csv|json|ndjson] FILE"
+
+// 1回のインポートで受け付ける最大の行数
+const maxImportRows = 10000
+
+var importFormats = []string{"csv", "json", "ndjson"}
+
+// CSVで使える列(1行目に列名を書く、titleとrelease_dateは必須)
+var importColumns = []string{"id", "title", "description", "release_date", "runtime", "rating", "mpaa_rating", "genres"}
+
+// JSONの配列の要素とNDJSONの1行
+type importRecord struct {
+	ID int `json:"id"`
+	Title string `json:"title"`
+	Description string `json:"description"`
+	ReleaseDate string `json:"release_date"`
+	Runtime int `json:"runtime"`
+	Rating int `json:"rating"`
+	MPAARating string `json:"mpaa_rating"`
+	Genres []string `json:"genres"`
+}
+
+func (rec importRecord) row(line int) models.ImportRow {
+	return models.ImportRow{
+		Line: line,
+		ID: rec.ID,
+		Title: rec.Title,
+		Description: rec.Description,
+		ReleaseDate: rec.ReleaseDate,
+		Runtime: rec.Runtime,
+		Rating: rec.Rating,
+		MPAARating: rec.MPAARating,
+		Genres: rec.Genres,
+	}
+}
+
+// インポートの結果
+type importReport struct {
+	DryRun bool `json:"dry_run"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Failed int `json:"failed"`
+	Rows []models.ImportResult `json:"rows"`
+}
+
+// 入力を読み込んで、行と読み込めなかった行の結果を返す(入力全体が読めない場合はエラー)
+func parseImport(r io.Reader, format string) ([]models.ImportRow, []models.ImportResult, error) {
+	var rows []models.ImportRow
+	var failed []models.ImportResult
+	var err error
+
+	switch format {
+	case "csv":
+		rows, failed, err = parseImportCSV(r)
+	case "json":
+		rows, failed, err = parseImportJSON(r)
+	case "ndjson":
+		rows, failed, err = parseImportNDJSON(r)
+	default:
+		return nil, nil, fmt.Errorf("unknown import format %q (allowed: %s)", format, strings.Join(importFormats, ", "))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(rows)+len(failed) > maxImportRows {
+		return nil, nil, fmt.Errorf("imports are limited to %d rows", maxImportRows)
+	}
+
+	return rows, failed, nil
+}
+
+// 1行目を列名とするCSV(genresは"|"区切り、空の場合は既存のmovieの紐づけを変更しない)
+func parseImportCSV(r io.Reader) ([]models.ImportRow, []models.ImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("csv header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		// エクスポートにだけある列(yearなど)は読み飛ばす
+		if !containsParam(importColumns, name) && containsParam(exportColumns, name) {
+			continue
+		}
+		if !containsParam(importColumns, name) {
+			return nil, nil, fmt.Errorf("unknown csv column %q (allowed: %s)", name, strings.Join(importColumns, ", "))
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"title", "release_date"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("csv column %q is required", required)
+		}
+	}
+
+	rows := []models.ImportRow{}
+	failed := []models.ImportResult{}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// 列数が違う行などはその行だけ失敗にする
+			failed = append(failed, models.FailedImport(parseErr.StartLine, "", parseErr.Err))
+			if len(rows)+len(failed) > maxImportRows {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := models.ImportRow{
+			Line: line,
+			Title: field("title"),
+			Description: field("description"),
+			ReleaseDate: field("release_date"),
+			MPAARating: field("mpaa_rating"),
+		}
+		if genres := field("genres"); genres != "" {
+			row.Genres = strings.Split(genres, "|")
+		}
+
+		err = parseImportInts(field, map[string]*int{"id": &row.ID, "runtime": &row.Runtime, "rating": &row.Rating})
+		if err != nil {
+			failed = append(failed, models.FailedImport(line, row.Title, err))
+		} else {
+			rows = append(rows, row)
+		}
+
+		if len(rows)+len(failed) > maxImportRows {
+			break
+		}
+	}
+
+	return rows, failed, nil
+}
+
+// CSVの数値の列を読み込む(空の場合は0)
+func parseImportInts(field func(string) string, targets map[string]*int) error {
+	for _, name := range importColumns {
+		target, ok := targets[name]
+		if !ok || field(name) == "" {
+			continue
+		}
+		n, err := strconv.Atoi(field(name))
+		if err != nil {
+			return fmt.Errorf("%s must be an integer", name)
+		}
+		*target = n
+	}
+	return nil
+}
+
+// movieの配列のJSON(要素の番号を行番号とする)
+func parseImportJSON(r io.Reader) ([]models.ImportRow, []models.ImportResult, error) {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("json: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, nil, errors.New("json imports must be an array of movies")
+	}
+
+	rows := []models.ImportRow{}
+	failed := []models.ImportResult{}
+
+	for i := 1; dec.More(); i++ {
+		var rec importRecord
+		err := dec.Decode(&rec)
+		if err != nil {
+			// 型が違う要素は読み飛ばせるが、構文エラーは続きを読めない
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				failed = append(failed, models.FailedImport(i, rec.Title, fmt.Errorf("%s must be a %s", typeErr.Field, typeErr.Type)))
+				continue
+			}
+			return nil, nil, fmt.Errorf("json element %d: %w", i, err)
+		}
+		rows = append(rows, rec.row(i))
+
+		if len(rows)+len(failed) > maxImportRows {
+			break
+		}
+	}
+
+	return rows, failed, nil
+}
+
+// 1行に1つのmovieのJSON(空行は無視する)
+func parseImportNDJSON(r io.Reader) ([]models.ImportRow, []models.ImportResult, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	rows := []models.ImportRow{}
+	failed := []models.ImportResult{}
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var rec importRecord
+		err := json.Unmarshal([]byte(text), &rec)
+		if err != nil {
+			failed = append(failed, models.FailedImport(line, rec.Title, err))
+		} else {
+			rows = append(rows, rec.row(line))
+		}
+
+		if len(rows)+len(failed) > maxImportRows {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return rows, failed, nil
+}
+
+// 行をImportBatchSizeごとのトランザクションで書き込み、行番号の順の結果を返す
+func runImportRows(store models.MovieStore, rows []models.ImportRow, failed []models.ImportResult, opts models.ImportOptions) *importReport {
+	results := append([]models.ImportResult{}, failed...)
+
+	batchSize := models.ImportBatchSize
+	if opts.DryRun {
+		// 後の行が前の行で追加したmovieの更新になるように、ひとつのトランザクションで確認する
+		batchSize = len(rows)
+	}
+
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[start:end]
+
+		batchResults, err := store.ImportMovies(batch, opts)
+		if err != nil {
+			// トランザクションごと取り消されたのでバッチのすべての行が失敗
+			for _, row := range batch {
+				results = append(results, models.FailedImport(row.Line, row.Title, err))
+			}
+			continue
+		}
+		results = append(results, batchResults...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Line < results[j].Line
+	})
+
+	report := &importReport{DryRun: opts.DryRun, Rows: results}
+	for _, res := range results {
+		switch res.Status {
+		case models.ImportCreated:
+			report.Created++
+		case models.ImportUpdated:
+			report.Updated++
+		case models.ImportFailed:
+			report.Failed++
+		}
+	}
+
+	return report
+}
+
+// importサブコマンド: ファイルのmovieをまとめて追加・更新する
+func runImport(cfg config, logger *log.Logger, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Validate the file and report what would change without writing")
+	format := fs.String("format", "", "Input format (csv|json|ndjson); defaults to the file extension")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(importUsage)
+	}
+	path := fs.Arg(0)
+
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			*format = "csv"
+		case ".json":
+			*format = "json"
+		case ".ndjson", ".jsonl":
+			*format = "ndjson"
+		default:
+			return fmt.Errorf("cannot tell the format of %s; pass -format", path)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rows, failed, err := parseImport(f, *format)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	store := newModels(cfg, db)
+
+	report := runImportRows(store.DB, rows, failed, models.ImportOptions{DryRun: *dryRun})
+
+	for _, res := range report.Rows {
+		if res.Status == models.ImportFailed {
+			fmt.Printf("line %d: %s\n", res.Line, res.Error)
+		}
+	}
+
+	mode := ""
+	if report.DryRun {
+		mode = " (dry run, nothing was written)"
+	}
+	logger.Printf("imported %s: %d created, %d updated, %d failed%s", path, report.Created, report.Updated, report.Failed, mode)
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d rows failed", report.Failed)
+	}
+
+	return nil
+}
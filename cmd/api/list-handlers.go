@@ -0,0 +1,177 @@
+package main
+
+import (
+	"backend/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ListEntryPayload struct {
+	// 省略した場合はすでにあるnoteを残す
+	Note *string `json:"note"`
+}
+
+// watchlist・favoritesの操作で返すエラーのステータスコード
+func listErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, models.ErrUnknownList):
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// サインインしている場合はmoviesのin_watchlistをセットする
+func (app *application) markWatchlist(r *http.Request, movies ...*models.Movie) error {
+	userID := userIDFromContext(r)
+	if userID == 0 || len(movies) == 0 {
+		return nil
+	}
+
+	var ids []int
+	for _, movie := range movies {
+		ids = append(ids, movie.ID)
+	}
+
+	listed, err := app.models.DB.ListedMovieIDs(userID, models.ListWatchlist, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		movie.InWatchlist = listed[movie.ID]
+	}
+
+	return nil
+}
+
+// /v1/me/watchlistまたは/v1/me/favoritesのmovieをpositionの順に返す
+func (app *application) getList(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	entries, err := app.models.DB.ListEntries(userIDFromContext(r), params.ByName("list"))
+	if err != nil {
+		app.errorJSON(w, err, listErrorStatus(err))
+		return
+	}
+
+	var movies []*models.Movie
+	for _, e := range entries {
+		movies = append(movies, e.Movie)
+	}
+	err = app.prepareMovies(r, movies...)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, entries, "entries")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// movieをリストの最後に追加する(すでにある場合はnoteを更新する)
+func (app *application) saveListEntry(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	movieID, err := strconv.Atoi(params.ByName("movie_id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid movie_id parameter"))
+		return
+	}
+
+	// ボディは省略してもよい
+	var payload ListEntryPayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil && !errors.Is(err, io.EOF) {
+		app.errorJSON(w, err)
+		return
+	}
+
+	entry := models.ListEntry{
+		UserID: userIDFromContext(r),
+		List: params.ByName("list"),
+		MovieID: movieID,
+		KeepNote: payload.Note == nil,
+	}
+	if payload.Note != nil {
+		entry.Note = *payload.Note
+	}
+
+	saved, err := app.models.DB.SaveListEntry(entry)
+	if err != nil {
+		app.errorJSON(w, err, listErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, saved, "entry")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) removeListEntry(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	movieID, err := strconv.Atoi(params.ByName("movie_id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid movie_id parameter"))
+		return
+	}
+
+	err = app.models.DB.RemoveListEntry(userIDFromContext(r), params.ByName("list"), movieID)
+	if err != nil {
+		app.errorJSON(w, err, listErrorStatus(err))
+		return
+	}
+
+	ok := jsonResp{
+		OK: true,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// {"movie_ids": [...]}の順にリストを並べ替える
+func (app *application) reorderList(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	var payload struct {
+		MovieIDs []int `json:"movie_ids"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.models.DB.ReorderList(userIDFromContext(r), params.ByName("list"), payload.MovieIDs)
+	if err != nil {
+		app.errorJSON(w, err, listErrorStatus(err))
+		return
+	}
+
+	ok := jsonResp{
+		OK: true,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"testing"
+)
+
+func TestWatchlist(t *testing.T) {
+	app, _ := newTestApplication(t)
+	token := testToken(t, 30)
+
+	for _, id := range []int{3, 1, 4} {
+		status, _ := doRequest(t, app, http.MethodPut, "/v1/me/watchlist/"+itoa(id), "", token)
+		if status != http.StatusOK {
+			t.Fatalf("add %d: status = %d, want %d", id, status, http.StatusOK)
+		}
+	}
+
+	status, resp := doRequest(t, app, http.MethodPut, "/v1/me/watchlist/1", `{"note":"with popcorn"}`, token)
+	if status != http.StatusOK {
+		t.Fatalf("update note: status = %d, want %d", status, http.StatusOK)
+	}
+	var entry models.ListEntry
+	decode(t, resp["entry"], &entry)
+	if entry.Position != 2 || entry.Note != "with popcorn" {
+		t.Errorf("updated entry = %+v, want position 2 with note", entry)
+	}
+
+	// noteを省略して追加し直してもnoteは消えない。空文字列なら消す
+	for _, tt := range []struct {
+		body string
+		want string
+	}{
+		{"", "with popcorn"},
+		{`{}`, "with popcorn"},
+		{`{"note":""}`, ""},
+	} {
+		status, resp := doRequest(t, app, http.MethodPut, "/v1/me/watchlist/1", tt.body, token)
+		if status != http.StatusOK {
+			t.Fatalf("re-add with %q: status = %d, want %d", tt.body, status, http.StatusOK)
+		}
+		var entry models.ListEntry
+		decode(t, resp["entry"], &entry)
+		if entry.Position != 2 || entry.Note != tt.want {
+			t.Errorf("re-add with %q: entry = %+v, want note %q", tt.body, entry, tt.want)
+		}
+	}
+
+	movieIDs := func() []int {
+		t.Helper()
+		status, resp := doRequest(t, app, http.MethodGet, "/v1/me/watchlist", "", token)
+		if status != http.StatusOK {
+			t.Fatalf("list: status = %d, want %d", status, http.StatusOK)
+		}
+		var entries []models.ListEntry
+		decode(t, resp["entries"], &entries)
+		var ids []int
+		for _, e := range entries {
+			if !e.Movie.InWatchlist {
+				t.Errorf("movie %d in watchlist has in_watchlist = false", e.MovieID)
+			}
+			ids = append(ids, e.MovieID)
+		}
+		return ids
+	}
+
+	if ids := movieIDs(); itoaList(ids) != "3,1,4" {
+		t.Errorf("watchlist = %v, want [3 1 4]", ids)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/me/watchlist", `{"movie_ids":[4,3,1]}`, token)
+	if status != http.StatusOK {
+		t.Fatalf("reorder: status = %d, want %d", status, http.StatusOK)
+	}
+	if ids := movieIDs(); itoaList(ids) != "4,3,1" {
+		t.Errorf("reordered watchlist = %v, want [4 3 1]", ids)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/me/watchlist", `{"movie_ids":[4,3]}`, token)
+	if status != http.StatusBadRequest {
+		t.Errorf("reorder with missing movie: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/me/watchlist/3", "", token)
+	if status != http.StatusOK {
+		t.Errorf("remove: status = %d, want %d", status, http.StatusOK)
+	}
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/me/watchlist/3", "", token)
+	if status != http.StatusNotFound {
+		t.Errorf("remove twice: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	// favoritesはwatchlistとは別
+	doRequest(t, app, http.MethodPut, "/v1/me/favorites/2", "", token)
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/me/favorites", "", token)
+	var favorites []models.ListEntry
+	decode(t, resp["entries"], &favorites)
+	if len(favorites) != 1 || favorites[0].MovieID != 2 || favorites[0].Movie.InWatchlist {
+		t.Errorf("favorites = %+v", favorites)
+	}
+
+	status, _ = doRequest(t, app, http.MethodGet, "/v1/me/later", "", token)
+	if status != http.StatusNotFound {
+		t.Errorf("unknown list: status = %d, want %d", status, http.StatusNotFound)
+	}
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/me/watchlist/999", "", token)
+	if status != http.StatusNotFound {
+		t.Errorf("add missing movie: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	// in_watchlistはトークンのユーザーのもの
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/movie/4", "", token)
+	var movie models.Movie
+	decode(t, resp["movie"], &movie)
+	if !movie.InWatchlist {
+		t.Errorf("movie 4 in_watchlist = false for owner")
+	}
+
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/movie/4", "", testToken(t, 31))
+	decode(t, resp["movie"], &movie)
+	if movie.InWatchlist {
+		t.Errorf("movie 4 in_watchlist = true for another user")
+	}
+
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/movies?sort=id", "", token)
+	var movies []models.Movie
+	decode(t, resp["movies"], &movies)
+	for _, m := range movies {
+		want := m.ID == 1 || m.ID == 4
+		if m.InWatchlist != want {
+			t.Errorf("movie %d in list: in_watchlist = %v, want %v", m.ID, m.InWatchlist, want)
+		}
+	}
+
+	status, _ = doRequest(t, app, http.MethodGet, "/v1/movies", "", "bad-token")
+	if status != http.StatusForbidden {
+		t.Errorf("invalid token on public route: status = %d, want %d", status, http.StatusForbidden)
+	}
+}
+
+func itoaList(ids []int) string {
+	s := ""
+	for i, id := range ids {
+		if i > 0 {
+			s += ","
+		}
+		s += itoa(id)
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"backend/models"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// "ja-JP" -> "ja"(対応していない場合は空)
+func supportedLocale(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if containsParam(models.SupportedLocales, tag) {
+		return tag
+	}
+	if i := strings.IndexAny(tag, "-_"); i > 0 && containsParam(models.SupportedLocales, tag[:i]) {
+		return tag[:i]
+	}
+	return ""
+}
+
+// 翻訳を探すlocaleの順序を返す
+// ?lang=ja,enがあればその順(対応していないlocaleはエラー)、なければAccept-Languageのqの大きい順
+func requestLocales(r *http.Request) ([]string, error) {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		return parseLang(lang)
+	}
+	return parseAcceptLanguage(r.Header.Get("Accept-Language")), nil
+}
+
+// "ja,en"のlocaleを順に返す
+func parseLang(lang string) ([]string, error) {
+	var locales []string
+	for _, tag := range splitParam(lang) {
+		locale := supportedLocale(tag)
+		if locale == "" {
+			return nil, fmt.Errorf("lang %q: %w", tag, models.ErrUnsupportedLocale)
+		}
+		if !containsParam(locales, locale) {
+			locales = append(locales, locale)
+		}
+	}
+	return locales, nil
+}
+
+// "ja-JP,ja;q=0.9,en;q=0.8"の対応しているlocaleをqの大きい順に返す
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				v, err := strconv.ParseFloat(f[2:], 64)
+				if err == nil {
+					q = v
+				}
+			}
+		}
+		// 対応していない言語と*は無視する(元の値にフォールバックする)
+		if locale := supportedLocale(fields[0]); locale != "" && q > 0 {
+			tags = append(tags, weighted{locale, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	var locales []string
+	for _, t := range tags {
+		if !containsParam(locales, t.locale) {
+			locales = append(locales, t.locale)
+		}
+	}
+	return locales
+}
+
+// 言語ごとにレスポンスが変わることをキャッシュに伝えるミドルウェア
+func (app *application) varyLanguage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"backend/metadata"
 	"backend/models"
+	"backend/storage"
 	"context"
 	"database/sql"
 	"flag"
@@ -9,6 +11,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -21,10 +24,32 @@ type config struct {
 	env string
 	db struct {
 		dsn string
+		store string
+		// カンマ区切りのreplicaの接続文字列
+		replicas string
+		replicaMaxLag time.Duration
 	}
 	jwt struct {
 		secret string
 	}
+	trash struct {
+		retention time.Duration
+	}
+	storage struct {
+		dir string
+	}
+	poster struct {
+		maxBytes int64
+	}
+	similar struct {
+		ttl time.Duration
+	}
+	metadata struct {
+		provider string
+		tmdbURL string
+		tmdbImageURL string
+		tmdbKey string
+	}
 }
 
 type AppStatus struct {
@@ -37,6 +62,16 @@ type application struct {
 	config config
 	logger *log.Logger
 	models models.Models
+	// ポスター画像などのファイルの保存先
+	blobs storage.BlobStore
+	// 外部のメタデータの検索先(設定しない場合はnil)
+	metadata metadata.MetadataProvider
+	// バックグラウンドの処理(シャットダウンのときに完了を待つ)
+	wg sync.WaitGroup
+	// 似ているmovieのインデックス
+	similar similarCache
+	// 最近書き込んだユーザー(replicaではなくprimaryから読み込む)
+	writes recentWrites
 }
 
 func main() {
@@ -46,28 +81,158 @@ func main() {
 	// 引数は変数のポインタ(メモリのアドレス値)、フラグの名前、デフォルト値、使い方の説明
 	flag.IntVar(&cfg.port, "port", 4000, "Server port to listen on")
 	flag.StringVar(&cfg.env, "env", "development", "Application environment (development|production")
-	flag.StringVar(&cfg.db.dsn, "dsn", "postgres://localhost/go_movies?sslmode=disable", "Postgres connection string")
+	flag.StringVar(&cfg.db.dsn, "dsn", "postgres://localhost/go_movies?sslmode=disable", "Postgres connection string (sqlite:FILE uses a local SQLite database instead)")
 	// flag.StringVar(&cfg.db.dsn, "dsn", "postgres://tcs@localhost/go_movies?sslmode=disable", "Postgres connection string")
+	flag.StringVar(&cfg.db.replicas, "replica-dsns", "", "Comma separated Postgres connection strings of read replicas")
+	flag.DurationVar(&cfg.db.replicaMaxLag, "replica-max-lag", 5*time.Second, "Replicas further behind the primary than this are not used for reads")
+	flag.StringVar(&cfg.db.store, "store", "postgres", "Storage backend (postgres|sqlite|memory)")
 	flag.StringVar(&cfg.jwt.secret, "jwt-secret", "2dce505d96a53c5768052ee90f3df2055657518dad489160df9913f66042e160", "secret")
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./uploads", "Directory where uploaded posters are stored")
+	flag.Int64Var(&cfg.poster.maxBytes, "poster-max-bytes", 5<<20, "Largest poster image accepted by the upload endpoint")
+	flag.StringVar(&cfg.metadata.provider, "metadata-provider", "none", "External metadata provider used to enrich new movies (none|tmdb)")
+	flag.StringVar(&cfg.metadata.tmdbURL, "tmdb-url", metadata.DefaultTMDBURL, "Base URL of the TMDB-compatible API")
+	flag.StringVar(&cfg.metadata.tmdbImageURL, "tmdb-image-url", metadata.DefaultTMDBImageURL, "Base URL of TMDB poster images")
+	flag.StringVar(&cfg.metadata.tmdbKey, "tmdb-api-key", os.Getenv("TMDB_API_KEY"), "TMDB API key (defaults to $TMDB_API_KEY)")
+	flag.DurationVar(&cfg.similar.ttl, "similar-ttl", 10*time.Minute, "How long the similar movies index is reused before it is rebuilt")
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted movies stay in the trash before purge removes them")
 	// 引数のフラグを解析しcfgにバインドする
 	flag.Parse()
 
 	// Loggerオブジェクトを生成して出力フォーマットを設定する
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
 
-	// DBと接続する
-	db, err := openDB(cfg)
-	if err != nil {
-		logger.Fatal(err)
+	// サブコマンドが指定された場合はサーバーを起動しない
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case "migrate":
+			err := runMigrate(cfg, logger, flag.Args()[1:])
+			if err != nil {
+				logger.Fatal(err)
+			}
+		case "import":
+			err := runImport(cfg, logger, flag.Args()[1:])
+			if err != nil {
+				logger.Fatal(err)
+			}
+		case "purge":
+			err := runPurge(cfg, logger)
+			if err != nil {
+				logger.Fatal(err)
+			}
+		default:
+			logger.Fatalf("unknown command %q", flag.Arg(0))
+		}
+		return
 	}
-	// main()がreturnするときにDBとの接続を閉じる
-	defer db.Close()
 
 	// アプリケーションの設定をする(参照渡しをおこなうためにポインタを使用)
 	app := &application{
 		config: cfg,
 		logger: logger,
-		models: models.NewModels(db),
+	}
+
+	blobs, err := storage.NewLocalStore(cfg.storage.dir)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	app.blobs = blobs
+
+	switch cfg.metadata.provider {
+	case "none":
+	case "tmdb":
+		if cfg.metadata.tmdbKey == "" {
+			logger.Fatal("-tmdb-api-key or $TMDB_API_KEY is required for the tmdb metadata provider")
+		}
+		app.metadata = metadata.NewTMDBClient(cfg.metadata.tmdbURL, cfg.metadata.tmdbImageURL, cfg.metadata.tmdbKey)
+	default:
+		logger.Fatalf("unknown metadata provider %q", cfg.metadata.provider)
+	}
+
+	// DSNがsqlite:の場合はPostgresの代わりにSQLiteを使う
+	backend := cfg.db.store
+	if backend == "postgres" && models.IsSQLiteDSN(cfg.db.dsn) {
+		backend = "sqlite"
+	}
+
+	switch backend {
+	case "memory":
+		// DBを使わずにメモリ上の初期データで起動する
+		store := models.NewMemoryModel()
+		err := store.Seed()
+		if err != nil {
+			logger.Fatal(err)
+		}
+		app.models = models.NewMemoryModels(store)
+	case "sqlite":
+		// -store sqliteでPostgresのDSNを開かないようにする
+		if !models.IsSQLiteDSN(cfg.db.dsn) {
+			logger.Fatal("-store sqlite requires a sqlite: DSN")
+		}
+
+		db, err := openDB(cfg)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer db.Close()
+
+		// ほかのサービスなしで起動できるように、スキーマを最新にして初期データを入れる
+		migrator, err := newMigrator(cfg, db)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		err = migrator.Up()
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		store := models.NewSQLiteModel(db)
+		err = store.Seed()
+		if err != nil {
+			logger.Fatal(err)
+		}
+		app.models = models.Models{DB: store}
+	case "postgres":
+		// DBと接続する
+		db, err := openDB(cfg)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		// main()がreturnするときにDBとの接続を閉じる
+		defer db.Close()
+
+		// スキーマがバイナリより古い場合は起動しない
+		migrator, err := models.NewMigrator(db)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		err = migrator.CheckSchema()
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		app.models = models.NewModels(db)
+
+		// 読み込みを振り分けるreplica
+		if cfg.db.replicas != "" {
+			var replicas []*sql.DB
+			for _, dsn := range splitParam(cfg.db.replicas) {
+				replica, err := openDSN(dsn)
+				if err != nil {
+					logger.Fatal(err)
+				}
+				defer replica.Close()
+				replicas = append(replicas, replica)
+			}
+
+			pool := models.NewReplicaPool(replicas, cfg.db.replicaMaxLag)
+			pool.Check(context.Background())
+			logger.Printf("%d of %d replicas are healthy", pool.Healthy(), len(replicas))
+			go pool.Run(context.Background(), replicaCheckInterval)
+
+			app.models = models.NewModelsWithReplicas(db, pool)
+		}
+	default:
+		logger.Fatalf("unknown store %q", cfg.db.store)
 	}
 
 	// サーバー設定をカスタマイズする
@@ -80,16 +245,39 @@ func main() {
 
 	logger.Println("Starting server on port", cfg.port)
 
-	// サーバをlistenする
-	err = srv.ListenAndServe()
+	// サーバをlistenする(シグナルを受け取ったらバックグラウンドの処理を待って終了する)
+	err = app.serve(srv)
 	if err != nil {
 		log.Println(err)
 	}
 }
 
 func openDB(cfg config) (*sql.DB, error) {
+	if models.IsSQLiteDSN(cfg.db.dsn) {
+		return models.OpenSQLite(cfg.db.dsn)
+	}
+	return openDSN(cfg.db.dsn)
+}
+
+// DSNに合わせたマイグレーションを返す
+func newMigrator(cfg config, db *sql.DB) (*models.Migrator, error) {
+	if models.IsSQLiteDSN(cfg.db.dsn) {
+		return models.NewSQLiteMigrator(db)
+	}
+	return models.NewMigrator(db)
+}
+
+// DSNに合わせたストアを返す
+func newModels(cfg config, db *sql.DB) models.Models {
+	if models.IsSQLiteDSN(cfg.db.dsn) {
+		return models.Models{DB: models.NewSQLiteModel(db)}
+	}
+	return models.NewModels(db)
+}
+
+func openDSN(dsn string) (*sql.DB, error) {
 	// DBへアクセスする(接続はまだ確立されない)
-	db, err := sql.Open("postgres", cfg.db.dsn)
+	db, err := sql.Open("postgres", dsn)
 	// エラー処理
 	if err != nil {
 		return nil, err
@@ -97,7 +285,7 @@ func openDB(cfg config) (*sql.DB, error) {
 
 	// 5sでタイムアウトする
 	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
-	// openDB()がreturnするまで実行されない
+	// openDSN()がreturnするまで実行されない
 	defer cancel()
 
 	// DBとの接続を検証する
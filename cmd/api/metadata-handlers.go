@@ -0,0 +1,330 @@
+package main
+
+import (
+	"backend/metadata"
+	"backend/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var errNoMetadataProvider = errors.New("no metadata provider is configured")
+
+// 提案の操作で返すエラーのステータスコード
+func suggestionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, metadata.ErrNoMatch), errors.Is(err, metadata.ErrImageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrSuggestionResolved), errors.Is(err, models.ErrVersionConflict):
+		return http.StatusConflict
+	case errors.Is(err, errPosterType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errNoMetadataProvider):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+// バックグラウンドでfnを実行する(panicしてもサーバーを止めない)
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Println("background:", err)
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// プロバイダーでmovieを検索して、変更がある場合は提案として保存する
+func (app *application) enrichMovie(ctx context.Context, id int) (*models.MetadataSuggestion, error) {
+	if app.metadata == nil {
+		return nil, errNoMetadataProvider
+	}
+
+	// 追加した直後に呼ばれるのでreplicaではなくprimaryから読み込む
+	movie, err := app.models.DB.Primary().Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := app.metadata.Lookup(ctx, movie.Title, movie.Year)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestion := models.MetadataSuggestion{
+		MovieID: id,
+		Provider: app.metadata.Name(),
+		ExternalID: found.ExternalID,
+		Title: found.Title,
+		Description: found.Overview,
+		PosterRef: found.PosterRef,
+	}
+	if !found.ReleaseDate.IsZero() {
+		suggestion.ReleaseDate = &found.ReleaseDate
+	}
+
+	// 今の値と同じなら提案しない
+	if len(suggestion.Changes(movie)) == 0 {
+		return nil, nil
+	}
+
+	suggestion.ID, err = app.models.DB.InsertSuggestion(suggestion)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.models.DB.GetSuggestion(suggestion.ID)
+}
+
+// movieの作成後にレスポンスを待たせずにメタデータを検索する
+func (app *application) enrichMovieAsync(id int) {
+	if app.metadata == nil {
+		return
+	}
+
+	app.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		_, err := app.enrichMovie(ctx, id)
+		if err != nil && !errors.Is(err, metadata.ErrNoMatch) {
+			app.logger.Printf("enrich movie %d: %v", id, err)
+		}
+	})
+}
+
+// 既存のmovieのメタデータをすぐに検索する
+func (app *application) requestEnrichment(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	suggestion, err := app.enrichMovie(r.Context(), id)
+	if err != nil {
+		status := suggestionErrorStatus(err)
+		if status == http.StatusBadRequest {
+			// プロバイダーとの通信のエラー
+			status = http.StatusBadGateway
+		}
+		app.errorJSON(w, err, status)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, suggestion, "suggestion")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// ?status=pending|accepted|rejected|all(省略した場合はpending)
+func (app *application) getSuggestions(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch {
+	case status == "":
+		status = models.SuggestionPending
+	case status == "all":
+		status = ""
+	case !containsParam(models.SuggestionStatuses, status):
+		app.errorJSON(w, fmt.Errorf("status must be one of %s, all", strings.Join(models.SuggestionStatuses, ", ")))
+		return
+	}
+
+	suggestions, err := app.models.DB.Suggestions(status)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, suggestions, "suggestions")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+type AcceptSuggestionPayload struct {
+	// 採用するフィールド(省略した場合は今の値と異なるものすべて)
+	Fields []string `json:"fields"`
+}
+
+// 提案の値をmovieに反映する
+func (app *application) acceptSuggestion(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload AcceptSuggestionPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil && !errors.Is(err, io.EOF) {
+		app.errorJSON(w, err)
+		return
+	}
+
+	// 同時に採用されないように採用中にする(失敗したら未確認に戻す)
+	suggestion, err := app.models.DB.ClaimSuggestion(id)
+	if err != nil {
+		app.errorJSON(w, err, suggestionErrorStatus(err))
+		return
+	}
+	accepted := false
+	defer func() {
+		if !accepted {
+			err := app.models.DB.ReleaseSuggestion(id)
+			if err != nil {
+				app.logger.Println("release suggestion:", err)
+			}
+		}
+	}()
+
+	movie, err := app.models.DB.Primary().Get(suggestion.MovieID)
+	if err != nil {
+		app.errorJSON(w, err, suggestionErrorStatus(err))
+		return
+	}
+
+	fields := payload.Fields
+	if fields == nil {
+		fields = suggestion.Changes(movie)
+	}
+	err = suggestion.ValidateFields(fields)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	// ポスターは先にダウンロードする(失敗した場合はmovieを変更しない)
+	var poster []byte
+	if containsParam(fields, "poster") {
+		poster, err = app.fetchSuggestedPoster(r.Context(), suggestion.PosterRef)
+		if err != nil {
+			status := suggestionErrorStatus(err)
+			if status == http.StatusBadRequest {
+				status = http.StatusBadGateway
+			}
+			app.errorJSON(w, err, status)
+			return
+		}
+	}
+
+	// 画像を先に保存して、movieの変更と提案の採用は1つのトランザクションで行う
+	posterKey := ""
+	if poster != nil {
+		posterKey, err = app.storePoster(r.Context(), movie.ID, poster)
+		if err != nil {
+			app.errorJSON(w, err, posterErrorStatus(err))
+			return
+		}
+	}
+
+	old, err := app.models.DB.AcceptSuggestion(id, fields, posterKey, userIDFromContext(r))
+	if err != nil {
+		// 同じ画像が今のポスターならキーも同じなので消さない
+		app.deleteOldPoster(r.Context(), posterKey, movie.Poster)
+		app.errorJSON(w, err, suggestionErrorStatus(err))
+		return
+	}
+	accepted = true
+	if posterKey != "" {
+		app.deleteOldPoster(r.Context(), old, posterKey)
+	}
+	app.invalidateSimilar()
+
+	app.writeResolvedSuggestion(w, r, id)
+}
+
+func (app *application) rejectSuggestion(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	err = app.models.DB.ResolveSuggestion(id, models.SuggestionRejected, nil)
+	if err != nil {
+		app.errorJSON(w, err, suggestionErrorStatus(err))
+		return
+	}
+
+	app.writeResolvedSuggestion(w, r, id)
+}
+
+// 確認後の提案と反映後のmovieを返す
+func (app *application) writeResolvedSuggestion(w http.ResponseWriter, r *http.Request, id int) {
+	suggestion, err := app.models.DB.GetSuggestion(id)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	movie, err := app.store(r).Get(suggestion.MovieID)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.prepareMovies(r, movie)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeEnvelope(w, http.StatusOK, map[string]interface{}{
+		"suggestion": suggestion,
+		"movie": movie,
+	})
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// 提案されたポスターをダウンロードする(アップロードと同じ上限を適用する)
+func (app *application) fetchSuggestedPoster(ctx context.Context, ref string) ([]byte, error) {
+	if app.metadata == nil {
+		return nil, errNoMetadataProvider
+	}
+
+	image, err := app.metadata.Image(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer image.Close()
+
+	maxBytes := app.config.poster.maxBytes
+	data, err := io.ReadAll(io.LimitReader(image, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("suggested poster is larger than %d bytes", maxBytes)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"backend/metadata"
+	"backend/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMetadataEnrichment(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	provider := metadata.NewFakeProvider()
+	provider.Add("Inception", 2010, metadata.Suggestion{
+		ExternalID: "27205",
+		Title: "Inception",
+		Overview: "Cobb, a skilled thief, is offered a chance at redemption",
+		ReleaseDate: time.Date(2010, 7, 15, 0, 0, 0, 0, time.UTC),
+		PosterRef: "/inception.png",
+	})
+	provider.AddImage("/inception.png", testPNG(t))
+	app.metadata = provider
+
+	body := `{"id":"0","title":"Inception","description":"A thief","release_date":"2010-07-16","runtime":"148","rating":"5","mpaa_rating":"PG-13","genres":[3]}`
+	status, _ := doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("insert: status = %d", status)
+	}
+
+	// 検索はレスポンスのあとにおこなわれる
+	app.wg.Wait()
+	if provider.Lookups() != 1 {
+		t.Fatalf("lookups = %d, want 1", provider.Lookups())
+	}
+
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/admin/suggestions", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("suggestions: status = %d", status)
+	}
+	var suggestions []models.MetadataSuggestion
+	decode(t, resp["suggestions"], &suggestions)
+	if len(suggestions) != 1 || suggestions[0].MovieID != 5 || suggestions[0].Provider != "fake" {
+		t.Fatalf("suggestions = %+v", suggestions)
+	}
+	id := suggestions[0].ID
+
+	// 提案は採用するまでmovieを変更しない
+	movie, _ := store.Get(5)
+	if movie.Description != "A thief" {
+		t.Errorf("description changed before accept: %q", movie.Description)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/suggestions/"+itoa(id)+"/accept", `{"fields":["title"]}`, token)
+	if status != http.StatusBadRequest {
+		t.Errorf("unknown field: status = %d, want 400", status)
+	}
+
+	status, resp = doRequest(t, app, http.MethodPost, "/v1/admin/suggestions/"+itoa(id)+"/accept", `{"fields":["description","poster"]}`, token)
+	if status != http.StatusOK {
+		t.Fatalf("accept: status = %d", status)
+	}
+	var accepted models.MetadataSuggestion
+	decode(t, resp["suggestion"], &accepted)
+	if accepted.Status != models.SuggestionAccepted || len(accepted.AcceptedFields) != 2 {
+		t.Errorf("accepted suggestion = %+v", accepted)
+	}
+
+	movie, _ = store.Get(5)
+	if movie.Description != "Cobb, a skilled thief, is offered a chance at redemption" {
+		t.Errorf("description = %q", movie.Description)
+	}
+	if movie.ReleaseDate.Day() != 16 {
+		t.Errorf("release_date changed without being accepted: %v", movie.ReleaseDate)
+	}
+	if movie.Poster == "" {
+		t.Error("poster was not stored")
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/suggestions/"+itoa(id)+"/reject", "", token)
+	if status != http.StatusConflict {
+		t.Errorf("reject accepted suggestion: status = %d, want 409", status)
+	}
+}
+
+func TestRejectSuggestion(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	status, _ := doRequest(t, app, http.MethodPost, "/v1/admin/movies/1/enrich", "", token)
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("without provider: status = %d, want 503", status)
+	}
+
+	provider := metadata.NewFakeProvider()
+	provider.Add("The Godfather", 1972, metadata.Suggestion{Overview: "Something else"})
+	app.metadata = provider
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/movies/1/enrich", "", token)
+	if status != http.StatusNotFound {
+		t.Errorf("no match: status = %d, want 404", status)
+	}
+
+	status, resp := doRequest(t, app, http.MethodPost, "/v1/admin/movies/2/enrich", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("enrich: status = %d", status)
+	}
+	var suggestion models.MetadataSuggestion
+	decode(t, resp["suggestion"], &suggestion)
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/suggestions/"+itoa(suggestion.ID)+"/reject", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("reject: status = %d", status)
+	}
+
+	movie, _ := store.Get(2)
+	if movie.Description == "Something else" {
+		t.Error("rejected suggestion was applied")
+	}
+
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/admin/suggestions", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("suggestions: status = %d", status)
+	}
+	var pending []models.MetadataSuggestion
+	decode(t, resp["suggestions"], &pending)
+	if len(pending) != 0 {
+		t.Errorf("pending suggestions = %+v, want none", pending)
+	}
+}
+
+func TestAcceptSuggestionAtomic(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	// 画像が見つからないポスターの提案
+	provider := metadata.NewFakeProvider()
+	provider.Add("The Godfather", 1972, metadata.Suggestion{Overview: "An aging patriarch", PosterRef: "/missing.png"})
+	app.metadata = provider
+
+	status, resp := doRequest(t, app, http.MethodPost, "/v1/admin/movies/2/enrich", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("enrich: status = %d", status)
+	}
+	var suggestion models.MetadataSuggestion
+	decode(t, resp["suggestion"], &suggestion)
+	accept := "/v1/admin/suggestions/" + itoa(suggestion.ID) + "/accept"
+
+	// ポスターの取得に失敗したらmovieは変えず、提案は未確認のまま
+	status, _ = doRequest(t, app, http.MethodPost, accept, `{"fields":["description","poster"]}`, token)
+	if status == http.StatusOK {
+		t.Fatalf("accept with a missing poster: status = %d", status)
+	}
+	movie, _ := store.Get(2)
+	if movie.Description == "An aging patriarch" || movie.Version != 1 {
+		t.Errorf("movie changed by a failed accept: %+v", movie)
+	}
+	s, _ := store.GetSuggestion(suggestion.ID)
+	if s.Status != models.SuggestionPending {
+		t.Errorf("status after a failed accept = %q, want pending", s.Status)
+	}
+
+	// 採用中の提案は同時に採用できない
+	_, err := store.ClaimSuggestion(suggestion.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, _ = doRequest(t, app, http.MethodPost, accept, `{"fields":["description"]}`, token)
+	if status != http.StatusConflict {
+		t.Errorf("accept while accepting: status = %d, want 409", status)
+	}
+	err = store.ReleaseSuggestion(suggestion.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, accept, `{"fields":["description"]}`, token)
+	if status != http.StatusOK {
+		t.Fatalf("accept: status = %d", status)
+	}
+	movie, _ = store.Get(2)
+	revisions, _ := store.Revisions(2)
+	if movie.Description != "An aging patriarch" || movie.Version != 2 || len(revisions) != 2 || revisions[0].UserID != 10 {
+		t.Errorf("accepted movie = %+v, revisions = %+v", movie, revisions)
+	}
+	status, _ = doRequest(t, app, http.MethodPost, accept, `{"fields":["description"]}`, token)
+	if status != http.StatusConflict {
+		t.Errorf("accept twice: status = %d, want 409", status)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"log"
 	"net/http"
@@ -11,11 +12,24 @@ import (
 	"github.com/pascaldekloe/jwt"
 )
 
+type contextKey string
+
+// 認証したuserIDをリクエストのコンテキストに保存するキー
+const userIDContextKey = contextKey("userID")
+
+// checkTokenで認証したuserIDを返す(認証していない場合は0)
+func userIDFromContext(r *http.Request) int {
+	userID, _ := r.Context().Value(userIDContextKey).(int)
+	return userID
+}
+
 // CORSを許可するミドルウェア
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization,If-Match,Accept-Language")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
+		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
 		next.ServeHTTP(w, r)
 	})
 }
@@ -26,63 +40,86 @@ func (app *application) checkToken(next http.Handler) http.Handler {
 		// キャッシュを行う際、データを一意に特定するためにURI以外に"Authorization"を利用する
 		w.Header().Add("Vary", "Authorization")
 
-		// Authorizationヘッダーの値(Bearer ~)を取得する
-		authHeader := r.Header.Get("Authorization")
-
-		if authHeader == "" {
-			// could set an anonymous user
-		}
-
-		// ["Bearer", "~"]を返す
-		headerParts := strings.Split(authHeader, " ")
-		if len(headerParts) != 2 {
-			app.errorJSON(w, errors.New("invalid auth header"))
-			return
-		}
-
-		if headerParts[0] != "Bearer" {
-			app.errorJSON(w, errors.New("unauthorized - no bearer"))
-			return
-		}
-
-		// ~(JWTトークン)を取得する
-		token := headerParts[1]
-
-		// 取得したトークンが照合できたらclaimsを返す
-		claims, err := jwt.HMACCheck([]byte(token), []byte(app.config.jwt.secret))
+		userID, status, err := app.authenticate(r)
 		if err != nil {
-			app.errorJSON(w, errors.New("unauthorized - failed hmac check"), http.StatusForbidden)
+			app.errorJSON(w, err, status)
 			return
 		}
 
-		// 期限内かどうかを確認する
-		if !claims.Valid(time.Now()) {
-			app.errorJSON(w, errors.New("unauthorized - token expired"), http.StatusForbidden)
-			return
-		}
+		log.Println("Valid user:", userID)
 
-		// 想定利用者を確認する
-		if !claims.AcceptAudience("mydomain.com") {
-			app.errorJSON(w, errors.New("unauthorized - invalid audience"), http.StatusForbidden)
-			return
-		}
+		// ここまでエラーにならなければOK(userIDをハンドラーに渡す)
+		ctx := context.WithValue(r.Context(), userIDContextKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		// tokenの発行者を確認する
-		if claims.Issuer != "mydomain.com" {
-			app.errorJSON(w, errors.New("unauthorized - invalid issuer"), http.StatusForbidden)
+// トークンがあれば検証してuserIDを渡すミドルウェア(なければ匿名のまま通す)
+func (app *application) optionalToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ユーザーごとにレスポンスが変わる
+		w.Header().Add("Vary", "Authorization")
+
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		// 認証したuserIDを返す
-		userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+		userID, status, err := app.authenticate(r)
 		if err != nil {
-			app.errorJSON(w, errors.New("unauthorized"), http.StatusForbidden)
+			app.errorJSON(w, err, status)
 			return
 		}
 
-		log.Println("Valid user:", userID)
-
-		// ここまでエラーにならなければOK
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), userIDContextKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
-}
\ No newline at end of file
+}
+
+// Authorizationヘッダーのトークンを検証してuserIDを返す(エラーの場合は返すステータスコードも返す)
+func (app *application) authenticate(r *http.Request) (int, int, error) {
+	// Authorizationヘッダーの値(Bearer ~)を取得する
+	authHeader := r.Header.Get("Authorization")
+
+	// ["Bearer", "~"]を返す
+	headerParts := strings.Split(authHeader, " ")
+	if len(headerParts) != 2 {
+		return 0, http.StatusBadRequest, errors.New("invalid auth header")
+	}
+
+	if headerParts[0] != "Bearer" {
+		return 0, http.StatusBadRequest, errors.New("unauthorized - no bearer")
+	}
+
+	// ~(JWTトークン)を取得する
+	token := headerParts[1]
+
+	// 取得したトークンが照合できたらclaimsを返す
+	claims, err := jwt.HMACCheck([]byte(token), []byte(app.config.jwt.secret))
+	if err != nil {
+		return 0, http.StatusForbidden, errors.New("unauthorized - failed hmac check")
+	}
+
+	// 期限内かどうかを確認する
+	if !claims.Valid(time.Now()) {
+		return 0, http.StatusForbidden, errors.New("unauthorized - token expired")
+	}
+
+	// 想定利用者を確認する
+	if !claims.AcceptAudience("mydomain.com") {
+		return 0, http.StatusForbidden, errors.New("unauthorized - invalid audience")
+	}
+
+	// tokenの発行者を確認する
+	if claims.Issuer != "mydomain.com" {
+		return 0, http.StatusForbidden, errors.New("unauthorized - invalid issuer")
+	}
+
+	// 認証したuserIDを返す
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, http.StatusForbidden, errors.New("unauthorized")
+	}
+
+	return int(userID), http.StatusOK, nil
+}
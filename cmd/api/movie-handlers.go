@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -15,6 +16,7 @@ import (
 type jsonResp struct {
 	OK bool `json:"ok"`
 	Message string `json:"message"`
+	ID int `json:"id,omitempty"`
 }
 
 func (app *application) getOneMovie(w http.ResponseWriter, r *http.Request) {
@@ -31,7 +33,7 @@ func (app *application) getOneMovie(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 指定したidのデータを取得する
-	movie, err := app.models.DB.Get(id)
+	movie, err := app.store(r).Get(id)
 	if err != nil {
 		app.errorJSON(w, err)
 		return
@@ -50,30 +52,115 @@ func (app *application) getOneMovie(w http.ResponseWriter, r *http.Request) {
 	// 	UpdatedAt: time.Now(),
 	// }
 
+	err = app.prepareMovies(r, movie)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	// 監督・出演者などのcredits
+	credits, err := app.models.DB.Credits(id)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	// 更新時にIf-Matchで送り返してもらうversion
+	w.Header().Set("ETag", movieETag(movie.Version))
+
 	// レスポンスをJSONで返す
-	err = app.writeJSON(w, http.StatusOK, movie, "movie")
+	err = app.writeEnvelope(w, http.StatusOK, map[string]interface{}{
+		"movie": movie,
+		"credits": credits,
+	})
 	if err != nil {
 		app.errorJSON(w, err)
 		return 
 	}
 }
 
+// レスポンスを返す前に、movieにposter_urlとユーザーごとの情報をセットし、リクエストの言語に翻訳してregionの公開日と年齢制限にする
+func (app *application) prepareMovies(r *http.Request, movies ...*models.Movie) error {
+	for _, movie := range movies {
+		movie.PosterURL = posterURL(movie.Poster)
+	}
+
+	locales, err := requestLocales(r)
+	if err != nil {
+		return err
+	}
+	err = app.models.DB.LocalizeMovies(movies, locales)
+	if err != nil {
+		return err
+	}
+	region, err := requestRegion(r)
+	if err != nil {
+		return err
+	}
+	err = app.models.DB.ApplyRegion(movies, region)
+	if err != nil {
+		return err
+	}
+	err = app.models.DB.LoadCollections(movies)
+	if err != nil {
+		return err
+	}
+	err = app.models.DB.LoadTags(movies)
+	if err != nil {
+		return err
+	}
+
+	return app.markWatchlist(r, movies...)
+}
+
+// ページをmovies, next_cursor, has_moreのJSONで返す
+func (app *application) writeMoviePage(w http.ResponseWriter, page *models.MoviePage) {
+	err := app.writeEnvelope(w, http.StatusOK, map[string]interface{}{
+		"movies": page.Movies,
+		"next_cursor": page.NextCursor,
+		"has_more": page.HasMore,
+	})
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
 func (app *application) getAllMovies(w http.ResponseWriter, r *http.Request) {
-	movies, err := app.models.DB.All()
+	filter, err := app.readMovieFilter(r, "lang", "region")
 	if err != nil {
 		app.errorJSON(w, err)
-		return 
+		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, movies, "movies")
+	page, err := app.store(r).List(filter)
 	if err != nil {
 		app.errorJSON(w, err)
 		return 
 	}
+
+	err = app.prepareMovies(r, page.Movies...)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	app.writeMoviePage(w, page)
 }
 
 func (app *application) getAllGenres(w http.ResponseWriter, r *http.Request) {
-	genres, err := app.models.DB.GenresAll()
+	genres, err := app.store(r).GenresAll()
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	locales, err := requestLocales(r)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	err = app.models.DB.LocalizeGenres(genres, locales)
 	if err != nil {
 		app.errorJSON(w, err)
 		return
@@ -95,17 +182,27 @@ func (app *application) getAllMoviesByGenre(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	movies, err := app.models.DB.All(genreID)
+	filter, err := app.readMovieFilter(r, "lang", "region")
 	if err != nil {
 		app.errorJSON(w, err)
-		return 
+		return
 	}
+	// genresの条件とは別に、パスのgenreを必ず含める
+	filter.RequiredGenreID = genreID
 
-	err = app.writeJSON(w, http.StatusOK, movies, "movies")
+	page, err := app.store(r).List(filter)
 	if err != nil {
 		app.errorJSON(w, err)
 		return 
 	}
+
+	err = app.prepareMovies(r, page.Movies...)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	app.writeMoviePage(w, page)
 }
 
 func (app *application) deleteMovie(w http.ResponseWriter, r *http.Request) {
@@ -119,11 +216,12 @@ func (app *application) deleteMovie(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// データの削除処理を行う
-	err = app.models.DB.DeleteMovie(id)
+	err = app.models.DB.DeleteMovie(id, userIDFromContext(r))
 	if err != nil {
 		app.errorJSON(w, err)
 		return
 	}
+	app.invalidateSimilar()
 
 	ok := jsonResp{
 		OK: true,
@@ -146,6 +244,106 @@ type MoviePayload struct {
 	Runtime string `json:"runtime"`
 	Rating string `json:"rating"`
 	MPAARating string `json:"mpaa_rating"`
+	// genreのidの一覧(省略した場合は変更しない、空の配列ならすべて外す)
+	Genres []int `json:"genres"`
+	// タグ名の一覧(省略した場合は変更しない、空の配列ならすべて外す)
+	Tags []string `json:"tags"`
+	// 更新時に必要(If-Matchヘッダーでもよい)
+	Version json.Number `json:"version"`
+}
+
+var errVersionRequired = errors.New("updates require an If-Match header or a version field")
+
+// 弱いETagは強い比較で一致しないので412にする
+var errWeakETag = errors.New("If-Match does not match: weak ETags are never equal to the movie's version")
+
+var errInvalidIfMatch = errors.New("If-Match must be * or the ETag returned by GET /v1/movie/:id")
+
+// versionをETagの形式("3")にする
+func movieETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// "3"や W/"3" -> 3, 弱いETagか
+func parseMovieETag(tag string) (int, bool, error) {
+	weak := strings.HasPrefix(tag, "W/")
+	v, err := strconv.Unquote(strings.TrimPrefix(tag, "W/"))
+	if err != nil {
+		return 0, weak, errInvalidIfMatch
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version <= 0 {
+		return 0, weak, errInvalidIfMatch
+	}
+	return version, weak, nil
+}
+
+// If-Matchヘッダーかpayloadのversionから、クライアントが読み込んだversionを返す
+// If-Match: * は存在するmovieなら何にでも一致するので、現在のversion(current)を返す
+func readMovieVersion(r *http.Request, payload MoviePayload, current int) (int, error) {
+	version := 0
+	wildcard := false
+
+	if ifMatch := strings.TrimSpace(r.Header.Get("If-Match")); ifMatch == "*" {
+		wildcard = true
+		version = current
+	} else if ifMatch != "" {
+		// カンマ区切りのETagのいずれかに一致すればよい
+		weak := false
+		for _, tag := range strings.Split(ifMatch, ",") {
+			v, w, err := parseMovieETag(strings.TrimSpace(tag))
+			if err != nil {
+				return 0, err
+			}
+			if w {
+				weak = true
+				continue
+			}
+			if version == 0 || v == current {
+				version = v
+			}
+		}
+		if version == 0 && weak {
+			return 0, errWeakETag
+		}
+	}
+
+	if payload.Version != "" {
+		v, err := strconv.Atoi(payload.Version.String())
+		if err != nil || v <= 0 {
+			return 0, errors.New("version must be a positive integer")
+		}
+		if version != 0 && !wildcard && v != version {
+			return 0, errors.New("If-Match and version do not match")
+		}
+		version = v
+	}
+
+	if version == 0 {
+		return 0, errVersionRequired
+	}
+
+	return version, nil
+}
+
+// 409と現在のmovieを返す(クライアントが差分を確認して再送できるようにする)
+func (app *application) writeVersionConflict(w http.ResponseWriter, id int) {
+	current, err := app.models.DB.Primary().Get(id)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", movieETag(current.Version))
+
+	err = app.writeEnvelope(w, http.StatusConflict, map[string]interface{}{
+		"error": map[string]string{"message": models.ErrVersionConflict.Error()},
+		"movie": current,
+	})
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
 }
 
 func (app *application) editMovie(w http.ResponseWriter, r *http.Request) {
@@ -166,9 +364,28 @@ func (app *application) editMovie(w http.ResponseWriter, r *http.Request) {
 	// データ更新時にUpdatedAtを更新する
 	if payload.ID != "0" {
 		id, _ := strconv.Atoi(payload.ID)
-		m, _ := app.models.DB.Get(id)
+		m, err := app.store(r).Get(id)
+		if err != nil {
+			app.errorJSON(w, err)
+			return
+		}
 		movie = *m
 		movie.UpdatedAt = time.Now()
+
+		// 読み込んだあとにほかの更新が入っていないかを確認する
+		movie.Version, err = readMovieVersion(r, payload, m.Version)
+		if err != nil {
+			if errors.Is(err, errVersionRequired) {
+				app.errorJSON(w, err, http.StatusPreconditionRequired)
+				return
+			}
+			if errors.Is(err, errWeakETag) {
+				app.errorJSON(w, err, http.StatusPreconditionFailed)
+				return
+			}
+			app.errorJSON(w, err)
+			return
+		}
 	}
 
 	// payloadの各プロパティの型を変換してmovieに代入する
@@ -180,29 +397,38 @@ func (app *application) editMovie(w http.ResponseWriter, r *http.Request) {
 	movie.Runtime, _ = strconv.Atoi(payload.Runtime)
 	movie.Rating, _ = strconv.Atoi(payload.Rating)
 	movie.MPAARating = payload.MPAARating
+	movie.GenreIDs = payload.Genres
+	movie.TagNames = payload.Tags
 	movie.CreatedAt = time.Now()
 	movie.UpdatedAt = time.Now()
 
-	// if movie.Poster == "" {
-	// 	movie = getPoster(movie)
-	// }
-
 	if movie.ID == 0 { // データ作成時の処理
-		err = app.models.DB.InsertMovie(movie)
+		movie.ID, err = app.models.DB.InsertMovie(movie, userIDFromContext(r))
 		if err != nil {
 			app.errorJSON(w, err)
 			return
 		}
+		w.Header().Set("ETag", movieETag(1))
+
+		// ポスターや概要などを外部のプロバイダーで探す(結果は提案として確認を待つ)
+		app.enrichMovieAsync(movie.ID)
 	} else { // データ更新時の処理
-		err = app.models.DB.UpdateMovie(movie)
+		err = app.models.DB.UpdateMovie(movie, userIDFromContext(r))
+		if errors.Is(err, models.ErrVersionConflict) {
+			app.writeVersionConflict(w, movie.ID)
+			return
+		}
 		if err != nil {
 			app.errorJSON(w, err)
 			return
 		}
+		w.Header().Set("ETag", movieETag(movie.Version+1))
 	}
+	app.invalidateSimilar()
 	
 	ok := jsonResp{
 		OK: true,
+		ID: movie.ID,
 	}
 
 	err = app.writeJSON(w, http.StatusOK, ok, "response")
@@ -211,62 +437,3 @@ func (app *application) editMovie(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
-
-// func getPoster(movie models.Movie) models.Movie {
-// 	type TheMovieDB struct {
-// 		Page int `json:"page"`
-// 		Results []struct {
-// 			Adult bool `json:adult`
-// 			BackdropPath string `json:"backdrop_path"`
-// 			GenreIds []int `json:"genre_ids"`
-// 			ID int `json:"id"`
-// 			OriginalLanguage string `json:"original_language"`
-// 			OriginalTitle string `json:"original_title"`
-// 			Overview string `json:"overview"`
-// 			Popularity float64 `json:"popularity"`
-// 			PosterPath string `json:"poster_path"`
-// 			ReleaseDate string `json:"release_date"`
-// 			Title string `json:"title"`
-// 			Video bool `json:"video"`
-// 			VoteAverage float64 `json:"vote_average"`
-// 			VoteCount int `json:"vote_count"`
-// 		} `json:"results"`
-// 		TotalPages int `json:"total_pages"`
-// 		TotalResults int `json:"total_results"`
-// 	}
-
-// 	client := &http.Client{}
-// 	key := "" //APIキー
-// 	theUrl := "https://api.themoviedb.org/3/search/movie?api_key="
-// 	log.Println(theUrl + key + "&query=" + url.QueryEscape(movie.Title))
-
-// 	req, err := http.NewRequest("GET", theUrl+key+"&query="+url.QueryEscape(movie.Title), nil)
-// 	if err != nil {
-// 		log.Println(err)
-// 		return movie
-// 	}
-
-// 	req.Header.Add("Accept", "application/json")
-// 	req.Header.Add("Content-Type", "application/json")
-// 	resp, err := client.Do(req)
-// 	if err != nil {
-// 		log.Println(err)
-// 		return movie
-// 	}
-// 	defer resp.Body.Close()
-// 	bodyBytes, err := io.ReadAll(resp.Body)
-// 	if err != nil {
-// 		log.Println(err)
-// 		return movie
-// 	}
-
-// 	var responseObject TheMovieDB
-	
-// 	json.Unmarshal(bodyBytes, &responseObject)
-
-// 	if len(responseObject.Results) > 0 {
-// 		movie.Poster = responseObject.Results[0].PosterPath
-// 	}
-
-// 	return movie 
-// }
\ No newline at end of file
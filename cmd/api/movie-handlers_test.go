@@ -0,0 +1,573 @@
+package main
+
+import (
+	"backend/models"
+	"backend/storage"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pascaldekloe/jwt"
+)
+
+const testSecret = "test-secret"
+
+// メモリストアの初期データで起動したapplicationを返す
+func newTestApplication(t *testing.T) (*application, *models.MemoryModel) {
+	t.Helper()
+
+	store := models.NewMemoryModel()
+	err := store.Seed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config
+	cfg.env = "test"
+	cfg.db.store = "memory"
+	cfg.jwt.secret = testSecret
+	cfg.poster.maxBytes = 1 << 20
+	cfg.similar.ttl = time.Hour
+
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application{
+		config: cfg,
+		logger: log.New(io.Discard, "", 0),
+		models: models.NewMemoryModels(store),
+		blobs: blobs,
+	}
+
+	return app, store
+}
+
+// Signinと同じclaimsでトークンを発行する
+func testToken(t *testing.T, userID int) string {
+	t.Helper()
+
+	var claims jwt.Claims
+	claims.Subject = fmt.Sprint(userID)
+	claims.Issued = jwt.NewNumericTime(time.Now())
+	claims.NotBefore = jwt.NewNumericTime(time.Now())
+	claims.Expires = jwt.NewNumericTime(time.Now().Add(time.Hour))
+	claims.Issuer = "mydomain.com"
+	claims.Audiences = []string{"mydomain.com"}
+
+	token, err := claims.HMACSign(jwt.HS256, []byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(token)
+}
+
+// ルーター経由でリクエストを送り、ステータスコードとJSONボディを返す
+func doRequest(t *testing.T, app *application, method, url, body, token string) (int, map[string]json.RawMessage) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	resp := make(map[string]json.RawMessage)
+	err := json.Unmarshal(rr.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatalf("%s %s: invalid JSON response %q: %v", method, url, rr.Body.String(), err)
+	}
+
+	return rr.Code, resp
+}
+
+func decode(t *testing.T, raw json.RawMessage, v interface{}) {
+	t.Helper()
+
+	err := json.Unmarshal(raw, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func itoa(i int) string {
+	return fmt.Sprint(i)
+}
+
+func TestGetOneMovie(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/movie/1", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	var movie models.Movie
+	decode(t, resp["movie"], &movie)
+
+	if movie.Title != "The Shawshank Redemption" {
+		t.Errorf("title = %q", movie.Title)
+	}
+	if len(movie.MovieGenre) != 2 {
+		t.Errorf("genres = %v, want 2 genres", movie.MovieGenre)
+	}
+
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/movie/999", "", "")
+	if status != http.StatusBadRequest {
+		t.Errorf("missing movie: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if _, ok := resp["error"]; !ok {
+		t.Errorf("missing movie: no error in response")
+	}
+}
+
+func TestGetAllMovies(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/movies", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	var movies []models.Movie
+	decode(t, resp["movies"], &movies)
+
+	if len(movies) != 4 {
+		t.Fatalf("got %d movies, want 4", len(movies))
+	}
+	if movies[0].Title != "American Psycho" {
+		t.Errorf("movies are not ordered by title: first = %q", movies[0].Title)
+	}
+}
+
+func TestGetAllMoviesPagination(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	var titles []string
+	url := "/v1/movies?limit=3"
+
+	for i := 0; i < 3; i++ {
+		status, resp := doRequest(t, app, http.MethodGet, url, "", "")
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, want %d", status, http.StatusOK)
+		}
+
+		var movies []models.Movie
+		var next string
+		var hasMore bool
+		decode(t, resp["movies"], &movies)
+		decode(t, resp["next_cursor"], &next)
+		decode(t, resp["has_more"], &hasMore)
+
+		for _, movie := range movies {
+			titles = append(titles, movie.Title)
+		}
+
+		if !hasMore {
+			break
+		}
+		url = "/v1/movies?limit=3&cursor=" + next
+	}
+
+	want := []string{"American Psycho", "The Dark Knight", "The Godfather", "The Shawshank Redemption"}
+	if strings.Join(titles, ",") != strings.Join(want, ",") {
+		t.Errorf("paged titles = %v, want %v", titles, want)
+	}
+
+	for _, bad := range []string{"/v1/movies?limit=0", "/v1/movies?limit=abc", "/v1/movies?cursor=!!!"} {
+		status, _ := doRequest(t, app, http.MethodGet, bad, "", "")
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", bad, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetAllMoviesFilters(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	tests := []struct {
+		url string
+		want []string
+	}{
+		{"/v1/movies?year_min=1990&year_max=2005", []string{"American Psycho", "The Shawshank Redemption"}},
+		{"/v1/movies?released_after=2000-01-01&sort=-release_date", []string{"The Dark Knight", "American Psycho"}},
+		{"/v1/movies?runtime_min=150", []string{"The Dark Knight", "The Godfather"}},
+		{"/v1/movies?mpaa_rating=PG-13,G", []string{"The Dark Knight"}},
+		{"/v1/movies?genres=3,6", []string{"American Psycho", "The Dark Knight"}},
+		{"/v1/movies?genres=1,2&genre_match=all&sort=-year", []string{"The Shawshank Redemption", "The Godfather"}},
+		{"/v1/movies?rating_min=5&sort=-runtime&limit=2", []string{"The Godfather", "The Dark Knight"}},
+	}
+
+	for _, tt := range tests {
+		status, resp := doRequest(t, app, http.MethodGet, tt.url, "", "")
+		if status != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", tt.url, status, http.StatusOK)
+			continue
+		}
+
+		var movies []models.Movie
+		decode(t, resp["movies"], &movies)
+
+		var titles []string
+		for _, movie := range movies {
+			titles = append(titles, movie.Title)
+		}
+		if strings.Join(titles, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: got %v, want %v", tt.url, titles, tt.want)
+		}
+	}
+
+	// 降順の並び替えでもカーソルで続きを取得できる
+	_, resp := doRequest(t, app, http.MethodGet, "/v1/movies?sort=-year&limit=3", "", "")
+	var next string
+	decode(t, resp["next_cursor"], &next)
+
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/movies?sort=-year&limit=3&cursor="+next, "", "")
+	var movies []models.Movie
+	decode(t, resp["movies"], &movies)
+	if len(movies) != 1 || movies[0].Title != "The Godfather" {
+		t.Errorf("second page sorted by -year = %+v, want only The Godfather", movies)
+	}
+
+	bad := []string{
+		"/v1/movies?color=red",
+		"/v1/movies?sort=description",
+		"/v1/movies?mpaa_rating=X",
+		"/v1/movies?year_min=2010&year_max=2000",
+		"/v1/movies?released_after=yesterday",
+		"/v1/movies?genre_match=some",
+		"/v1/movies?sort=year&cursor=" + next,
+	}
+	for _, url := range bad {
+		status, _ := doRequest(t, app, http.MethodGet, url, "", "")
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", url, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGetAllMoviesByGenre(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	// 3 = Action
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/movies/3", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	var movies []models.Movie
+	decode(t, resp["movies"], &movies)
+
+	if len(movies) != 1 || movies[0].Title != "The Dark Knight" {
+		t.Errorf("got %+v, want only The Dark Knight", movies)
+	}
+}
+
+func TestGetAllMoviesByGenreWithGenres(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	// パスのgenre(3 = Action)はgenresとのorではなく、必ず満たす条件
+	tests := []struct {
+		url string
+		want []string
+	}{
+		{"/v1/movies/3?genres=1", nil},
+		{"/v1/movies/3?genres=1,2", []string{"The Dark Knight"}},
+		{"/v1/movies/3?genres=1,2&genre_match=all", nil},
+		{"/v1/movies/3?genres=2,4&genre_match=all", []string{"The Dark Knight"}},
+	}
+
+	for _, tt := range tests {
+		status, resp := doRequest(t, app, http.MethodGet, tt.url, "", "")
+		if status != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tt.url, status, http.StatusOK)
+		}
+
+		var movies []models.Movie
+		decode(t, resp["movies"], &movies)
+
+		var titles []string
+		for _, movie := range movies {
+			titles = append(titles, movie.Title)
+		}
+		if strings.Join(titles, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: got %v, want %v", tt.url, titles, tt.want)
+		}
+	}
+}
+
+func TestGetAllGenres(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/genres", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	var genres []models.Genre
+	decode(t, resp["genres"], &genres)
+
+	if len(genres) != 9 {
+		t.Fatalf("got %d genres, want 9", len(genres))
+	}
+	if genres[0].GenreName != "Action" {
+		t.Errorf("genres are not ordered by name: first = %q", genres[0].GenreName)
+	}
+}
+
+func TestEditMovie(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	body := `{"id":"0","title":"Inception","description":"A thief who steals corporate secrets","release_date":"2010-07-16","runtime":"148","rating":"5","mpaa_rating":"PG-13","genres":[3,5]}`
+
+	status, _ := doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", body, "")
+	if status != http.StatusBadRequest {
+		t.Errorf("without token: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	status, resp := doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("insert: status = %d, want %d", status, http.StatusOK)
+	}
+
+	var result jsonResp
+	decode(t, resp["response"], &result)
+	if result.ID != 5 {
+		t.Fatalf("insert returned id %d, want 5", result.ID)
+	}
+
+	movie, err := store.Get(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "Inception" || movie.Year != 2010 || movie.Runtime != 148 {
+		t.Errorf("inserted movie = %+v", movie)
+	}
+	if fmt.Sprint(movie.GenreIDs) != "[3 5]" {
+		t.Errorf("inserted genres = %v, want [3 5]", movie.GenreIDs)
+	}
+
+	// genresを省略した場合は紐づけを変更しない
+	body = `{"id":"5","title":"Inception (2010)","description":"A thief who steals corporate secrets","release_date":"2010-07-16","runtime":"148","rating":"4","mpaa_rating":"PG-13","version":"1"}`
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("update: status = %d, want %d", status, http.StatusOK)
+	}
+
+	movie, err = store.Get(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "Inception (2010)" || movie.Rating != 4 {
+		t.Errorf("updated movie = %+v", movie)
+	}
+	if fmt.Sprint(movie.GenreIDs) != "[3 5]" {
+		t.Errorf("genres after update without genres = %v, want [3 5]", movie.GenreIDs)
+	}
+
+	body = `{"id":"5","title":"Inception (2010)","release_date":"2010-07-16","genres":[7],"version":2}`
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("update genres: status = %d, want %d", status, http.StatusOK)
+	}
+
+	movie, err = store.Get(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(movie.GenreIDs) != "[7]" {
+		t.Errorf("replaced genres = %v, want [7]", movie.GenreIDs)
+	}
+
+	body = `{"id":"5","title":"Inception (2010)","release_date":"2010-07-16","genres":[7,999],"version":3}`
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", body, token)
+	if status != http.StatusBadRequest {
+		t.Errorf("unknown genre: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	movie, err = store.Get(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(movie.GenreIDs) != "[7]" {
+		t.Errorf("genres after failed update = %v, want [7]", movie.GenreIDs)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", `{"id":"999","title":"Nothing"}`, token)
+	if status != http.StatusBadRequest {
+		t.Errorf("update missing movie: status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestDeleteMovie(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	status, _ := doRequest(t, app, http.MethodGet, "/v1/admin/deletemovie/1", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	_, err := store.Get(1)
+	if err == nil {
+		t.Errorf("movie 1 still exists after delete")
+	}
+
+	movies, err := store.All(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movies) != 2 {
+		t.Errorf("got %d drama movies after delete, want 2", len(movies))
+	}
+
+	// ゴミ箱に入っていて元に戻せる
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/admin/trash", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("trash: status = %d, want %d", status, http.StatusOK)
+	}
+	var trash []models.Movie
+	decode(t, resp["movies"], &trash)
+	if len(trash) != 1 || trash[0].ID != 1 || trash[0].DeletedAt == nil {
+		t.Fatalf("trash = %+v, want only movie 1", trash)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/trash/1/restore", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("restore: status = %d, want %d", status, http.StatusOK)
+	}
+	if _, err := store.Get(1); err != nil {
+		t.Errorf("movie 1 not restored: %v", err)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/trash/1/restore", "", token)
+	if status != http.StatusNotFound {
+		t.Errorf("restore twice: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	// 保存期間を過ぎたものだけ完全に削除する
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/admin/deletemovie/2", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("delete: status = %d, want %d", status, http.StatusOK)
+	}
+
+	n, _, err := store.PurgeDeleted(time.Now().Add(-time.Hour))
+	if err != nil || n != 0 {
+		t.Errorf("purge before an hour ago = %d, %v; want 0", n, err)
+	}
+
+	n, _, err = store.PurgeDeleted(time.Now().Add(time.Second))
+	if err != nil || n != 1 {
+		t.Errorf("purge now = %d, %v; want 1", n, err)
+	}
+
+	remaining, _ := store.Trash()
+	if len(remaining) != 0 {
+		t.Errorf("trash after purge = %+v, want empty", remaining)
+	}
+}
+
+func TestEditMovieVersion(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	// If-Matchつきで送る
+	update := func(ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/admin/editmovie", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/movie/3", nil)
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	etag := rr.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("ETag = %q, want %q", etag, `"1"`)
+	}
+
+	body := `{"id":"3","title":"The Dark Knight","release_date":"2008-07-18","runtime":"152","rating":"4","mpaa_rating":"PG-13"}`
+
+	if rr := update("", body); rr.Code != http.StatusPreconditionRequired {
+		t.Errorf("without version: status = %d, want %d", rr.Code, http.StatusPreconditionRequired)
+	}
+	if rr := update(`W/"x"`, body); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid If-Match: status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	// 弱いETagは強い比較で一致しない
+	if rr := update(`W/"1"`, body); rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("weak If-Match: status = %d, want %d", rr.Code, http.StatusPreconditionFailed)
+	}
+
+	rr = update(etag, body)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if rr.Header().Get("ETag") != `"2"` {
+		t.Errorf("ETag after update = %q, want %q", rr.Header().Get("ETag"), `"2"`)
+	}
+
+	// 古いETagでの更新は現在のmovieとともに409を返す
+	rr = update(etag, `{"id":"3","title":"Stale","release_date":"2008-07-18"}`)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("stale update: status = %d, want %d", rr.Code, http.StatusConflict)
+	}
+	var resp struct {
+		Movie models.Movie `json:"movie"`
+	}
+	err := json.Unmarshal(rr.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Movie.Version != 2 || resp.Movie.Rating != 4 || rr.Header().Get("ETag") != `"2"` {
+		t.Errorf("conflict response = %+v, ETag %q", resp.Movie, rr.Header().Get("ETag"))
+	}
+
+	movie, err := store.Get(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "The Dark Knight" {
+		t.Errorf("stale update was applied: title = %q", movie.Title)
+	}
+
+	// payloadのversionでもよい(If-Matchと異なる場合はエラー)
+	if rr := update(`"2"`, `{"id":"3","title":"The Dark Knight","release_date":"2008-07-18","version":1}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("If-Match and version differ: status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := update("", `{"id":"3","title":"The Dark Knight","release_date":"2008-07-18","version":2}`); rr.Code != http.StatusOK {
+		t.Errorf("update with payload version: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	// *は現在のversionに一致する
+	rr = update("*", body)
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"4"` {
+		t.Errorf("If-Match *: status = %d, ETag %q, want %d, %q", rr.Code, rr.Header().Get("ETag"), http.StatusOK, `"4"`)
+	}
+	// ETagのリストはいずれかに一致すればよい
+	if rr := update(`W/"4", "1", "4"`, body); rr.Code != http.StatusOK {
+		t.Errorf("If-Match list: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if rr := update("*", `{"id":"99","title":"Missing","release_date":"2008-07-18"}`); rr.Code == http.StatusOK {
+		t.Errorf("If-Match * on a missing movie: status = %d", rr.Code)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"backend/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type PersonPayload struct {
+	Name string `json:"name"`
+	Biography string `json:"biography"`
+}
+
+type CreditPayload struct {
+	PersonID int `json:"person_id"`
+	Role string `json:"role"`
+	CharacterName string `json:"character_name"`
+	BillingOrder int `json:"billing_order"`
+}
+
+// 人とcreditの操作で返すエラーのステータスコード
+func personErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrPersonInUse):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (app *application) getAllPeople(w http.ResponseWriter, r *http.Request) {
+	people, err := app.models.DB.People()
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, people, "people")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) getOnePerson(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	person, err := app.models.DB.GetPerson(id)
+	if err != nil {
+		app.errorJSON(w, err, personErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, person, "person")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// 人が参加したmovieを公開日の新しい順に返す
+func (app *application) getFilmography(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	person, err := app.models.DB.GetPerson(id)
+	if err != nil {
+		app.errorJSON(w, err, personErrorStatus(err))
+		return
+	}
+
+	filmography, err := app.models.DB.Filmography(id)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeEnvelope(w, http.StatusOK, map[string]interface{}{
+		"person": person,
+		"filmography": filmography,
+	})
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) createPerson(w http.ResponseWriter, r *http.Request) {
+	var payload PersonPayload
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	person := models.Person{
+		Name: strings.TrimSpace(payload.Name),
+		Biography: payload.Biography,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	person.ID, err = app.models.DB.InsertPerson(person)
+	if err != nil {
+		app.errorJSON(w, err, personErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, person, "person")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) updatePerson(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload PersonPayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	person := models.Person{
+		ID: id,
+		Name: payload.Name,
+		Biography: payload.Biography,
+		UpdatedAt: time.Now(),
+	}
+
+	err = app.models.DB.UpdatePerson(person)
+	if err != nil {
+		app.errorJSON(w, err, personErrorStatus(err))
+		return
+	}
+
+	ok := jsonResp{
+		OK: true,
+		ID: id,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// creditが残っている人は削除できない
+func (app *application) deletePerson(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	err = app.models.DB.DeletePerson(id)
+	if err != nil {
+		app.errorJSON(w, err, personErrorStatus(err))
+		return
+	}
+
+	ok := jsonResp{
+		OK: true,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// movieのcreditsを{"credits": [...]}の内容で置き換える
+func (app *application) updateCredits(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload struct {
+		Credits []CreditPayload `json:"credits"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	credits := []models.Credit{}
+	for _, c := range payload.Credits {
+		credits = append(credits, models.Credit{
+			PersonID: c.PersonID,
+			Role: strings.ToLower(strings.TrimSpace(c.Role)),
+			CharacterName: strings.TrimSpace(c.CharacterName),
+			BillingOrder: c.BillingOrder,
+		})
+	}
+
+	err = app.models.DB.ReplaceCredits(id, credits)
+	if err != nil {
+		app.errorJSON(w, err, personErrorStatus(err))
+		return
+	}
+	app.invalidateSimilar()
+
+	saved, err := app.models.DB.Credits(id)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, saved, "credits")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
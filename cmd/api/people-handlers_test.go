@@ -0,0 +1,124 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"testing"
+)
+
+func TestMovieCredits(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/movie/3", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	var credits []models.Credit
+	decode(t, resp["credits"], &credits)
+	if len(credits) != 3 || credits[0].Role != "director" || credits[2].CharacterName != "Joker" {
+		t.Errorf("credits of The Dark Knight = %+v", credits)
+	}
+}
+
+func TestPeopleAdmin(t *testing.T) {
+	app, _ := newTestApplication(t)
+	token := testToken(t, 10)
+
+	status, resp := doRequest(t, app, http.MethodPost, "/v1/admin/people", `{"name":" Cillian Murphy "}`, token)
+	if status != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d", status, http.StatusCreated)
+	}
+	var person models.Person
+	decode(t, resp["person"], &person)
+	if person.ID == 0 || person.Name != "Cillian Murphy" {
+		t.Errorf("created person = %+v", person)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/people", `{"name":""}`, token)
+	if status != http.StatusBadRequest {
+		t.Errorf("empty name: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	// 3 = The Dark Knight, 7 = Christopher Nolan, 8 = Christian Bale
+	body := `{"credits":[
+		{"person_id":7,"role":"director"},
+		{"person_id":8,"role":"actor","character_name":"Bruce Wayne","billing_order":1},
+		{"person_id":` + itoa(person.ID) + `,"role":"actor","character_name":"Scarecrow","billing_order":2}
+	]}`
+	status, resp = doRequest(t, app, http.MethodPut, "/v1/admin/movies/3/credits", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("replace credits: status = %d, want %d", status, http.StatusOK)
+	}
+	var credits []models.Credit
+	decode(t, resp["credits"], &credits)
+	if len(credits) != 3 || credits[2].Name != "Cillian Murphy" {
+		t.Errorf("replaced credits = %+v", credits)
+	}
+
+	bad := []string{
+		`{"credits":[{"person_id":999,"role":"actor"}]}`,
+		`{"credits":[{"person_id":7,"role":"caterer"}]}`,
+		`{"credits":[{"person_id":7,"role":"director","character_name":"Himself"}]}`,
+		`{"credits":[{"person_id":7,"role":"director"},{"person_id":7,"role":"director"}]}`,
+	}
+	for _, body := range bad {
+		status, _ := doRequest(t, app, http.MethodPut, "/v1/admin/movies/3/credits", body, token)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/movies/999/credits", `{"credits":[]}`, token)
+	if status != http.StatusNotFound {
+		t.Errorf("credits of missing movie: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/admin/people/"+itoa(person.ID), "", token)
+	if status != http.StatusConflict {
+		t.Errorf("delete credited person: status = %d, want %d", status, http.StatusConflict)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/people/"+itoa(person.ID), `{"name":"Cillian Murphy","biography":"Irish actor"}`, token)
+	if status != http.StatusOK {
+		t.Errorf("update: status = %d, want %d", status, http.StatusOK)
+	}
+
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/people/"+itoa(person.ID), "", "")
+	if status != http.StatusOK {
+		t.Fatalf("get: status = %d, want %d", status, http.StatusOK)
+	}
+	decode(t, resp["person"], &person)
+	if person.Biography != "Irish actor" {
+		t.Errorf("updated person = %+v", person)
+	}
+
+	status, _ = doRequest(t, app, http.MethodGet, "/v1/people/999", "", "")
+	if status != http.StatusNotFound {
+		t.Errorf("missing person: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestFilmography(t *testing.T) {
+	app, _ := newTestApplication(t)
+	token := testToken(t, 10)
+
+	// 8 = Christian Bale(The Dark Knight, American Psycho)
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/people/8/filmography", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	var filmography []models.FilmographyEntry
+	decode(t, resp["filmography"], &filmography)
+	if len(filmography) != 2 || filmography[0].Title != "The Dark Knight" || filmography[1].CharacterName != "Patrick Bateman" {
+		t.Errorf("filmography = %+v", filmography)
+	}
+
+	// ゴミ箱のmovieは含めない
+	doRequest(t, app, http.MethodDelete, "/v1/admin/deletemovie/3", "", token)
+
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/people/8/filmography", "", "")
+	decode(t, resp["filmography"], &filmography)
+	if len(filmography) != 1 || filmography[0].Title != "American Psycho" {
+		t.Errorf("filmography after delete = %+v", filmography)
+	}
+}
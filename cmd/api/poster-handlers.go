@@ -0,0 +1,246 @@
+package main
+
+import (
+	"backend/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// 受け付ける画像の形式と保存するときの拡張子
+var posterTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png": ".png",
+	"image/webp": ".webp",
+}
+
+// ストレージのキー"posters/<name>"のうちURLに使う部分
+const posterKeyPrefix = "posters/"
+
+// ポスターのキーを配信用のURLにする
+func posterURL(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "/v1/posters/" + strings.TrimPrefix(key, posterKeyPrefix)
+}
+
+var errPosterType = errors.New("unsupported poster type (allowed: JPEG, PNG, WebP)")
+
+// ポスターの保存で返すエラーのステータスコード
+func posterErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, errPosterType):
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusInternalServerError
+}
+
+// movieのポスターの画像をストレージに保存してキーを返す(movieはまだ変更しない)
+func (app *application) storePoster(ctx context.Context, id int, data []byte) (string, error) {
+	// 申告されたContent-Typeではなく中身から形式を判定する
+	contentType := http.DetectContentType(data)
+	ext, ok := posterTypes[contentType]
+	if !ok {
+		return "", fmt.Errorf("%w: got %s", errPosterType, contentType)
+	}
+
+	// 中身のハッシュをキーに含めて、URLごとに内容が変わらないようにする(長期間キャッシュできる)
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("%s%d-%x%s", posterKeyPrefix, id, sum[:8], ext)
+
+	err := app.blobs.Put(ctx, key, bytes.NewReader(data), contentType)
+	if err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// 画像を保存してmovieのポスターを差し替える
+func (app *application) savePoster(ctx context.Context, id int, data []byte, userID int) error {
+	key, err := app.storePoster(ctx, id, data)
+	if err != nil {
+		return err
+	}
+
+	old, err := app.models.DB.SetPoster(id, key, userID)
+	if err != nil {
+		app.blobs.Delete(ctx, key)
+		return err
+	}
+	app.deleteOldPoster(ctx, old, key)
+
+	return nil
+}
+
+// multipartの"poster"フィールドの画像をmovieのポスターとして保存する
+func (app *application) uploadPoster(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	_, err = app.store(r).Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	// multipartのヘッダーなどの分だけ余裕をもたせる
+	maxBytes := app.config.poster.maxBytes
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+64<<10)
+
+	err = r.ParseMultipartForm(1 << 20)
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			app.errorJSON(w, fmt.Errorf("poster must not be larger than %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		app.errorJSON(w, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("poster")
+	if err != nil {
+		app.errorJSON(w, errors.New("multipart field poster is required"))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxBytes {
+		app.errorJSON(w, fmt.Errorf("poster must not be larger than %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		app.errorJSON(w, fmt.Errorf("poster must not be larger than %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	err = app.savePoster(r.Context(), id, data, userIDFromContext(r))
+	if err != nil {
+		app.errorJSON(w, err, posterErrorStatus(err))
+		return
+	}
+
+	movie, err := app.store(r).Get(id)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.prepareMovies(r, movie)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, movie, "movie")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) deletePoster(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	old, err := app.models.DB.SetPoster(id, "", userIDFromContext(r))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			app.errorJSON(w, err, http.StatusNotFound)
+			return
+		}
+		app.errorJSON(w, err)
+		return
+	}
+	app.deleteOldPoster(r.Context(), old, "")
+
+	ok := jsonResp{
+		OK: true,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// 差し替える前のポスターを削除する(失敗してもリクエストはエラーにしない)
+func (app *application) deleteOldPoster(ctx context.Context, old, current string) {
+	if old == "" || old == current {
+		return
+	}
+
+	err := app.blobs.Delete(ctx, old)
+	if err != nil {
+		app.logger.Println("delete old poster:", err)
+	}
+}
+
+// ポスター画像を返す(URLごとに内容が変わらないので長期間キャッシュさせる)
+func (app *application) servePoster(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	name := params.ByName("name")
+
+	blob, info, err := app.blobs.Get(r.Context(), posterKeyPrefix+name)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		app.errorJSON(w, errors.New("poster not found"), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	etag := strconv.Quote(name)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, blob)
+}
+
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"backend/models"
+	"backend/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// multipartでポスターをアップロードする
+func uploadPosterRequest(t *testing.T, app *application, movieID int, data []byte, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("poster", "poster.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/movies/"+itoa(movieID)+"/poster", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	return rr
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadPoster(t *testing.T) {
+	app, _ := newTestApplication(t)
+	token := testToken(t, 1)
+	data := testPNG(t)
+
+	rr := uploadPosterRequest(t, app, 1, data, token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload: status %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		Movie struct {
+			PosterURL string `json:"poster_url"`
+		} `json:"movie"`
+	}
+	err := json.Unmarshal(rr.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := resp.Movie.PosterURL
+	if url == "" {
+		t.Fatal("poster_url is empty after upload")
+	}
+
+	status, body := doRequest(t, app, http.MethodGet, "/v1/movie/1", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("get movie: status %d", status)
+	}
+	var movie struct {
+		PosterURL string `json:"poster_url"`
+	}
+	decode(t, body["movie"], &movie)
+	if movie.PosterURL != url {
+		t.Errorf("poster_url = %q, want %q", movie.PosterURL, url)
+	}
+
+	// 配信
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	get := httptest.NewRecorder()
+	app.routes().ServeHTTP(get, req)
+	if get.Code != http.StatusOK {
+		t.Fatalf("serve: status %d", get.Code)
+	}
+	if ct := get.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !bytes.Equal(get.Body.Bytes(), data) {
+		t.Error("served poster differs from the upload")
+	}
+	etag := get.Header().Get("ETag")
+	if etag == "" || get.Header().Get("Cache-Control") == "" {
+		t.Errorf("missing cache headers: %v", get.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("If-None-Match", etag)
+	cached := httptest.NewRecorder()
+	app.routes().ServeHTTP(cached, req)
+	if cached.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, want 304", cached.Code)
+	}
+
+	// 削除すると古い画像も配信されなくなる
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/admin/movies/1/poster", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("delete: status %d", status)
+	}
+	status, _ = doRequest(t, app, http.MethodGet, url, "", "")
+	if status != http.StatusNotFound {
+		t.Errorf("serve deleted poster: status %d, want 404", status)
+	}
+}
+
+func TestUploadPosterRejects(t *testing.T) {
+	app, _ := newTestApplication(t)
+	token := testToken(t, 1)
+
+	rr := uploadPosterRequest(t, app, 1, []byte("not an image at all"), token)
+	if rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("text file: status %d, want 415", rr.Code)
+	}
+
+	big := append(testPNG(t), make([]byte, app.config.poster.maxBytes)...)
+	rr = uploadPosterRequest(t, app, 1, big, token)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversize: status %d, want 413", rr.Code)
+	}
+
+	rr = uploadPosterRequest(t, app, 999, testPNG(t), token)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("unknown movie: status %d, want 404", rr.Code)
+	}
+
+	rr = uploadPosterRequest(t, app, 1, testPNG(t), "")
+	if rr.Code == http.StatusOK {
+		t.Error("upload without a token succeeded")
+	}
+}
+
+func TestPurgeDeletesPoster(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 1)
+
+	rr := uploadPosterRequest(t, app, 1, testPNG(t), token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload: status %d: %s", rr.Code, rr.Body.String())
+	}
+	movie, err := store.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := movie.Poster
+
+	status, _ := doRequest(t, app, http.MethodDelete, "/v1/admin/deletemovie/1", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("delete: status = %d, want %d", status, http.StatusOK)
+	}
+	// ゴミ箱にある間は画像を残す
+	if _, _, err := app.blobs.Get(context.Background(), key); err != nil {
+		t.Fatalf("poster removed before purge: %v", err)
+	}
+
+	n, err := purgeTrash(app.models.DB, app.blobs, time.Now().Add(time.Second), app.logger)
+	if err != nil || n != 1 {
+		t.Fatalf("purge = %d, %v; want 1", n, err)
+	}
+	if _, _, err := app.blobs.Get(context.Background(), key); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("poster after purge: err = %v, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func TestPosterChangesVersion(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 7)
+
+	// ポスターの変更でもETagが変わり、履歴に残る
+	rr := uploadPosterRequest(t, app, 1, testPNG(t), token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload: status %d: %s", rr.Code, rr.Body.String())
+	}
+	status, _ := doRequest(t, app, http.MethodDelete, "/v1/admin/movies/1/poster", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("delete: status %d", status)
+	}
+
+	movie, err := store.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Version != 3 {
+		t.Errorf("version = %d, want 3", movie.Version)
+	}
+
+	revisions, err := store.Revisions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 || revisions[0].UserID != 7 || revisions[1].UserID != 7 || revisions[0].Action != models.RevisionUpdate {
+		t.Errorf("revisions = %+v", revisions)
+	}
+}
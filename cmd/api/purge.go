@@ -0,0 +1,54 @@
+package main
+
+import (
+	"backend/models"
+	"backend/storage"
+	"context"
+	"log"
+	"time"
+)
+
+// purgeサブコマンド: 保存期間を過ぎたゴミ箱のmovieを完全に削除する
+func runPurge(cfg config, logger *log.Logger) error {
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	store := newModels(cfg, db)
+
+	blobs, err := storage.NewLocalStore(cfg.storage.dir)
+	if err != nil {
+		return err
+	}
+
+	before := time.Now().Add(-cfg.trash.retention)
+
+	n, err := purgeTrash(store.DB, blobs, before, logger)
+	if err != nil {
+		return err
+	}
+
+	logger.Printf("purged %d movies deleted before %s", n, before.Format(time.RFC3339))
+
+	return nil
+}
+
+// ゴミ箱のmovieを完全に削除して、ストレージに残るポスター画像も削除する
+func purgeTrash(store models.MovieStore, blobs storage.BlobStore, before time.Time, logger *log.Logger) (int, error) {
+	n, posters, err := store.PurgeDeleted(before)
+	if err != nil {
+		return 0, err
+	}
+
+	// movieは削除済みなので、画像の削除に失敗しても続ける
+	for _, key := range posters {
+		err := blobs.Delete(context.Background(), key)
+		if err != nil {
+			logger.Println("delete purged poster:", err)
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"backend/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ReleasePayload struct {
+	Country string `json:"country"`
+	Type string `json:"type"`
+	ReleaseDate string `json:"release_date"`
+}
+
+type CertificationPayload struct {
+	Country string `json:"country"`
+	Rating string `json:"rating"`
+}
+
+// ?region=JPの国(指定がない場合は空)
+func requestRegion(r *http.Request) (string, error) {
+	region := r.URL.Query().Get("region")
+	if region == "" {
+		return "", nil
+	}
+	return models.NormalizeRegion(region)
+}
+
+// 公開日と年齢制限の操作で返すエラーのステータスコード
+func releaseErrorStatus(err error) int {
+	if errors.Is(err, sql.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// movieの国ごとの公開日と年齢制限を返す
+func (app *application) getReleases(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	app.writeReleases(w, id)
+}
+
+func (app *application) writeReleases(w http.ResponseWriter, id int) {
+	releases, certifications, err := app.models.DB.Releases(id)
+	if err != nil {
+		app.errorJSON(w, err, releaseErrorStatus(err))
+		return
+	}
+	if releases == nil {
+		releases = []*models.MovieRelease{}
+	}
+	if certifications == nil {
+		certifications = []*models.MovieCertification{}
+	}
+
+	err = app.writeEnvelope(w, http.StatusOK, map[string]interface{}{
+		"releases": releases,
+		"certifications": certifications,
+	})
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// movieの国ごとの公開日と年齢制限をすべて置き換える
+func (app *application) updateReleases(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload struct {
+		Releases []ReleasePayload `json:"releases"`
+		Certifications []CertificationPayload `json:"certifications"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	releases := []models.MovieRelease{}
+	for _, p := range payload.Releases {
+		date, err := time.Parse("2006-01-02", p.ReleaseDate)
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("release_date must be a date in YYYY-MM-DD format"))
+			return
+		}
+		releases = append(releases, models.MovieRelease{
+			Country: p.Country,
+			Type: strings.ToLower(strings.TrimSpace(p.Type)),
+			ReleaseDate: date,
+		})
+	}
+
+	certifications := []models.MovieCertification{}
+	for _, p := range payload.Certifications {
+		certifications = append(certifications, models.MovieCertification{
+			Country: p.Country,
+			Rating: p.Rating,
+		})
+	}
+
+	err = app.models.DB.ReplaceReleases(id, releases, certifications)
+	if err != nil {
+		app.errorJSON(w, err, releaseErrorStatus(err))
+		return
+	}
+
+	app.writeReleases(w, id)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"testing"
+)
+
+func TestRegionalReleases(t *testing.T) {
+	app, _ := newTestApplication(t)
+	token := testToken(t, 10)
+
+	body := `{
+		"releases":[
+			{"country":"jp","type":"digital","release_date":"2008-12-19"},
+			{"country":"JP","type":"theatrical","release_date":"2008-08-09"},
+			{"country":"US","type":"theatrical","release_date":"2008-07-18"}
+		],
+		"certifications":[{"country":"JP","rating":"g"},{"country":"GB","rating":"12A"},{"country":"us","rating":"pg-13"}]
+	}`
+	status, resp := doRequest(t, app, http.MethodPut, "/v1/admin/movies/3/releases", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("replace: status = %d, want %d", status, http.StatusOK)
+	}
+	var releases []models.MovieRelease
+	decode(t, resp["releases"], &releases)
+	if len(releases) != 3 || releases[0].Country != "JP" || releases[0].Type != "theatrical" {
+		t.Errorf("releases = %+v", releases)
+	}
+	var certifications []models.MovieCertification
+	decode(t, resp["certifications"], &certifications)
+	if len(certifications) != 3 || certifications[1].System != "EIRIN" || certifications[1].Rating != "G" {
+		t.Errorf("certifications = %+v", certifications)
+	}
+	// USの年齢制限はmpaa_ratingから返す
+	if c := certifications[2]; c.Country != "US" || c.System != "MPAA" || c.Rating != "PG-13" {
+		t.Errorf("US certification = %+v, want MPAA PG-13", c)
+	}
+
+	bad := []string{
+		`{"releases":[{"country":"FR","type":"theatrical","release_date":"2008-08-13"}]}`,
+		`{"releases":[{"country":"JP","type":"festival","release_date":"2008-08-13"}]}`,
+		`{"releases":[{"country":"JP","type":"theatrical","release_date":"13/08/2008"}]}`,
+		`{"releases":[{"country":"JP","type":"theatrical","release_date":"2008-08-09"},{"country":"JP","type":"theatrical","release_date":"2008-08-10"}]}`,
+		`{"certifications":[{"country":"JP","rating":"PG-13"}]}`,
+		`{"certifications":[{"country":"DE","rating":"16"},{"country":"DE","rating":"12"}]}`,
+		// USの年齢制限はeditmovieのmpaa_ratingで変更する
+		`{"certifications":[{"country":"US","rating":"R"}]}`,
+	}
+	for _, body := range bad {
+		status, _ := doRequest(t, app, http.MethodPut, "/v1/admin/movies/3/releases", body, token)
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/movies/999/releases", `{}`, token)
+	if status != http.StatusNotFound {
+		t.Errorf("missing movie: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	// 劇場公開日と映倫の区分を返す
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/movie/3?region=jp", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("get: status = %d, want %d", status, http.StatusOK)
+	}
+	var movie models.Movie
+	decode(t, resp["movie"], &movie)
+	if movie.Region != "JP" || movie.ReleaseType != "theatrical" || movie.ReleaseDate.Format("2006-01-02") != "2008-08-09" {
+		t.Errorf("JP release of movie 3 = %s %s %s", movie.Region, movie.ReleaseType, movie.ReleaseDate)
+	}
+	if movie.Certification == nil || movie.Certification.System != "EIRIN" || movie.Certification.Rating != "G" {
+		t.Errorf("JP certification of movie 3 = %+v", movie.Certification)
+	}
+
+	// USの年齢制限がない場合はmpaa_ratingを使う
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/movies?region=US&limit=50", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("list: status = %d, want %d", status, http.StatusOK)
+	}
+	var movies []models.Movie
+	decode(t, resp["movies"], &movies)
+	for _, m := range movies {
+		if m.MPAARating != "" && (m.Certification == nil || m.Certification.Rating != m.MPAARating) {
+			t.Errorf("US certification of movie %d = %+v, want %s", m.ID, m.Certification, m.MPAARating)
+		}
+	}
+
+	status, _ = doRequest(t, app, http.MethodGet, "/v1/movies?region=XX", "", "")
+	if status != http.StatusBadRequest {
+		t.Errorf("unknown region: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	// 絞り込みと並び替えはregionに関係なく元のrelease_dateとmpaa_ratingで行う
+	body = `{"releases":[{"country":"JP","type":"theatrical","release_date":"2010-01-01"}]}`
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/movies/2/releases", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("replace movie 2: status = %d, want %d", status, http.StatusOK)
+	}
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/movies?region=JP&sort=release_date&released_before=1980-01-01", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("list by release_date: status = %d, want %d", status, http.StatusOK)
+	}
+	movies = nil
+	decode(t, resp["movies"], &movies)
+	if len(movies) != 1 || movies[0].ID != 2 || movies[0].ReleaseDate.Format("2006-01-02") != "2010-01-01" {
+		t.Errorf("movies released before 1980 with region JP = %+v, want movie 2 shown with its JP date", movies)
+	}
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/movies?region=JP&sort=release_date", "", "")
+	movies = nil
+	decode(t, resp["movies"], &movies)
+	if len(movies) == 0 || movies[0].ID != 2 {
+		t.Errorf("first movie by release_date with region JP = %+v, want movie 2", movies)
+	}
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/movies?region=US&mpaa_rating=PG-13", "", "")
+	movies = nil
+	decode(t, resp["movies"], &movies)
+	if len(movies) != 1 || movies[0].ID != 3 || movies[0].Certification == nil || movies[0].Certification.Rating != "PG-13" {
+		t.Errorf("PG-13 movies with region US = %+v, want movie 3 with certification PG-13", movies)
+	}
+
+	// regionを指定しない場合は元の値のまま
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/movie/3", "", "")
+	movie = models.Movie{}
+	decode(t, resp["movie"], &movie)
+	if movie.Region != "" || movie.Certification != nil {
+		t.Errorf("movie 3 without region = %s %+v", movie.Region, movie.Certification)
+	}
+}
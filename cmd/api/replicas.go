@@ -0,0 +1,75 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// replicaの状態を確認する間隔
+const replicaCheckInterval = 2 * time.Second
+
+// 最近書き込んだユーザー(その間の読み込みはprimaryに送って、書き込んだ内容が見えるようにする)
+type recentWrites struct {
+	mu sync.Mutex
+	at map[int]time.Time
+}
+
+func (rw *recentWrites) mark(userID int, window time.Duration) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	now := time.Now()
+	if rw.at == nil {
+		rw.at = make(map[int]time.Time)
+	}
+	// 期限の切れたユーザーを消しておく
+	for id, t := range rw.at {
+		if now.Sub(t) > window {
+			delete(rw.at, id)
+		}
+	}
+	rw.at[userID] = now
+}
+
+func (rw *recentWrites) recent(userID int, window time.Duration) bool {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	t, ok := rw.at[userID]
+	return ok && time.Since(t) <= window
+}
+
+// 書き込んだあとにprimaryを使う期間(使っているreplicaはこれより遅れていない)
+func (app *application) readYourWritesWindow() time.Duration {
+	return app.config.db.replicaMaxLag + replicaCheckInterval
+}
+
+// 書き込むリクエストを記録するミドルウェア(checkTokenのあとに置く)
+func (app *application) trackWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isReadOnlyMethod(r.Method) {
+			if userID := userIDFromContext(r); userID != 0 {
+				app.writes.mark(userID, app.readYourWritesWindow())
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// リクエストで使うストアを返す
+// 書き込むリクエストと、最近書き込んだユーザーの読み込みはprimaryを使い、それ以外はreplicaに振り分ける
+func (app *application) store(r *http.Request) models.MovieStore {
+	if !isReadOnlyMethod(r.Method) {
+		return app.models.DB.Primary()
+	}
+	if userID := userIDFromContext(r); userID != 0 && app.writes.recent(userID, app.readYourWritesWindow()) {
+		return app.models.DB.Primary()
+	}
+	return app.models.DB
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecentWrites(t *testing.T) {
+	var rw recentWrites
+
+	if rw.recent(1, time.Minute) {
+		t.Fatal("user 1 should not be recent before writing")
+	}
+
+	rw.mark(1, time.Minute)
+	if !rw.recent(1, time.Minute) {
+		t.Error("user 1 should be pinned to the primary after writing")
+	}
+	if rw.recent(2, time.Minute) {
+		t.Error("user 2 has not written")
+	}
+
+	// 期間が過ぎたらreplicaに戻る
+	rw.at[1] = time.Now().Add(-2 * time.Minute)
+	if rw.recent(1, time.Minute) {
+		t.Error("user 1 should fall back to replicas after the window")
+	}
+}
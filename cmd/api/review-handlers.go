@@ -0,0 +1,220 @@
+package main
+
+import (
+	"backend/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// レビューの一覧で受け付けるクエリパラメータ
+var reviewParams = []string{"sort", "limit", "cursor"}
+
+type ReviewPayload struct {
+	Score int `json:"score"`
+	Body string `json:"body"`
+}
+
+// レビューの操作で返すエラーのステータスコード
+func reviewErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrDuplicateReview):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrReviewNotOwned):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// ?sort=newest|helpful&limit=&cursor=でページ分割したレビューを返す
+func (app *application) getMovieReviews(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	qs := r.URL.Query()
+
+	for key := range qs {
+		if !containsParam(reviewParams, key) {
+			app.errorJSON(w, fmt.Errorf("unknown query parameter %q (allowed: %s)", key, strings.Join(reviewParams, ", ")))
+			return
+		}
+	}
+
+	filter := models.ReviewFilter{
+		Sort: qs.Get("sort"),
+		Limit: models.DefaultPageLimit,
+		Cursor: qs.Get("cursor"),
+	}
+
+	if qs.Get("limit") != "" {
+		limit, err := strconv.Atoi(qs.Get("limit"))
+		if err != nil || limit < 1 || limit > models.MaxPageLimit {
+			app.errorJSON(w, fmt.Errorf("limit must be between 1 and %d", models.MaxPageLimit))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	page, err := app.models.DB.Reviews(id, filter)
+	if err != nil {
+		app.errorJSON(w, err, reviewErrorStatus(err))
+		return
+	}
+
+	err = app.writeEnvelope(w, http.StatusOK, map[string]interface{}{
+		"reviews": page.Reviews,
+		"next_cursor": page.NextCursor,
+		"has_more": page.HasMore,
+	})
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// レビューを読み込んでJSONで返す
+func (app *application) writeReview(w http.ResponseWriter, status int, id int) {
+	review, err := app.models.DB.GetReview(id)
+	if err != nil {
+		app.errorJSON(w, err, reviewErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, status, review, "review")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) createReview(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload ReviewPayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	review := models.Review{
+		MovieID: id,
+		UserID: userIDFromContext(r),
+		Score: payload.Score,
+		Body: strings.TrimSpace(payload.Body),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	review.ID, err = app.models.DB.InsertReview(review)
+	if err != nil {
+		app.errorJSON(w, err, reviewErrorStatus(err))
+		return
+	}
+
+	app.writeReview(w, http.StatusCreated, review.ID)
+}
+
+// 自分のレビューのみ編集できる
+func (app *application) updateReview(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload ReviewPayload
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	review := models.Review{
+		ID: id,
+		UserID: userIDFromContext(r),
+		Score: payload.Score,
+		Body: strings.TrimSpace(payload.Body),
+		UpdatedAt: time.Now(),
+	}
+
+	err = app.models.DB.UpdateReview(review)
+	if err != nil {
+		app.errorJSON(w, err, reviewErrorStatus(err))
+		return
+	}
+
+	app.writeReview(w, http.StatusOK, id)
+}
+
+// 自分のレビューのみ削除できる
+func (app *application) deleteReview(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	err = app.models.DB.DeleteReview(id, userIDFromContext(r))
+	if err != nil {
+		app.errorJSON(w, err, reviewErrorStatus(err))
+		return
+	}
+
+	ok := jsonResp{
+		OK: true,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// POSTで「参考になった」の票を入れ、DELETEで取り消す
+func (app *application) voteReview(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	helpful := r.Method != http.MethodDelete
+
+	err = app.models.DB.VoteReview(id, userIDFromContext(r), helpful)
+	if err != nil {
+		app.errorJSON(w, err, reviewErrorStatus(err))
+		return
+	}
+
+	app.writeReview(w, http.StatusOK, id)
+}
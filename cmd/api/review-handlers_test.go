@@ -0,0 +1,130 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"testing"
+)
+
+func TestMovieReviews(t *testing.T) {
+	app, store := newTestApplication(t)
+	alice, bob, carol := testToken(t, 21), testToken(t, 22), testToken(t, 23)
+
+	status, _ := doRequest(t, app, http.MethodPost, "/v1/movie/1/reviews", `{"score":5,"body":"Hope is a good thing"}`, "")
+	if status != http.StatusBadRequest {
+		t.Errorf("without token: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	status, resp := doRequest(t, app, http.MethodPost, "/v1/movie/1/reviews", `{"score":5,"body":"Hope is a good thing"}`, alice)
+	if status != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d", status, http.StatusCreated)
+	}
+	var first models.Review
+	decode(t, resp["review"], &first)
+	if first.UserID != 21 || first.Score != 5 {
+		t.Errorf("created review = %+v", first)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/movie/1/reviews", `{"score":4}`, alice)
+	if status != http.StatusConflict {
+		t.Errorf("second review by same user: status = %d, want %d", status, http.StatusConflict)
+	}
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/movie/1/reviews", `{"score":6}`, bob)
+	if status != http.StatusBadRequest {
+		t.Errorf("score out of range: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/movie/999/reviews", `{"score":3}`, bob)
+	if status != http.StatusNotFound {
+		t.Errorf("review of missing movie: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	status, resp = doRequest(t, app, http.MethodPost, "/v1/movie/1/reviews", `{"score":2,"body":"Too long"}`, bob)
+	if status != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d", status, http.StatusCreated)
+	}
+	var second models.Review
+	decode(t, resp["review"], &second)
+
+	movie, _ := store.Get(1)
+	if movie.ReviewCount != 2 || movie.ReviewScore != 3.5 {
+		t.Errorf("aggregate = %d / %v, want 2 / 3.5", movie.ReviewCount, movie.ReviewScore)
+	}
+
+	// 他人のレビューは編集できない
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/reviews/"+itoa(second.ID), `{"score":5}`, alice)
+	if status != http.StatusForbidden {
+		t.Errorf("edit someone else's review: status = %d, want %d", status, http.StatusForbidden)
+	}
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/reviews/"+itoa(second.ID), `{"score":3,"body":"Grew on me"}`, bob)
+	if status != http.StatusOK {
+		t.Errorf("edit: status = %d, want %d", status, http.StatusOK)
+	}
+
+	// 編集後の集計は一覧のmovieにも反映される
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/movies?rating_min=5&sort=id&limit=1", "", "")
+	var movies []models.Movie
+	decode(t, resp["movies"], &movies)
+	if len(movies) != 1 || movies[0].ReviewCount != 2 || movies[0].ReviewScore != 4 {
+		t.Errorf("movie in list = %+v, want 2 reviews averaging 4", movies)
+	}
+
+	// 参考になった順
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/reviews/"+itoa(second.ID)+"/helpful", "", bob)
+	if status != http.StatusBadRequest {
+		t.Errorf("vote own review: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	for _, token := range []string{alice, carol, carol} {
+		status, _ = doRequest(t, app, http.MethodPost, "/v1/reviews/"+itoa(second.ID)+"/helpful", "", token)
+		if status != http.StatusOK {
+			t.Fatalf("vote: status = %d, want %d", status, http.StatusOK)
+		}
+	}
+
+	reviewIDs := func(url string) []int {
+		t.Helper()
+		status, resp := doRequest(t, app, http.MethodGet, url, "", "")
+		if status != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", url, status, http.StatusOK)
+		}
+		var reviews []models.Review
+		decode(t, resp["reviews"], &reviews)
+		var ids []int
+		for _, r := range reviews {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	if ids := reviewIDs("/v1/movie/1/reviews"); len(ids) != 2 || ids[0] != second.ID {
+		t.Errorf("newest first = %v", ids)
+	}
+
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/movie/1/reviews?sort=helpful&limit=1", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("helpful: status = %d, want %d", status, http.StatusOK)
+	}
+	var page []models.Review
+	var next string
+	decode(t, resp["reviews"], &page)
+	decode(t, resp["next_cursor"], &next)
+	if len(page) != 1 || page[0].ID != second.ID || page[0].HelpfulCount != 2 {
+		t.Errorf("most helpful = %+v, want review %d with 2 votes", page, second.ID)
+	}
+	if ids := reviewIDs("/v1/movie/1/reviews?sort=helpful&limit=1&cursor=" + next); len(ids) != 1 || ids[0] != first.ID {
+		t.Errorf("second page by helpful = %v, want [%d]", ids, first.ID)
+	}
+
+	status, _ = doRequest(t, app, http.MethodGet, "/v1/movie/1/reviews?sort=best", "", "")
+	if status != http.StatusBadRequest {
+		t.Errorf("unknown sort: status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/reviews/"+itoa(first.ID), "", alice)
+	if status != http.StatusOK {
+		t.Errorf("delete: status = %d, want %d", status, http.StatusOK)
+	}
+	movie, _ = store.Get(1)
+	if movie.ReviewCount != 1 || movie.ReviewScore != 3 {
+		t.Errorf("aggregate after delete = %d / %v, want 1 / 3", movie.ReviewCount, movie.ReviewScore)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"backend/models"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// 履歴の操作で返すエラーのステータスコード
+func revisionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, models.ErrRevisionMismatch):
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+func (app *application) getRevisions(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	revisions, err := app.models.DB.Revisions(id)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, revisions, "revisions")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// movieの履歴を読み込む(別のmovieの履歴はエラーにする)
+func (app *application) movieRevision(movieID int, value string) (*models.Revision, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, errors.New("from and to must be revision ids")
+	}
+
+	revision, err := app.models.DB.GetRevision(id)
+	if err != nil {
+		return nil, err
+	}
+	if revision.MovieID != movieID {
+		return nil, models.ErrRevisionMismatch
+	}
+
+	return revision, nil
+}
+
+// ?from=<revision id>&to=<revision id>の2つの履歴の差分を返す
+func (app *application) diffRevisions(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	from, err := app.movieRevision(id, r.URL.Query().Get("from"))
+	if err != nil {
+		app.errorJSON(w, err, revisionErrorStatus(err))
+		return
+	}
+
+	to, err := app.movieRevision(id, r.URL.Query().Get("to"))
+	if err != nil {
+		app.errorJSON(w, err, revisionErrorStatus(err))
+		return
+	}
+
+	diff := struct {
+		From *models.Revision `json:"from"`
+		To *models.Revision `json:"to"`
+		Changes []models.RevisionChange `json:"changes"`
+	}{
+		From: from,
+		To: to,
+		Changes: models.DiffRevisions(from, to),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, diff, "diff")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) revertMovie(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	revisionID, err := strconv.Atoi(params.ByName("revision_id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid revision_id parameter"))
+		return
+	}
+
+	err = app.models.DB.RevertMovie(id, revisionID, userIDFromContext(r))
+	if err != nil {
+		app.errorJSON(w, err, revisionErrorStatus(err))
+		return
+	}
+	app.invalidateSimilar()
+
+	ok := jsonResp{
+		OK: true,
+		ID: id,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"testing"
+)
+
+func TestMovieRevisions(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	body := `{"id":"4","title":"American Psycho","description":"A banker with a secret","release_date":"2000-04-14","runtime":"102","rating":"3","mpaa_rating":"R","genres":[6],"version":"1"}`
+	status, _ := doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("update: status = %d, want %d", status, http.StatusOK)
+	}
+
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/admin/movies/4/revisions", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("revisions: status = %d, want %d", status, http.StatusOK)
+	}
+	var revisions []models.Revision
+	decode(t, resp["revisions"], &revisions)
+	if len(revisions) != 2 {
+		t.Fatalf("got %d revisions, want 2", len(revisions))
+	}
+
+	// 新しい順
+	updated, inserted := revisions[0], revisions[1]
+	if updated.Action != models.RevisionUpdate || updated.UserID != 10 {
+		t.Errorf("latest revision = %+v, want update by user 10", updated)
+	}
+	if inserted.Action != models.RevisionInsert || inserted.Snapshot.Rating != 4 {
+		t.Errorf("first revision = %+v, want insert with rating 4", inserted)
+	}
+
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/admin/movies/4/diff?from="+itoa(inserted.ID)+"&to="+itoa(updated.ID), "", token)
+	if status != http.StatusOK {
+		t.Fatalf("diff: status = %d, want %d", status, http.StatusOK)
+	}
+	var diff struct {
+		Changes []models.RevisionChange `json:"changes"`
+	}
+	decode(t, resp["diff"], &diff)
+
+	fields := map[string]bool{}
+	for _, c := range diff.Changes {
+		fields[c.Field] = true
+	}
+	if len(fields) != 3 || !fields["description"] || !fields["rating"] || !fields["genre_ids"] {
+		t.Errorf("diff changes = %+v, want description, rating and genre_ids", diff.Changes)
+	}
+
+	// 別のmovieの履歴は使えない
+	status, _ = doRequest(t, app, http.MethodGet, "/v1/admin/movies/1/diff?from="+itoa(inserted.ID)+"&to="+itoa(updated.ID), "", token)
+	if status != http.StatusNotFound {
+		t.Errorf("diff of another movie: status = %d, want %d", status, http.StatusNotFound)
+	}
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/movies/1/revert/"+itoa(inserted.ID), "", token)
+	if status != http.StatusNotFound {
+		t.Errorf("revert to another movie's revision: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/movies/4/revert/"+itoa(inserted.ID), "", testToken(t, 11))
+	if status != http.StatusOK {
+		t.Fatalf("revert: status = %d, want %d", status, http.StatusOK)
+	}
+
+	movie, err := store.Get(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Rating != 4 || movie.Description != inserted.Snapshot.Description || len(movie.GenreIDs) != 2 {
+		t.Errorf("reverted movie = %+v", movie)
+	}
+
+	revs, err := store.Revisions(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 3 || revs[0].Action != models.RevisionRevert || revs[0].UserID != 11 {
+		t.Errorf("latest revision after revert = %+v, want revert by user 11", revs[0])
+	}
+}
+
+func TestRevisionTagsAndPoster(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	edit := func(version int, tags string) {
+		t.Helper()
+		body := `{"id":"4","title":"American Psycho","description":"A banker with a secret","release_date":"2000-04-14","runtime":"102","rating":"4","mpaa_rating":"R","genres":[6,1],"tags":` + tags + `,"version":"` + itoa(version) + `"}`
+		status, _ := doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", body, token)
+		if status != http.StatusOK {
+			t.Fatalf("update: status = %d, want %d", status, http.StatusOK)
+		}
+	}
+	edit(1, `["Cult classic"]`)
+	edit(2, `["Satire"]`)
+	rr := uploadPosterRequest(t, app, 4, testPNG(t), token)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload: status %d: %s", rr.Code, rr.Body.String())
+	}
+
+	revisions, err := store.Revisions(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 4 {
+		t.Fatalf("got %d revisions, want 4", len(revisions))
+	}
+	poster, satire, cult := revisions[0], revisions[1], revisions[2]
+
+	// タグとポスターの変更も差分に出る
+	changes := models.DiffRevisions(cult, poster)
+	fields := map[string]bool{}
+	for _, c := range changes {
+		fields[c.Field] = true
+	}
+	if len(fields) != 2 || !fields["tags"] || !fields["poster"] {
+		t.Errorf("diff changes = %+v, want tags and poster", changes)
+	}
+
+	// タグは戻し、ポスターは今のものを残す
+	status, _ := doRequest(t, app, http.MethodPost, "/v1/admin/movies/4/revert/"+itoa(cult.ID), "", token)
+	if status != http.StatusOK {
+		t.Fatalf("revert: status = %d, want %d", status, http.StatusOK)
+	}
+	revisions, err = store.Revisions(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reverted := revisions[0].Snapshot
+	if len(reverted.Tags) != 1 || reverted.Tags[0] != "Cult classic" || reverted.Poster != poster.Snapshot.Poster || reverted.Poster == "" {
+		t.Errorf("snapshot after revert = %+v", reverted)
+	}
+	if len(satire.Snapshot.Tags) != 1 || satire.Snapshot.Tags[0] != "Satire" {
+		t.Errorf("satire snapshot = %+v", satire.Snapshot)
+	}
+}
@@ -10,7 +10,8 @@ import (
 
 func (app *application) wrap(next http.Handler) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		ctx := context.WithValue(r.Context(), "params", ps)
+		// httprouter.ParamsFromContextで取り出せるキーで保存する
+		ctx := context.WithValue(r.Context(), httprouter.ParamsKey, ps)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
@@ -18,8 +19,10 @@ func (app *application) wrap(next http.Handler) httprouter.Handle {
 // ルートハンドラーのレシーバ
 func (app *application) routes() http.Handler {
 	router := httprouter.New()
-	// ミドルウェアチェーンをつくる(今回は一つだけ)
-	secure := alice.New(app.checkToken)
+	// ミドルウェアチェーンをつくる(書き込んだユーザーはしばらくprimaryから読み込む)
+	secure := alice.New(app.checkToken, app.trackWrites)
+	// トークンがあればユーザーごとの情報(in_watchlist)を返す
+	optional := alice.New(app.optionalToken, app.varyLanguage)
 
 	router.HandlerFunc(http.MethodGet, "/status", app.statusHandler)
 
@@ -27,18 +30,101 @@ func (app *application) routes() http.Handler {
 
 	router.HandlerFunc(http.MethodPost, "/v1/signin", app.Signin)
 
-	router.HandlerFunc(http.MethodGet, "/v1/movie/:id", app.getOneMovie)
-	router.HandlerFunc(http.MethodGet, "/v1/movies", app.getAllMovies)
-	router.HandlerFunc(http.MethodGet, "/v1/movies/:genre_id", app.getAllMoviesByGenre)
+	router.Handler(http.MethodGet, "/v1/movie/:id", optional.ThenFunc(app.getOneMovie))
+	router.Handler(http.MethodGet, "/v1/movie/:id/similar", optional.ThenFunc(app.getSimilarMovies))
+	router.Handler(http.MethodGet, "/v1/movies", optional.ThenFunc(app.getAllMovies))
+	router.Handler(http.MethodGet, "/v1/movies/:genre_id", optional.ThenFunc(app.getAllMoviesByGenre))
 
-	router.HandlerFunc(http.MethodGet, "/v1/genres", app.getAllGenres)
+	router.Handler(http.MethodGet, "/v1/search", optional.ThenFunc(app.searchMovies))
+
+	// 国ごとの公開日と年齢制限
+	router.HandlerFunc(http.MethodGet, "/v1/movie/:id/releases", app.getReleases)
+	router.PUT("/v1/admin/movies/:id/releases", app.wrap(secure.ThenFunc(app.updateReleases)))
+
+	// レビュー(書き込みはサインインしたユーザーのみ)
+	router.HandlerFunc(http.MethodGet, "/v1/movie/:id/reviews", app.getMovieReviews)
+	router.POST("/v1/movie/:id/reviews", app.wrap(secure.ThenFunc(app.createReview)))
+	router.PUT("/v1/reviews/:id", app.wrap(secure.ThenFunc(app.updateReview)))
+	router.DELETE("/v1/reviews/:id", app.wrap(secure.ThenFunc(app.deleteReview)))
+	router.POST("/v1/reviews/:id/helpful", app.wrap(secure.ThenFunc(app.voteReview)))
+	router.DELETE("/v1/reviews/:id/helpful", app.wrap(secure.ThenFunc(app.voteReview)))
+
+	// サインインしたユーザーのwatchlistとfavorites
+	router.GET("/v1/me/:list", app.wrap(secure.ThenFunc(app.getList)))
+	router.PUT("/v1/me/:list", app.wrap(secure.ThenFunc(app.reorderList)))
+	router.PUT("/v1/me/:list/:movie_id", app.wrap(secure.ThenFunc(app.saveListEntry)))
+	router.DELETE("/v1/me/:list/:movie_id", app.wrap(secure.ThenFunc(app.removeListEntry)))
+
+	router.Handler(http.MethodGet, "/v1/genres", alice.New(app.varyLanguage).ThenFunc(app.getAllGenres))
+
+	// シリーズなどのcollectionとそのmovie(順番どおり)
+	router.HandlerFunc(http.MethodGet, "/v1/collections", app.getAllCollections)
+	router.Handler(http.MethodGet, "/v1/collections/:id", optional.ThenFunc(app.getOneCollection))
+
+	// 編集者がつけたタグ(件数つきのタグクラウドとタグのついたmovie)
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.getTagCloud)
+	router.HandlerFunc(http.MethodGet, "/v1/tags/:slug", app.getOneTag)
+	router.Handler(http.MethodGet, "/v1/tags/:slug/movies", optional.ThenFunc(app.getMoviesByTag))
+
+	router.HandlerFunc(http.MethodGet, "/v1/people", app.getAllPeople)
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id", app.getOnePerson)
+	router.HandlerFunc(http.MethodGet, "/v1/people/:id/filmography", app.getFilmography)
 
 	// checkTokenミドルウェアを通過したときのみリクエストを通す
 	router.POST("/v1/admin/editmovie", app.wrap(secure.ThenFunc(app.editMovie)))
+	// CSV・JSON・NDJSONでまとめて追加・更新する
+	router.POST("/v1/admin/import", app.wrap(secure.ThenFunc(app.importMovies)))
+	router.GET("/v1/admin/export", app.wrap(secure.ThenFunc(app.exportMovies)))
 	// router.HandlerFunc(http.MethodPost, "/v1/admin/editmovie", app.editMovie)
 
 	router.GET("/v1/admin/deletemovie/:id", app.wrap(secure.ThenFunc(app.deleteMovie)))
+	router.DELETE("/v1/admin/deletemovie/:id", app.wrap(secure.ThenFunc(app.deleteMovie)))
 	// router.HandlerFunc(http.MethodGet, "/v1/admin/deletemovie/:id", app.deleteMovie)
 
+	// ゴミ箱(削除したmovie)の一覧と復元
+	router.GET("/v1/admin/trash", app.wrap(secure.ThenFunc(app.getTrash)))
+	router.POST("/v1/admin/trash/:id/restore", app.wrap(secure.ThenFunc(app.restoreMovie)))
+
+	// movieの変更履歴
+	router.GET("/v1/admin/movies/:id/revisions", app.wrap(secure.ThenFunc(app.getRevisions)))
+	router.GET("/v1/admin/movies/:id/diff", app.wrap(secure.ThenFunc(app.diffRevisions)))
+	router.POST("/v1/admin/movies/:id/revert/:revision_id", app.wrap(secure.ThenFunc(app.revertMovie)))
+	router.PUT("/v1/admin/movies/:id/credits", app.wrap(secure.ThenFunc(app.updateCredits)))
+
+	// ポスター画像のアップロードと配信
+	router.POST("/v1/admin/movies/:id/poster", app.wrap(secure.ThenFunc(app.uploadPoster)))
+	router.DELETE("/v1/admin/movies/:id/poster", app.wrap(secure.ThenFunc(app.deletePoster)))
+	router.HandlerFunc(http.MethodGet, "/v1/posters/:name", app.servePoster)
+
+	// 外部のメタデータの提案を確認して採用・却下する
+	router.POST("/v1/admin/movies/:id/enrich", app.wrap(secure.ThenFunc(app.requestEnrichment)))
+	router.GET("/v1/admin/suggestions", app.wrap(secure.ThenFunc(app.getSuggestions)))
+	router.POST("/v1/admin/suggestions/:id/accept", app.wrap(secure.ThenFunc(app.acceptSuggestion)))
+	router.POST("/v1/admin/suggestions/:id/reject", app.wrap(secure.ThenFunc(app.rejectSuggestion)))
+
+	router.POST("/v1/admin/genres", app.wrap(secure.ThenFunc(app.createGenre)))
+	router.PUT("/v1/admin/genres/:id", app.wrap(secure.ThenFunc(app.updateGenre)))
+	router.DELETE("/v1/admin/genres/:id", app.wrap(secure.ThenFunc(app.deleteGenre)))
+
+	// title・description・genre名の翻訳
+	router.GET("/v1/admin/movies/:id/translations", app.wrap(secure.ThenFunc(app.getMovieTranslations)))
+	router.PUT("/v1/admin/movies/:id/translations/:locale", app.wrap(secure.ThenFunc(app.saveMovieTranslation)))
+	router.DELETE("/v1/admin/movies/:id/translations/:locale", app.wrap(secure.ThenFunc(app.deleteMovieTranslation)))
+	router.GET("/v1/admin/genres/:id/translations", app.wrap(secure.ThenFunc(app.getGenreTranslations)))
+	router.PUT("/v1/admin/genres/:id/translations/:locale", app.wrap(secure.ThenFunc(app.saveGenreTranslation)))
+	router.DELETE("/v1/admin/genres/:id/translations/:locale", app.wrap(secure.ThenFunc(app.deleteGenreTranslation)))
+
+	router.POST("/v1/admin/collections", app.wrap(secure.ThenFunc(app.createCollection)))
+	router.PUT("/v1/admin/collections/:id", app.wrap(secure.ThenFunc(app.updateCollection)))
+	router.DELETE("/v1/admin/collections/:id", app.wrap(secure.ThenFunc(app.deleteCollection)))
+	router.PUT("/v1/admin/collections/:id/movies", app.wrap(secure.ThenFunc(app.setCollectionMovies)))
+
+	router.POST("/v1/admin/tags/:slug/synonyms", app.wrap(secure.ThenFunc(app.addTagSynonym)))
+	router.POST("/v1/admin/tags/:slug/merge", app.wrap(secure.ThenFunc(app.mergeTag)))
+
+	router.POST("/v1/admin/people", app.wrap(secure.ThenFunc(app.createPerson)))
+	router.PUT("/v1/admin/people/:id", app.wrap(secure.ThenFunc(app.updatePerson)))
+	router.DELETE("/v1/admin/people/:id", app.wrap(secure.ThenFunc(app.deletePerson)))
+
 	return app.enableCORS(router)
 }
\ No newline at end of file
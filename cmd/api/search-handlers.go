@@ -0,0 +1,64 @@
+package main
+
+import (
+	"backend/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 検索で受け付けるクエリパラメータ
+var searchParams = []string{"q", "limit", "cursor", "lang", "region"}
+
+func (app *application) searchMovies(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	for key := range qs {
+		if !containsParam(searchParams, key) {
+			app.errorJSON(w, fmt.Errorf("unknown query parameter %q (allowed: %s)", key, strings.Join(searchParams, ", ")))
+			return
+		}
+	}
+
+	filter := models.SearchFilter{
+		Query: qs.Get("q"),
+		Limit: models.DefaultPageLimit,
+		Cursor: qs.Get("cursor"),
+	}
+
+	if qs.Get("limit") != "" {
+		limit, err := strconv.Atoi(qs.Get("limit"))
+		if err != nil || limit < 1 || limit > models.MaxPageLimit {
+			app.errorJSON(w, fmt.Errorf("limit must be between 1 and %d", models.MaxPageLimit))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	page, err := app.store(r).Search(filter)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	var movies []*models.Movie
+	for _, result := range page.Results {
+		movies = append(movies, result.Movie)
+	}
+	err = app.prepareMovies(r, movies...)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeEnvelope(w, http.StatusOK, map[string]interface{}{
+		"results": page.Results,
+		"next_cursor": page.NextCursor,
+		"has_more": page.HasMore,
+	})
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
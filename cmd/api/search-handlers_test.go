@@ -0,0 +1,79 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSearchMovies(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/search?q=CRIME", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	var results []models.SearchResult
+	decode(t, resp["results"], &results)
+
+	if len(results) != 1 || results[0].Movie.Title != "The Godfather" {
+		t.Fatalf("results = %+v, want only The Godfather", results)
+	}
+	if !strings.Contains(results[0].Snippet, "<b>crime</b>") {
+		t.Errorf("snippet = %q, want highlighted term", results[0].Snippet)
+	}
+
+	// titleの一致はdescriptionの一致より上位になる
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/search?q=the&limit=1", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	decode(t, resp["results"], &results)
+	var next string
+	decode(t, resp["next_cursor"], &next)
+	if len(results) != 1 || next == "" {
+		t.Fatalf("first page = %+v, next_cursor = %q", results, next)
+	}
+	first := results[0].Movie.ID
+
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/search?q=the&limit=1&cursor="+next, "", "")
+	decode(t, resp["results"], &results)
+	if len(results) != 1 || results[0].Movie.ID == first {
+		t.Errorf("second page = %+v, want a different movie", results)
+	}
+
+	for _, url := range []string{"/v1/search", "/v1/search?q=the&page=2"} {
+		status, _ := doRequest(t, app, http.MethodGet, url, "", "")
+		if status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", url, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestSearchHighlightEscapesHTML(t *testing.T) {
+	app, store := newTestApplication(t)
+
+	_, err := store.InsertMovie(models.Movie{Title: "<script>alert(1)</script> Heist", Description: "A crew & a <i>vault</i>"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/search?q=heist", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	var results []models.SearchResult
+	decode(t, resp["results"], &results)
+	if len(results) != 1 {
+		t.Fatalf("results = %+v, want one movie", results)
+	}
+	if want := "&lt;script&gt;alert(1)&lt;/script&gt; <b>Heist</b>"; results[0].TitleHighlight != want {
+		t.Errorf("title_highlight = %q, want %q", results[0].TitleHighlight, want)
+	}
+	if want := "A crew &amp; a &lt;i&gt;vault&lt;/i&gt;"; results[0].Snippet != want {
+		t.Errorf("snippet = %q, want %q", results[0].Snippet, want)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// シャットダウンのときに処理中のリクエストを待つ時間
+const shutdownTimeout = 20 * time.Second
+
+// サーバーをlistenする
+// SIGINTかSIGTERMを受け取ったら、処理中のリクエストとバックグラウンドの処理が終わるのを待ってからreturnする
+func (app *application) serve(srv *http.Server) error {
+	shutdownErr := make(chan error)
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.Println("shutting down server:", s)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := srv.Shutdown(ctx)
+
+		// 新しいリクエストは来ないので、メタデータの検索などの完了を待てる
+		app.logger.Println("waiting for background tasks")
+		app.wg.Wait()
+
+		shutdownErr <- err
+	}()
+
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	err = <-shutdownErr
+	if err != nil {
+		return err
+	}
+
+	app.logger.Println("stopped server")
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"backend/models"
+	"backend/recommend"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// 似ているmovieの件数
+const (
+	defaultSimilarLimit = 10
+	maxSimilarLimit = 50
+)
+
+// 似ているmovieを探すインデックス(movieが変更されるか古くなったら次のリクエストで作り直す)
+type similarCache struct {
+	// index・builtAt・builtGenerationを守る(作り直している間は持たない)
+	mu sync.Mutex
+	index *recommend.Index
+	builtAt time.Time
+	// indexを作り始めたときのgeneration
+	builtGeneration uint64
+	// invalidateSimilarのたびに増やす(作っている間に変更があった場合はindexが古いままになる)
+	generation atomic.Uint64
+	// 作り直すのは1つのリクエストだけにする
+	building sync.Mutex
+}
+
+// movie・genre・credit・タグを変更したハンドラーから呼ぶ(作り直しを待たない)
+func (app *application) invalidateSimilar() {
+	app.similar.generation.Add(1)
+}
+
+// 作り直さずに使えるインデックスを返す
+func (c *similarCache) current(ttl time.Duration) (*recommend.Index, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fresh := c.index != nil && c.builtGeneration == c.generation.Load() && time.Since(c.builtAt) < ttl
+	return c.index, fresh
+}
+
+// インデックスを返す(ほかのプロセスのimportなども反映するため、ttlを過ぎたものも作り直す)
+// ほかのリクエストが作り直している間は、前のインデックスがあればそれを返す
+func (app *application) similarIndex() (*recommend.Index, error) {
+	c := &app.similar
+	index, fresh := c.current(app.config.similar.ttl)
+	if fresh {
+		return index, nil
+	}
+
+	if index != nil {
+		if !c.building.TryLock() {
+			return index, nil
+		}
+	} else {
+		c.building.Lock()
+	}
+	defer c.building.Unlock()
+
+	// 待っている間にほかのリクエストが作り直した場合はそれを使う
+	index, fresh = c.current(app.config.similar.ttl)
+	if fresh {
+		return index, nil
+	}
+
+	generation := c.generation.Load()
+	features, err := app.models.DB.MovieFeatures()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]recommend.Item, 0, len(features))
+	for _, f := range features {
+		items = append(items, recommend.Item{
+			ID: f.MovieID,
+			Year: f.Year,
+			GenreIDs: f.GenreIDs,
+			PersonIDs: f.PersonIDs,
+			TagIDs: f.TagIDs,
+			Text: f.Title + "\n" + f.Description,
+		})
+	}
+	index = recommend.NewIndex(items)
+
+	c.mu.Lock()
+	c.index = index
+	c.builtAt = time.Now()
+	c.builtGeneration = generation
+	c.mu.Unlock()
+
+	return index, nil
+}
+
+// 似ているmovieをscoreの高い順に返す
+func (app *application) getSimilarMovies(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	qs := r.URL.Query()
+	for key := range qs {
+		if !containsParam([]string{"limit", "lang", "region"}, key) {
+			app.errorJSON(w, fmt.Errorf("unknown query parameter %q (allowed: limit, lang, region)", key))
+			return
+		}
+	}
+
+	limit := defaultSimilarLimit
+	if v := qs.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSimilarLimit {
+			app.errorJSON(w, fmt.Errorf("limit must be between 1 and %d", maxSimilarLimit))
+			return
+		}
+		limit = n
+	}
+
+	_, err = app.store(r).Get(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	index, err := app.similarIndex()
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	// インデックスを作ったあとに追加・削除されたmovieは飛ばす
+	type similarMovie struct {
+		Score float64 `json:"score"`
+		Movie *models.Movie `json:"movie"`
+	}
+	matches := index.Similar(id, limit)
+	ids := make([]int, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.ID)
+	}
+	movies, err := app.store(r).GetMany(ids)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	// GetManyはidsの順(scoreの高い順)に返す
+	similar := []similarMovie{}
+	scores := make(map[int]float64)
+	for _, match := range matches {
+		scores[match.ID] = match.Score
+	}
+	for _, movie := range movies {
+		similar = append(similar, similarMovie{Score: scores[movie.ID], Movie: movie})
+	}
+
+	err = app.prepareMovies(r, movies...)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, similar, "similar")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
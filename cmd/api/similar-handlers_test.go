@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSimilarMovies(t *testing.T) {
+	app, store := newTestApplication(t)
+	token := testToken(t, 10)
+
+	similar := func(id string) []int {
+		t.Helper()
+		status, resp := doRequest(t, app, http.MethodGet, "/v1/movie/"+id+"/similar", "", "")
+		if status != http.StatusOK {
+			t.Fatalf("similar of %s: status = %d, want %d", id, status, http.StatusOK)
+		}
+		var results []struct {
+			Score float64 `json:"score"`
+			Movie struct {
+				ID int `json:"id"`
+			} `json:"movie"`
+		}
+		decode(t, resp["similar"], &results)
+		var ids []int
+		for _, r := range results {
+			if r.Score <= 0 {
+				t.Errorf("score of movie %d = %v", r.Movie.ID, r.Score)
+			}
+			ids = append(ids, r.Movie.ID)
+		}
+		return ids
+	}
+
+	// 同じgenreのThe Godfatherが一番近い
+	if ids := similar("1"); len(ids) == 0 || ids[0] != 2 {
+		t.Errorf("similar to The Shawshank Redemption = %v, want 2 first", ids)
+	}
+	if ids := similar("4"); len(ids) == 0 || ids[0] != 1 {
+		t.Errorf("similar to American Psycho = %v, want 1 first", ids)
+	}
+
+	// タグを共有すると(キャッシュを作り直して)順位が変わる
+	for _, id := range []int{3, 4} {
+		movie, err := store.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		movie.TagNames = []string{"antihero", "dark", "psychological"}
+		err = store.UpdateMovie(*movie, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ids := similar("4"); ids[0] != 1 {
+		t.Errorf("similar to American Psycho before invalidation = %v, want the cached ranking", ids)
+	}
+
+	body := `{"id":"4","title":"American Psycho","release_date":"2000-04-14","runtime":"102","rating":"4","mpaa_rating":"R","version":"2"}`
+	status, _ := doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", body, token)
+	if status != http.StatusOK {
+		t.Fatalf("edit: status = %d, want %d", status, http.StatusOK)
+	}
+	if ids := similar("4"); len(ids) == 0 || ids[0] != 3 {
+		t.Errorf("similar to American Psycho after tagging = %v, want 3 first", ids)
+	}
+
+	status, _ = doRequest(t, app, http.MethodGet, "/v1/movie/999/similar", "", "")
+	if status != http.StatusNotFound {
+		t.Errorf("missing movie: status = %d, want %d", status, http.StatusNotFound)
+	}
+	status, _ = doRequest(t, app, http.MethodGet, "/v1/movie/1/similar?limit=500", "", "")
+	if status != http.StatusBadRequest {
+		t.Errorf("limit too large: status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+// 作り直している間もinvalidateSimilarは待たず、ほかのリクエストは前のインデックスを使う
+func TestSimilarIndexRebuild(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	first, err := app.similarIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.similar.building.Lock()
+	done := make(chan struct{})
+	go func() {
+		app.invalidateSimilar()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("invalidateSimilar waited for the rebuild")
+	}
+
+	index, err := app.similarIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index != first {
+		t.Error("similarIndex did not return the previous index while another request was rebuilding")
+	}
+	app.similar.building.Unlock()
+
+	index, err = app.similarIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index == first {
+		t.Error("similarIndex did not rebuild after invalidation")
+	}
+	again, err := app.similarIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != index {
+		t.Error("similarIndex rebuilt an index that was still fresh")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"backend/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// タグクラウドの件数
+const (
+	defaultTagCloudLimit = 100
+	maxTagCloudLimit = 1000
+)
+
+// タグの操作で返すエラーのステータスコード
+func tagErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrTagExists):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// movieについているタグを件数の多い順に返す
+func (app *application) getTagCloud(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	for key := range qs {
+		if key != "limit" {
+			app.errorJSON(w, fmt.Errorf("unknown query parameter %q (allowed: limit)", key))
+			return
+		}
+	}
+
+	limit := defaultTagCloudLimit
+	if v := qs.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxTagCloudLimit {
+			app.errorJSON(w, fmt.Errorf("limit must be between 1 and %d", maxTagCloudLimit))
+			return
+		}
+		limit = n
+	}
+
+	tags, err := app.models.DB.TagCloud(limit)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, tags, "tags")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) getOneTag(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	tag, err := app.models.DB.GetTag(params.ByName("slug"))
+	if err != nil {
+		app.errorJSON(w, err, tagErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, tag, "tag")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// タグ(別名のslugでもよい)のついたmovieを一覧と同じ絞り込み・並び替えで返す
+func (app *application) getMoviesByTag(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	tag, err := app.models.DB.GetTag(params.ByName("slug"))
+	if err != nil {
+		app.errorJSON(w, err, tagErrorStatus(err))
+		return
+	}
+
+	filter, err := app.readMovieFilter(r, "lang", "region")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	filter.TagID = tag.ID
+
+	page, err := app.store(r).List(filter)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.prepareMovies(r, page.Movies...)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	app.writeMoviePage(w, page)
+}
+
+// 同じ意味の別の名前をタグに追加する
+func (app *application) addTagSynonym(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	tag, err := app.models.DB.GetTag(params.ByName("slug"))
+	if err != nil {
+		app.errorJSON(w, err, tagErrorStatus(err))
+		return
+	}
+
+	var payload struct {
+		Name string `json:"name"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.models.DB.AddTagSynonym(tag.ID, payload.Name)
+	if err != nil {
+		app.errorJSON(w, err, tagErrorStatus(err))
+		return
+	}
+
+	app.writeTag(w, tag.Slug)
+}
+
+// タグをintoのslugのタグにまとめる(元のslugはintoの別名になる)
+func (app *application) mergeTag(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	tag, err := app.models.DB.GetTag(params.ByName("slug"))
+	if err != nil {
+		app.errorJSON(w, err, tagErrorStatus(err))
+		return
+	}
+
+	var payload struct {
+		Into string `json:"into"`
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	into, err := app.models.DB.GetTag(payload.Into)
+	if errors.Is(err, sql.ErrNoRows) {
+		app.errorJSON(w, fmt.Errorf("unknown tag %q", payload.Into))
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	err = app.models.DB.MergeTags(tag.ID, into.ID)
+	if err != nil {
+		app.errorJSON(w, err, tagErrorStatus(err))
+		return
+	}
+	app.invalidateSimilar()
+
+	app.writeTag(w, into.Slug)
+}
+
+func (app *application) writeTag(w http.ResponseWriter, slug string) {
+	tag, err := app.models.DB.GetTag(slug)
+	if err != nil {
+		app.errorJSON(w, err, tagErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, tag, "tag")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
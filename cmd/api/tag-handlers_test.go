@@ -0,0 +1,92 @@
+package main
+
+import (
+	"backend/models"
+	"net/http"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	app, _ := newTestApplication(t)
+	token := testToken(t, 10)
+
+	// 大文字小文字や記号の違いは同じタグになる
+	edits := map[string]string{
+		"1": `["Prison", "based on a true story"]`,
+		"2": `["Mafia", "Based on a TRUE story!"]`,
+		"3": `["vigilante", "  Prison  ", "prison"]`,
+	}
+	for id, tags := range edits {
+		_, resp := doRequest(t, app, http.MethodGet, "/v1/movie/"+id, "", "")
+		var movie models.Movie
+		decode(t, resp["movie"], &movie)
+		body := `{"id":"` + id + `","title":"` + movie.Title + `","release_date":"` + movie.ReleaseDate.Format("2006-01-02") +
+			`","runtime":"` + itoa(movie.Runtime) + `","rating":"` + itoa(movie.Rating) + `","mpaa_rating":"` + movie.MPAARating +
+			`","version":"` + itoa(movie.Version) + `","tags":` + tags + `}`
+		status, _ := doRequest(t, app, http.MethodPost, "/v1/admin/editmovie", body, token)
+		if status != http.StatusOK {
+			t.Fatalf("edit movie %s: status = %d, want %d", id, status, http.StatusOK)
+		}
+	}
+
+	_, resp := doRequest(t, app, http.MethodGet, "/v1/movie/3", "", "")
+	var movie models.Movie
+	decode(t, resp["movie"], &movie)
+	if len(movie.Tags) != 2 || movie.Tags[0].Slug != "prison" || movie.Tags[1].Name != "vigilante" {
+		t.Errorf("tags of movie 3 = %+v", movie.Tags)
+	}
+
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/tags", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("tag cloud: status = %d, want %d", status, http.StatusOK)
+	}
+	var cloud []models.Tag
+	decode(t, resp["tags"], &cloud)
+	if len(cloud) != 4 || cloud[0].Slug != "based-on-a-true-story" || cloud[0].MovieCount != 2 || cloud[1].Slug != "prison" {
+		t.Errorf("tag cloud = %+v", cloud)
+	}
+
+	status, resp = doRequest(t, app, http.MethodGet, "/v1/tags/prison/movies?sort=-id", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("movies by tag: status = %d, want %d", status, http.StatusOK)
+	}
+	var movies []models.Movie
+	decode(t, resp["movies"], &movies)
+	if len(movies) != 2 || movies[0].ID != 3 || movies[1].ID != 1 {
+		t.Errorf("movies tagged prison = %+v", movies)
+	}
+
+	// vigilanteをprisonにまとめると、古いslugでも同じタグが見つかる
+	status, resp = doRequest(t, app, http.MethodPost, "/v1/admin/tags/vigilante/merge", `{"into":"prison"}`, token)
+	if status != http.StatusOK {
+		t.Fatalf("merge: status = %d, want %d", status, http.StatusOK)
+	}
+	var tag models.Tag
+	decode(t, resp["tag"], &tag)
+	if tag.Slug != "prison" || len(tag.Synonyms) != 1 || tag.Synonyms[0] != "vigilante" {
+		t.Errorf("merged tag = %+v", tag)
+	}
+	_, resp = doRequest(t, app, http.MethodGet, "/v1/tags/vigilante", "", "")
+	tag = models.Tag{}
+	decode(t, resp["tag"], &tag)
+	if tag.Slug != "prison" {
+		t.Errorf("tag for old slug = %+v", tag)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/tags/prison/synonyms", `{"name":"Jail"}`, token)
+	if status != http.StatusOK {
+		t.Errorf("add synonym: status = %d, want %d", status, http.StatusOK)
+	}
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/tags/prison/synonyms", `{"name":"mafia"}`, token)
+	if status != http.StatusConflict {
+		t.Errorf("synonym of another tag: status = %d, want %d", status, http.StatusConflict)
+	}
+	status, _ = doRequest(t, app, http.MethodPost, "/v1/admin/tags/jail/merge", `{"into":"prison"}`, token)
+	if status != http.StatusBadRequest {
+		t.Errorf("merge into itself: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	status, _ = doRequest(t, app, http.MethodGet, "/v1/tags/no-such-tag/movies", "", "")
+	if status != http.StatusNotFound {
+		t.Errorf("unknown tag: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"backend/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// 翻訳の操作で返すエラーのステータスコード
+func translationErrorStatus(err error) int {
+	if errors.Is(err, sql.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+type MovieTranslationPayload struct {
+	Title string `json:"title"`
+	Description string `json:"description"`
+}
+
+type GenreTranslationPayload struct {
+	GenreName string `json:"genre_name"`
+}
+
+func (app *application) getMovieTranslations(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	translations, err := app.models.DB.MovieTranslations(id)
+	if err != nil {
+		app.errorJSON(w, err, translationErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, translations, "translations")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// :localeのtitleとdescriptionを追加・置き換える
+func (app *application) saveMovieTranslation(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload MovieTranslationPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	translation, err := app.models.DB.SaveMovieTranslation(models.MovieTranslation{
+		MovieID: id,
+		Locale: params.ByName("locale"),
+		Title: payload.Title,
+		Description: payload.Description,
+	})
+	if err != nil {
+		app.errorJSON(w, err, translationErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, translation, "translation")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) deleteMovieTranslation(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	err = app.models.DB.DeleteMovieTranslation(id, params.ByName("locale"))
+	if err != nil {
+		app.errorJSON(w, err, translationErrorStatus(err))
+		return
+	}
+
+	ok := jsonResp{
+		OK: true,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) getGenreTranslations(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	translations, err := app.models.DB.GenreTranslations(id)
+	if err != nil {
+		app.errorJSON(w, err, translationErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, translations, "translations")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+// :localeのgenre名を追加・置き換える
+func (app *application) saveGenreTranslation(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	var payload GenreTranslationPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	translation, err := app.models.DB.SaveGenreTranslation(models.GenreTranslation{
+		GenreID: id,
+		Locale: params.ByName("locale"),
+		GenreName: payload.GenreName,
+	})
+	if err != nil {
+		app.errorJSON(w, err, translationErrorStatus(err))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, translation, "translation")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) deleteGenreTranslation(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	err = app.models.DB.DeleteGenreTranslation(id, params.ByName("locale"))
+	if err != nil {
+		app.errorJSON(w, err, translationErrorStatus(err))
+		return
+	}
+
+	ok := jsonResp{
+		OK: true,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"backend/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Accept-Languageを付けてmovieを取得する
+func getLocalizedMovie(t *testing.T, app *application, url, acceptLanguage string) (int, models.Movie) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	var resp struct {
+		Movie models.Movie `json:"movie"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	return rr.Code, resp.Movie
+}
+
+func TestMovieTranslations(t *testing.T) {
+	app, _ := newTestApplication(t)
+	token := testToken(t, 1)
+
+	status, _ := doRequest(t, app, http.MethodPut, "/v1/admin/movies/1/translations/ja", `{"title":"ショーシャンクの空に"}`, token)
+	if status != http.StatusOK {
+		t.Fatalf("save translation: status = %d", status)
+	}
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/genres/1/translations/ja", `{"genre_name":"ドラマ"}`, token)
+	if status != http.StatusOK {
+		t.Fatalf("save genre translation: status = %d", status)
+	}
+
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/movies/1/translations/en", `{"title":"x"}`, token)
+	if status != http.StatusBadRequest {
+		t.Errorf("default locale: status = %d, want 400", status)
+	}
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/movies/1/translations/fr", `{"title":"x"}`, token)
+	if status != http.StatusBadRequest {
+		t.Errorf("unsupported locale: status = %d, want 400", status)
+	}
+	status, _ = doRequest(t, app, http.MethodPut, "/v1/admin/movies/999/translations/ja", `{"title":"x"}`, token)
+	if status != http.StatusNotFound {
+		t.Errorf("missing movie: status = %d, want 404", status)
+	}
+
+	_, movie := getLocalizedMovie(t, app, "/v1/movie/1", "ja-JP,ja;q=0.9,en;q=0.8")
+	if movie.Title != "ショーシャンクの空に" || movie.Locale != "ja" {
+		t.Errorf("ja title = %q (locale %q)", movie.Title, movie.Locale)
+	}
+	// descriptionの翻訳がない場合は元の値を使う
+	if movie.Description != "Two imprisoned men bond over a number of years" {
+		t.Errorf("description = %q", movie.Description)
+	}
+	if !strings.Contains(strings.Join(genreNames(movie), ","), "ドラマ") {
+		t.Errorf("genres = %v", movie.MovieGenre)
+	}
+
+	// enを優先する場合は元の値
+	_, movie = getLocalizedMovie(t, app, "/v1/movie/1", "en,ja;q=0.5")
+	if movie.Title != "The Shawshank Redemption" || movie.Locale != "" {
+		t.Errorf("en title = %q (locale %q)", movie.Title, movie.Locale)
+	}
+
+	// langはAccept-Languageより優先する
+	_, movie = getLocalizedMovie(t, app, "/v1/movie/1?lang=ja", "en")
+	if movie.Title != "ショーシャンクの空に" {
+		t.Errorf("lang=ja title = %q", movie.Title)
+	}
+
+	// 翻訳がないmovieは元の値にフォールバックする
+	_, movie = getLocalizedMovie(t, app, "/v1/movie/2", "ja")
+	if movie.Title != "The Godfather" {
+		t.Errorf("untranslated title = %q", movie.Title)
+	}
+
+	status, _ = getLocalizedMovie(t, app, "/v1/movie/1?lang=fr", "")
+	if status != http.StatusBadRequest {
+		t.Errorf("lang=fr: status = %d, want 400", status)
+	}
+
+	status, resp := doRequest(t, app, http.MethodGet, "/v1/genres?lang=ja", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("genres: status = %d", status)
+	}
+	var genres []models.Genre
+	decode(t, resp["genres"], &genres)
+	found := false
+	for _, g := range genres {
+		found = found || g.GenreName == "ドラマ"
+	}
+	if !found {
+		t.Errorf("localized genres = %+v", genres)
+	}
+
+	status, _ = doRequest(t, app, http.MethodDelete, "/v1/admin/movies/1/translations/ja", "", token)
+	if status != http.StatusOK {
+		t.Fatalf("delete translation: status = %d", status)
+	}
+	_, movie = getLocalizedMovie(t, app, "/v1/movie/1", "ja")
+	if movie.Title != "The Shawshank Redemption" {
+		t.Errorf("title after delete = %q", movie.Title)
+	}
+}
+
+func TestGraphQLLang(t *testing.T) {
+	app, store := newTestApplication(t)
+
+	_, err := store.SaveMovieTranslation(models.MovieTranslation{MovieID: 3, Locale: "ja", Title: "ダークナイト"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := `{ movie(id: 3, lang: "ja") { title locale } search(titleContains: "ダーク", lang: "ja") { id } }`
+	req := httptest.NewRequest(http.MethodPost, "/v1/graphql", strings.NewReader(query))
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	var resp struct {
+		Data struct {
+			Movie struct {
+				Title string `json:"title"`
+				Locale string `json:"locale"`
+			} `json:"movie"`
+			Search []struct {
+				ID int `json:"id"`
+			} `json:"search"`
+		} `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatalf("%v: %s", err, rr.Body.String())
+	}
+	if resp.Data.Movie.Title != "ダークナイト" || resp.Data.Movie.Locale != "ja" {
+		t.Errorf("movie = %+v", resp.Data.Movie)
+	}
+	if len(resp.Data.Search) != 1 || resp.Data.Search[0].ID != 3 {
+		t.Errorf("search = %+v", resp.Data.Search)
+	}
+
+	// 共有しているmoviesは書き換えない
+	movie, _ := store.Get(3)
+	if movie.Title != "The Dark Knight" {
+		t.Errorf("stored title = %q", movie.Title)
+	}
+}
+
+func genreNames(movie models.Movie) []string {
+	var names []string
+	for _, name := range movie.MovieGenre {
+		names = append(names, name)
+	}
+	return names
+}
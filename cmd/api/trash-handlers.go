@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func (app *application) getTrash(w http.ResponseWriter, r *http.Request) {
+	movies, err := app.models.DB.Trash()
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	for _, movie := range movies {
+		movie.PosterURL = posterURL(movie.Poster)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, movies, "movies")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
+
+func (app *application) restoreMovie(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid id parameter"))
+		return
+	}
+
+	err = app.models.DB.RestoreMovie(id, userIDFromContext(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		app.errorJSON(w, errors.New("movie is not in the trash"), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+	app.invalidateSimilar()
+
+	ok := jsonResp{
+		OK: true,
+		ID: id,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, ok, "response")
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+}
@@ -12,8 +12,13 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data interf
 	// wrapキーの構造体にdataを代入する
 	wrapper[wrap] = data
 
+	return app.writeEnvelope(w, status, wrapper)
+}
+
+// 複数のキーをもつJSONを返す
+func (app *application) writeEnvelope(w http.ResponseWriter, status int, envelope map[string]interface{}) error {
 	// 構造体をJSONに変換する
-	js, err := json.Marshal(wrapper)
+	js, err := json.Marshal(envelope)
 	if err != nil {
 		return err
 	}
@@ -29,10 +34,10 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data interf
 }
 
 func (app *application) errorJSON(w http.ResponseWriter, err error, status ...int) {
-	// statusCode := http.StatusBadRequest
-	// if len(status) > 0 {
-	// 	statusCode = status[0]
-	// }
+	statusCode := http.StatusBadRequest
+	if len(status) > 0 {
+		statusCode = status[0]
+	}
 
 	type jsonError struct {
 		Message string `json:"message"`
@@ -42,5 +47,5 @@ func (app *application) errorJSON(w http.ResponseWriter, err error, status ...in
 		Message: err.Error(),
 	}
 
-	app.writeJSON(w, http.StatusBadRequest, theError, "error")
+	app.writeJSON(w, statusCode, theError, "error")
 }
\ No newline at end of file
@@ -0,0 +1,85 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// FakeProvider is an in-memory MetadataProvider for tests and local development
+type FakeProvider struct {
+	mu sync.Mutex
+	movies map[string]Suggestion
+	images map[string][]byte
+	lookups int
+}
+
+// NewFakeProvider returns a provider with no movies
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		movies: make(map[string]Suggestion),
+		images: make(map[string][]byte),
+	}
+}
+
+// titleとyearから検索用のキーをつくる
+func fakeKey(title string, year int) string {
+	return fmt.Sprintf("%s|%d", strings.ToLower(strings.TrimSpace(title)), year)
+}
+
+// Add registers the suggestion returned for the title and year
+func (p *FakeProvider) Add(title string, year int, s Suggestion) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.movies[fakeKey(title, year)] = s
+}
+
+// AddImage registers the image returned for the reference
+func (p *FakeProvider) AddImage(ref string, data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.images[ref] = data
+}
+
+// Lookups returns how many times Lookup was called
+func (p *FakeProvider) Lookups() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.lookups
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Lookup(ctx context.Context, title string, year int) (*Suggestion, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lookups++
+
+	s, ok := p.movies[fakeKey(title, year)]
+	if !ok {
+		return nil, ErrNoMatch
+	}
+
+	return &s, nil
+}
+
+func (p *FakeProvider) Image(ctx context.Context, ref string) (io.ReadCloser, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, ok := p.images[ref]
+	if !ok {
+		return nil, ErrImageNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
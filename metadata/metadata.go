@@ -0,0 +1,37 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrNoMatch is returned when the provider has no movie matching the title and year
+	ErrNoMatch = errors.New("no matching movie found")
+	// ErrImageNotFound is returned when the provider has no image for the reference
+	ErrImageNotFound = errors.New("image not found")
+)
+
+// Suggestion is the metadata a provider found for a movie
+type Suggestion struct {
+	// プロバイダーでのid(例: TMDBのmovie id)
+	ExternalID string
+	Title string
+	Overview string
+	// 不明な場合はゼロ値
+	ReleaseDate time.Time
+	// プロバイダーごとの画像の参照(Imageに渡す)
+	PosterRef string
+}
+
+// MetadataProvider is the interface implemented by every external metadata source
+type MetadataProvider interface {
+	// Name returns the identifier stored with each suggestion (e.g. "tmdb")
+	Name() string
+	// Lookup returns the best match for the title and release year (0 if unknown)
+	Lookup(ctx context.Context, title string, year int) (*Suggestion, error)
+	// Image downloads an image returned in Suggestion.PosterRef
+	Image(ctx context.Context, ref string) (io.ReadCloser, error)
+}
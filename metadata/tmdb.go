@@ -0,0 +1,141 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTMDBURL is the base URL of the TMDB v3 API
+	DefaultTMDBURL = "https://api.themoviedb.org/3"
+	// DefaultTMDBImageURL is the base URL TMDB serves poster images from
+	DefaultTMDBImageURL = "https://image.tmdb.org/t/p/w500"
+)
+
+// TMDBClient looks up metadata with the TMDB search API (or any server with the same API)
+type TMDBClient struct {
+	baseURL string
+	imageURL string
+	apiKey string
+	client *http.Client
+}
+
+// NewTMDBClient returns a client for the API at baseURL. Empty URLs use the TMDB defaults.
+func NewTMDBClient(baseURL, imageURL, apiKey string) *TMDBClient {
+	if baseURL == "" {
+		baseURL = DefaultTMDBURL
+	}
+	if imageURL == "" {
+		imageURL = DefaultTMDBImageURL
+	}
+
+	return &TMDBClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		imageURL: strings.TrimSuffix(imageURL, "/"),
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *TMDBClient) Name() string {
+	return "tmdb"
+}
+
+// /search/movieのレスポンスのうち使う部分
+type tmdbSearchResponse struct {
+	Results []struct {
+		ID int `json:"id"`
+		Title string `json:"title"`
+		Overview string `json:"overview"`
+		PosterPath string `json:"poster_path"`
+		ReleaseDate string `json:"release_date"`
+	} `json:"results"`
+}
+
+func (c *TMDBClient) Lookup(ctx context.Context, title string, year int) (*Suggestion, error) {
+	qs := url.Values{}
+	qs.Set("api_key", c.apiKey)
+	qs.Set("query", title)
+	if year > 0 {
+		qs.Set("year", strconv.Itoa(year))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/search/movie?"+qs.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// APIキーをログに出さないようにURLは含めない
+		return nil, fmt.Errorf("tmdb search: unexpected status %d", resp.StatusCode)
+	}
+
+	var body tmdbSearchResponse
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body)
+	if err != nil {
+		return nil, fmt.Errorf("tmdb search: %w", err)
+	}
+
+	// 検索結果は関連度の順なので先頭を使う
+	if len(body.Results) == 0 {
+		return nil, ErrNoMatch
+	}
+	result := body.Results[0]
+
+	suggestion := &Suggestion{
+		ExternalID: strconv.Itoa(result.ID),
+		Title: result.Title,
+		Overview: result.Overview,
+		PosterRef: result.PosterPath,
+	}
+	if result.ReleaseDate != "" {
+		suggestion.ReleaseDate, err = time.Parse("2006-01-02", result.ReleaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("tmdb search: invalid release_date %q", result.ReleaseDate)
+		}
+	}
+
+	return suggestion, nil
+}
+
+func (c *TMDBClient) Image(ctx context.Context, ref string) (io.ReadCloser, error) {
+	// poster_pathは"/abc.jpg"の形式
+	if !strings.HasPrefix(ref, "/") || strings.Contains(ref, "..") {
+		return nil, ErrImageNotFound
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.imageURL+ref, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrImageNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("tmdb image: unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,67 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTMDBClientLookup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search/movie":
+			if r.URL.Query().Get("api_key") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("query") != "Inception" || r.URL.Query().Get("year") != "2010" {
+				w.Write([]byte(`{"results":[]}`))
+				return
+			}
+			w.Write([]byte(`{"page":1,"results":[{"id":27205,"title":"Inception","overview":"Cobb","poster_path":"/abc.jpg","release_date":"2010-07-15"}]}`))
+		case "/img/abc.jpg":
+			w.Write([]byte("image"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewTMDBClient(srv.URL, srv.URL+"/img", "secret")
+
+	s, err := c.Lookup(context.Background(), "Inception", 2010)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ExternalID != "27205" || s.Overview != "Cobb" || s.PosterRef != "/abc.jpg" || s.ReleaseDate.Format("2006-01-02") != "2010-07-15" {
+		t.Errorf("suggestion = %+v", s)
+	}
+
+	_, err = c.Lookup(context.Background(), "Unknown", 0)
+	if !errors.Is(err, ErrNoMatch) {
+		t.Errorf("no results: err = %v, want ErrNoMatch", err)
+	}
+
+	img, err := c.Image(context.Background(), s.PosterRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(img)
+	img.Close()
+	if string(data) != "image" {
+		t.Errorf("image = %q", data)
+	}
+
+	_, err = c.Image(context.Background(), "/missing.jpg")
+	if !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("missing image: err = %v, want ErrImageNotFound", err)
+	}
+
+	_, err = NewTMDBClient(srv.URL, "", "wrong").Lookup(context.Background(), "Inception", 2010)
+	if err == nil {
+		t.Error("lookup with a wrong key succeeded")
+	}
+}
@@ -0,0 +1,361 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ゴミ箱を除いたmovieの件数を含めたcollectionのselect
+const collectionSelect = `select
+					c.id, c.name, c.description, c.created_at, c.updated_at,
+					(select count(*) from collection_movies cm join movies m on (m.id = cm.movie_id)
+						where cm.collection_id = c.id and m.deleted_at is null)
+				from
+					collections c`
+
+func scanCollection(row interface{ Scan(...interface{}) error }) (*Collection, error) {
+	var c Collection
+	err := row.Scan(
+		&c.ID,
+		&c.Name,
+		&c.Description,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+		&c.MovieCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// すべてのcollectionを名前順に返すメソッド
+func (m *DBModel) Collections() ([]*Collection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, collectionSelect+` order by lower(c.name), c.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []*Collection{}
+
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+
+	return collections, rows.Err()
+}
+
+// collectionとそのmovie(ゴミ箱のものを除く)をpositionの順に返すメソッド
+func (m *DBModel) GetCollection(id int) (*Collection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	c, err := scanCollection(m.DB.QueryRowContext(ctx, collectionSelect+` where c.id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	query := `select
+					m.id, m.title, m.description, m.year, m.release_date, m.runtime, m.rating, m.mpaa_rating,
+					m.created_at, m.updated_at, m.version, m.review_count, m.review_score, coalesce(m.poster, '')
+				from
+					collection_movies cm
+					join movies m on (m.id = cm.movie_id)
+				where
+					cm.collection_id = $1 and m.deleted_at is null
+				order by
+					cm.position, cm.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	c.Movies = []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.Title,
+			&movie.Description,
+			&movie.Year,
+			&movie.ReleaseDate,
+			&movie.Runtime,
+			&movie.Rating,
+			&movie.MPAARating,
+			&movie.CreatedAt,
+			&movie.UpdatedAt,
+			&movie.Version,
+			&movie.ReviewCount,
+			&movie.ReviewScore,
+			&movie.Poster,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.Movies = append(c.Movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	err = m.loadGenresBatch(ctx, c.Movies)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (m *DBModel) collectionNameExists(ctx context.Context, name string, exceptID int) (bool, error) {
+	query := `select exists(select 1 from collections where lower(name) = lower($1) and id <> $2)`
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, name, exceptID).Scan(&exists)
+	return exists, err
+}
+
+func (m *DBModel) InsertCollection(collection Collection) (int, error) {
+	err := collection.validate()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	exists, err := m.collectionNameExists(ctx, collection.Name, 0)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, ErrDuplicateCollection
+	}
+
+	stmt := `insert into collections (name, description, created_at, updated_at) values ($1, $2, $3, $4) returning id`
+
+	var id int
+	err = m.DB.QueryRowContext(ctx, stmt, collection.Name, collection.Description, collection.CreatedAt, collection.UpdatedAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) UpdateCollection(collection Collection) error {
+	err := collection.validate()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	exists, err := m.collectionNameExists(ctx, collection.Name, collection.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrDuplicateCollection
+	}
+
+	stmt := `update collections set name = $1, description = $2, updated_at = $3 where id = $4`
+
+	res, err := m.DB.ExecContext(ctx, stmt, collection.Name, collection.Description, collection.UpdatedAt, collection.ID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// collectionを削除する(movieは削除しない)
+func (m *DBModel) DeleteCollection(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, `delete from collections where id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// collectionのmovieをmovieIDsの順で置き換える(含めなかったmovieはゴミ箱のものも含めてcollectionから外れる)
+func (m *DBModel) SetCollectionMovies(id int, movieIDs []int) error {
+	err := validateCollectionMovies(movieIDs)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `select true from collections where id = $1` + m.dialect.rowLock("for update"), id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if len(movieIDs) > 0 {
+		var args []interface{}
+		var placeholders []string
+		for _, movieID := range movieIDs {
+			args = append(args, movieID)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		in := fmt.Sprintf("(%s)", strings.Join(placeholders, ", "))
+
+		var found int
+		query := `select count(*) from movies where deleted_at is null and id in ` + in
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&found)
+		if err != nil {
+			return err
+		}
+		if found != len(movieIDs) {
+			return ErrUnknownMovie
+		}
+
+		args = append(args, id)
+		query = fmt.Sprintf(`select exists(select 1 from collection_movies where movie_id in %s and collection_id <> $%d)`, in, len(args))
+		var inOther bool
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&inOther)
+		if err != nil {
+			return err
+		}
+		if inOther {
+			return ErrMovieInCollection
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from collection_movies where collection_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i, movieID := range movieIDs {
+		stmt := `insert into collection_movies (collection_id, movie_id, position, created_at, updated_at) values ($1, $2, $3, $4, $5)`
+		_, err = tx.ExecContext(ctx, stmt, id, movieID, i+1, now, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `update collections set updated_at = $1 where id = $2`, now, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// movieのcollectionと前後のmovieをセットする(ゴミ箱のmovieは飛ばす)
+func (m *DBModel) LoadCollections(movies []*Movie) error {
+	if len(movies) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var args []interface{}
+	var placeholders []string
+	for _, movie := range movies {
+		args = append(args, movie.ID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	in := fmt.Sprintf("(%s)", strings.Join(placeholders, ", "))
+
+	query := `select
+					t.movie_id, t.collection_id, t.name, t.position, t.prev_id, t.prev_title, t.next_id, t.next_title
+				from (
+					select
+						cm.movie_id, c.id as collection_id, c.name,
+						row_number() over w as position,
+						lag(m.id) over w as prev_id, lag(m.title) over w as prev_title,
+						lead(m.id) over w as next_id, lead(m.title) over w as next_title
+					from
+						collection_movies cm
+						join collections c on (c.id = cm.collection_id)
+						join movies m on (m.id = cm.movie_id)
+					where
+						m.deleted_at is null
+						and cm.collection_id in (select collection_id from collection_movies where movie_id in ` + in + `)
+					window w as (partition by cm.collection_id order by cm.position, cm.id)
+				) t
+				where
+					t.movie_id in ` + in
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	positions := make(map[int]*MovieCollection)
+	for rows.Next() {
+		var movieID int
+		var mc MovieCollection
+		var prevID, nextID sql.NullInt64
+		var prevTitle, nextTitle sql.NullString
+		err := rows.Scan(&movieID, &mc.ID, &mc.Name, &mc.Position, &prevID, &prevTitle, &nextID, &nextTitle)
+		if err != nil {
+			return err
+		}
+		if prevID.Valid {
+			mc.Previous = &CollectionNeighbor{ID: int(prevID.Int64), Title: prevTitle.String}
+		}
+		if nextID.Valid {
+			mc.Next = &CollectionNeighbor{ID: int(nextID.Int64), Title: nextTitle.String}
+		}
+		positions[movieID] = &mc
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		movie.Collection = positions[movie.ID]
+	}
+
+	return nil
+}
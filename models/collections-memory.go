@@ -0,0 +1,202 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+)
+
+// collectionのmovieのうちゴミ箱にないものをpositionの順に返す
+func (m *MemoryModel) visibleCollectionMovies(id int) []*Movie {
+	var movies []*Movie
+	for _, movieID := range m.collectionMovies[id] {
+		movie, ok := m.movies[movieID]
+		if ok && movie.DeletedAt == nil {
+			movies = append(movies, m.copyMovie(movie))
+		}
+	}
+	return movies
+}
+
+func (m *MemoryModel) copyCollection(c Collection) *Collection {
+	c.MovieCount = len(m.visibleCollectionMovies(c.ID))
+	c.Movies = nil
+	return &c
+}
+
+func (m *MemoryModel) Collections() ([]*Collection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	collections := []*Collection{}
+	for _, c := range m.collections {
+		collections = append(collections, m.copyCollection(c))
+	}
+
+	sort.Slice(collections, func(i, j int) bool {
+		a, b := strings.ToLower(collections[i].Name), strings.ToLower(collections[j].Name)
+		if a != b {
+			return a < b
+		}
+		return collections[i].ID < collections[j].ID
+	})
+
+	return collections, nil
+}
+
+func (m *MemoryModel) GetCollection(id int) (*Collection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.collections[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	collection := m.copyCollection(c)
+	collection.Movies = m.visibleCollectionMovies(id)
+	if collection.Movies == nil {
+		collection.Movies = []*Movie{}
+	}
+
+	return collection, nil
+}
+
+func (m *MemoryModel) collectionNameExists(name string, exceptID int) bool {
+	for _, c := range m.collections {
+		if c.ID != exceptID && strings.EqualFold(c.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryModel) InsertCollection(collection Collection) (int, error) {
+	err := collection.validate()
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.collectionNameExists(collection.Name, 0) {
+		return 0, ErrDuplicateCollection
+	}
+
+	collection.ID = m.nextCollectionID
+	collection.MovieCount = 0
+	collection.Movies = nil
+	m.nextCollectionID++
+
+	m.collections[collection.ID] = collection
+
+	return collection.ID, nil
+}
+
+func (m *MemoryModel) UpdateCollection(collection Collection) error {
+	err := collection.validate()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.collections[collection.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if m.collectionNameExists(collection.Name, collection.ID) {
+		return ErrDuplicateCollection
+	}
+
+	current.Name = collection.Name
+	current.Description = collection.Description
+	current.UpdatedAt = collection.UpdatedAt
+	m.collections[collection.ID] = current
+
+	return nil
+}
+
+func (m *MemoryModel) DeleteCollection(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.collections[id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	delete(m.collections, id)
+	delete(m.collectionMovies, id)
+
+	return nil
+}
+
+func (m *MemoryModel) SetCollectionMovies(id int, movieIDs []int) error {
+	err := validateCollectionMovies(movieIDs)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.collections[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	for _, movieID := range movieIDs {
+		movie, ok := m.movies[movieID]
+		if !ok || movie.DeletedAt != nil {
+			return ErrUnknownMovie
+		}
+		for otherID, ids := range m.collectionMovies {
+			if otherID != id && containsInt(ids, movieID) {
+				return ErrMovieInCollection
+			}
+		}
+	}
+
+	m.collectionMovies[id] = append([]int{}, movieIDs...)
+	c.UpdatedAt = time.Now()
+	m.collections[id] = c
+
+	return nil
+}
+
+func (m *MemoryModel) LoadCollections(movies []*Movie) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	positions := make(map[int]*MovieCollection)
+	for id, c := range m.collections {
+		if len(m.collectionMovies[id]) == 0 {
+			continue
+		}
+		for movieID, mc := range collectionNeighbors(c, m.visibleCollectionMovies(id)) {
+			positions[movieID] = mc
+		}
+	}
+
+	for _, movie := range movies {
+		movie.Collection = positions[movie.ID]
+	}
+
+	return nil
+}
+
+// collection_moviesのon delete cascadeに合わせて削除したmovieをcollectionから外す
+func (m *MemoryModel) removeFromCollections(movieID int) {
+	for id, ids := range m.collectionMovies {
+		var kept []int
+		for _, v := range ids {
+			if v != movieID {
+				kept = append(kept, v)
+			}
+		}
+		m.collectionMovies[id] = kept
+	}
+}
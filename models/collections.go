@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrDuplicateCollection is returned when another collection already has the name
+	ErrDuplicateCollection = errors.New("a collection with that name already exists")
+	// ErrMovieInCollection is returned when a movie already belongs to another collection
+	ErrMovieInCollection = errors.New("movie already belongs to another collection")
+	// ErrUnknownMovie is returned when a collection refers to a movie that does not exist or is in the trash
+	ErrUnknownMovie = errors.New("unknown movie id")
+)
+
+// Collection is an ordered group of movies such as a trilogy or a franchise
+type Collection struct {
+	ID int `json:"id"`
+	Name string `json:"name"`
+	Description string `json:"description"`
+	// ゴミ箱のmovieを除いた件数
+	MovieCount int `json:"movie_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// GetCollectionのみ(positionの順)
+	Movies []*Movie `json:"movies,omitempty"`
+}
+
+// MovieCollection is the collection a movie belongs to, as shown in movie responses
+type MovieCollection struct {
+	ID int `json:"id"`
+	Name string `json:"name"`
+	// 1から始まるcollectionの中での順番(ゴミ箱のmovieは数えない)
+	Position int `json:"position"`
+	Previous *CollectionNeighbor `json:"previous"`
+	Next *CollectionNeighbor `json:"next"`
+}
+
+// CollectionNeighbor is the movie before or after another one in a collection
+type CollectionNeighbor struct {
+	ID int `json:"id"`
+	Title string `json:"title"`
+}
+
+// collectionの名前と説明を検証する
+func (c *Collection) validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	if len(c.Name) > 255 {
+		return errors.New("name must not be longer than 255 bytes")
+	}
+	if len(c.Description) > 10000 {
+		return errors.New("description must not be longer than 10000 bytes")
+	}
+	return nil
+}
+
+// 並び順のmovie idに重複や不正な値がないかを確認する
+func validateCollectionMovies(movieIDs []int) error {
+	seen := make(map[int]bool)
+	for _, id := range movieIDs {
+		if id <= 0 {
+			return fmt.Errorf("%w %d", ErrUnknownMovie, id)
+		}
+		if seen[id] {
+			return fmt.Errorf("movie %d appears more than once", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// 並び順のmovieから、各movieのcollectionでの位置と前後のmovieを求める
+func collectionNeighbors(c Collection, ordered []*Movie) map[int]*MovieCollection {
+	positions := make(map[int]*MovieCollection)
+	for i, movie := range ordered {
+		mc := &MovieCollection{ID: c.ID, Name: c.Name, Position: i + 1}
+		if i > 0 {
+			mc.Previous = &CollectionNeighbor{ID: ordered[i-1].ID, Title: ordered[i-1].Title}
+		}
+		if i < len(ordered)-1 {
+			mc.Next = &CollectionNeighbor{ID: ordered[i+1].ID, Title: ordered[i+1].Title}
+		}
+		positions[movie.ID] = mc
+	}
+	return positions
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// DBModelが発行するSQLの方言(ほとんどのクエリは共通で、違うところだけここで切り替える)
+type dialect int
+
+const (
+	dialectPostgres dialect = iota
+	// ローカルの開発とテスト用
+	dialectSQLite
+)
+
+// 行ロックの句(SQLiteはトランザクションがDB全体をロックするので付けない)
+func (d dialect) rowLock(clause string) string {
+	if d == dialectSQLite {
+		return ""
+	}
+	return " " + clause
+}
+
+// search_vectorに入れる式(SQLiteはmovies_ftsをtriggerで更新するのでnullにしておく)
+func (d dialect) searchVector(title, description string) string {
+	if d == dialectSQLite {
+		return "null"
+	}
+	return searchVectorSQL(title, description)
+}
+
+// movieのgenreをidとgenre名のJSONオブジェクトとid順のJSON配列にする式
+func (d dialect) genresJSON(movieID string) (string, string) {
+	if d == dialectSQLite {
+		return fmt.Sprintf(`coalesce((select json_group_object(mg.id, g.genre_name) from movies_genres mg
+								join genres g on (g.id = mg.genre_id) where mg.movie_id = %s), '{}')`, movieID),
+			fmt.Sprintf(`coalesce((select json_group_array(genre_id) from (select mg.genre_id from movies_genres mg
+								where mg.movie_id = %s order by mg.genre_id)), '[]')`, movieID)
+	}
+	return fmt.Sprintf(`coalesce((select json_object_agg(mg.id, g.genre_name) from movies_genres mg
+								join genres g on (g.id = mg.genre_id) where mg.movie_id = %s), '{}')`, movieID),
+		fmt.Sprintf(`coalesce((select json_agg(mg.genre_id order by mg.genre_id) from movies_genres mg
+								where mg.movie_id = %s), '[]')`, movieID)
+}
+
+// unique制約に違反したエラーか(事前の確認のあとに同時に書き込まれた場合)
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return false
+}
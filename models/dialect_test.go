@@ -0,0 +1,44 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	db, err := OpenSQLite("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`create table t (name text unique)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`insert into t (name) values ('a')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sqliteErr := db.Exec(`insert into t (name) values ('a')`)
+
+	tests := []struct {
+		name string
+		err error
+		want bool
+	}{
+		{"postgres unique", &pq.Error{Code: "23505"}, true},
+		{"postgres foreign key", &pq.Error{Code: "23503"}, false},
+		{"sqlite unique", sqliteErr, true},
+		{"other", errors.New("boom"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := isUniqueViolation(tt.err); got != tt.want {
+			t.Errorf("%s: isUniqueViolation(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// カーソルから1回に読み込む行数
+const exportFetchSize = 500
+
+// filterに合うmovieを並び順にfnへ渡すメソッド(サーバー側のカーソルで少しずつ読み込む)
+// 件数に上限がないので、タイムアウトではなく呼び出し元のctx(リクエストの切断など)で止める
+func (m *DBModel) ExportMovies(ctx context.Context, filter MovieFilter, fn func(*Movie) error) error {
+	filter.Cursor = ""
+
+	var args []interface{}
+
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where, orderBy, err := movieFilterSQL(filter, arg)
+	if err != nil {
+		return err
+	}
+
+	// genreは行ごとにJSONでまとめて取得する(movieを溜めずに返せるようにする)
+	genres, genreIDs := m.dialect.genresJSON("movies.id")
+	query := fmt.Sprintf(`select id, title, description, year, release_date, runtime, rating, mpaa_rating,
+							created_at, updated_at, version, review_count, review_score, coalesce(poster, ''),
+							%s,
+							%s
+						from movies %s %s`, genres, genreIDs, where, orderBy)
+
+	// カーソルはトランザクションの中でしか使えない
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// SQLiteはカーソルがないが、行は読み込むたびに取り出されるのでそのまま読む
+	if m.dialect == dialectSQLite {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		_, err = exportScan(rows, fn)
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	_, err = tx.ExecContext(ctx, "declare movie_export no scroll cursor for "+query, args...)
+	if err != nil {
+		return err
+	}
+
+	for {
+		n, err := exportFetch(ctx, tx, fn)
+		if err != nil {
+			return err
+		}
+		if n < exportFetchSize {
+			break
+		}
+	}
+
+	return tx.Commit()
+}
+
+// カーソルから次の行を読み込んでfnに渡し、読み込んだ行数を返す
+func exportFetch(ctx context.Context, tx *sql.Tx, fn func(*Movie) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("fetch %d from movie_export", exportFetchSize))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	return exportScan(rows, fn)
+}
+
+// 行をmovieにしてfnに渡し、読み込んだ行数を返す
+func exportScan(rows *sql.Rows, fn func(*Movie) error) (int, error) {
+	n := 0
+	for rows.Next() {
+		var movie Movie
+		var genres, genreIDs []byte
+		err := rows.Scan(
+			&movie.ID,
+			&movie.Title,
+			&movie.Description,
+			&movie.Year,
+			&movie.ReleaseDate,
+			&movie.Runtime,
+			&movie.Rating,
+			&movie.MPAARating,
+			&movie.CreatedAt,
+			&movie.UpdatedAt,
+			&movie.Version,
+			&movie.ReviewCount,
+			&movie.ReviewScore,
+			&movie.Poster,
+			&genres,
+			&genreIDs,
+		)
+		if err != nil {
+			return n, err
+		}
+
+		err = json.Unmarshal(genres, &movie.MovieGenre)
+		if err != nil {
+			return n, err
+		}
+		err = json.Unmarshal(genreIDs, &movie.GenreIDs)
+		if err != nil {
+			return n, err
+		}
+
+		err = fn(&movie)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, rows.Err()
+}
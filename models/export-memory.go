@@ -0,0 +1,54 @@
+package models
+
+import (
+	"context"
+	"sort"
+)
+
+func (m *MemoryModel) ExportMovies(ctx context.Context, filter MovieFilter, fn func(*Movie) error) error {
+	filter.Cursor = ""
+
+	err := filter.Validate()
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	var movies []*Movie
+	for _, movie := range m.movies {
+		if movie.DeletedAt != nil {
+			continue
+		}
+		genreIDs := make(map[int]bool)
+		for _, mg := range m.movieGenres {
+			if mg.MovieID == movie.ID {
+				genreIDs[mg.GenreID] = true
+			}
+		}
+		movie := movie
+		if filter.TagID > 0 && !m.hasTag(movie.ID, filter.TagID) {
+			continue
+		}
+		if filter.matches(&movie, genreIDs) {
+			movies = append(movies, m.copyMovie(movie))
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(movies, func(i, j int) bool {
+		return compareMovies(movies[i], movies[j], filter.sort()) < 0
+	})
+
+	// fnの中でストアを呼び出せるようにロックを外してから渡す
+	for _, movie := range movies {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err = fn(movie)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ゴミ箱にないすべてのmovieのgenre・credit・タグを返すメソッド
+func (m *DBModel) MovieFeatures() ([]*MovieFeatures, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 同じ時点の内容を読むためにトランザクションにする
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `select id, title, description, year from movies where deleted_at is null order by id`)
+	if err != nil {
+		return nil, err
+	}
+
+	features := []*MovieFeatures{}
+	byID := make(map[int]*MovieFeatures)
+	for rows.Next() {
+		var f MovieFeatures
+		err := rows.Scan(&f.MovieID, &f.Title, &f.Description, &f.Year)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		features = append(features, &f)
+		byID[f.MovieID] = &f
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	links := []struct {
+		query string
+		add func(f *MovieFeatures, id int)
+	}{
+		{`select movie_id, genre_id from movies_genres`, func(f *MovieFeatures, id int) { f.GenreIDs = append(f.GenreIDs, id) }},
+		{`select distinct movie_id, person_id from movie_credits`, func(f *MovieFeatures, id int) { f.PersonIDs = append(f.PersonIDs, id) }},
+		{`select movie_id, tag_id from movie_tags`, func(f *MovieFeatures, id int) { f.TagIDs = append(f.TagIDs, id) }},
+	}
+	for _, l := range links {
+		rows, err := tx.QueryContext(ctx, l.query)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var movieID, id int
+			err := rows.Scan(&movieID, &id)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			// ゴミ箱のmovieの行は無視する
+			if f, ok := byID[movieID]; ok {
+				l.add(f, id)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return features, tx.Commit()
+}
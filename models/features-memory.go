@@ -0,0 +1,41 @@
+package models
+
+import "sort"
+
+func (m *MemoryModel) MovieFeatures() ([]*MovieFeatures, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	features := []*MovieFeatures{}
+	for _, movie := range m.movies {
+		if movie.DeletedAt != nil {
+			continue
+		}
+
+		f := &MovieFeatures{
+			MovieID: movie.ID,
+			Title: movie.Title,
+			Description: movie.Description,
+			Year: movie.Year,
+			TagIDs: append([]int{}, m.movieTags[movie.ID]...),
+		}
+		for _, mg := range m.movieGenres {
+			if mg.MovieID == movie.ID {
+				f.GenreIDs = append(f.GenreIDs, mg.GenreID)
+			}
+		}
+		for _, c := range m.credits {
+			if c.MovieID == movie.ID {
+				f.PersonIDs = append(f.PersonIDs, c.PersonID)
+			}
+		}
+		f.PersonIDs = uniqueInts(f.PersonIDs)
+		features = append(features, f)
+	}
+
+	sort.Slice(features, func(i, j int) bool {
+		return features[i].MovieID < features[j].MovieID
+	})
+
+	return features, nil
+}
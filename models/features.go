@@ -0,0 +1,12 @@
+package models
+
+// MovieFeatures is what the similar movies index needs to know about a movie
+type MovieFeatures struct {
+	MovieID int
+	Title string
+	Description string
+	Year int
+	GenreIDs []int
+	PersonIDs []int
+	TagIDs []int
+}
@@ -0,0 +1,254 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MPAARatings is the list of accepted MPAA ratings
+var MPAARatings = []string{"G", "PG", "PG-13", "R", "NC-17"}
+
+// 並び替えに使える項目とカラム名
+var movieSortFields = map[string]string{
+	"id": "id",
+	"title": "title",
+	"year": "year",
+	"release_date": "release_date",
+	"runtime": "runtime",
+	"rating": "rating",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// MovieFilter holds the filtering, sorting and paging options for listing movies
+type MovieFilter struct {
+	GenreIDs []int
+	// "any"(いずれかのgenre)または"all"(すべてのgenre)
+	GenreMatch string
+	YearMin int
+	YearMax int
+	// 公開日と年齢制限はregionに関係なく元のrelease_dateとmpaa_rating(USの年齢制限)で絞り込む
+	ReleasedAfter time.Time
+	ReleasedBefore time.Time
+	RuntimeMin int
+	RuntimeMax int
+	RatingMin int
+	MPAARatings []string
+	// GenreIDsとGenreMatchに関係なく必ず含むgenre(クエリパラメータではなく/v1/movies/:genre_idのidから決める)
+	RequiredGenreID int
+	// タグで絞り込む(クエリパラメータではなく/v1/tags/:slug/moviesのslugから決める)
+	TagID int
+	// "title"なら昇順、"-title"なら降順(release_dateもregionに関係なく元の値で並べる)
+	Sort string
+	Limit int
+	Cursor string
+}
+
+// "-year" -> "year", true
+func parseSort(sort string) (string, bool) {
+	if strings.HasPrefix(sort, "-") {
+		return sort[1:], true
+	}
+	return sort, false
+}
+
+func (f MovieFilter) sort() string {
+	if f.Sort == "" {
+		return "title"
+	}
+	return f.Sort
+}
+
+// Validate checks the filter for unknown or contradictory values
+func (f MovieFilter) Validate() error {
+	field, _ := parseSort(f.sort())
+	if _, ok := movieSortFields[field]; !ok {
+		var fields []string
+		for name := range movieSortFields {
+			fields = append(fields, name)
+		}
+		sort.Strings(fields)
+		return fmt.Errorf("unknown sort field %q (allowed: %s)", field, strings.Join(fields, ", "))
+	}
+
+	switch f.GenreMatch {
+	case "", "any", "all":
+	default:
+		return fmt.Errorf("genre_match must be any or all")
+	}
+
+	for _, id := range f.GenreIDs {
+		if id <= 0 {
+			return fmt.Errorf("invalid genre id %d", id)
+		}
+	}
+
+	for _, r := range f.MPAARatings {
+		if !containsString(MPAARatings, r) {
+			return fmt.Errorf("unknown mpaa rating %q (allowed: %s)", r, strings.Join(MPAARatings, ", "))
+		}
+	}
+
+	if f.YearMin > 0 && f.YearMax > 0 && f.YearMin > f.YearMax {
+		return fmt.Errorf("year_min must not be greater than year_max")
+	}
+	if f.RuntimeMin > 0 && f.RuntimeMax > 0 && f.RuntimeMin > f.RuntimeMax {
+		return fmt.Errorf("runtime_min must not be greater than runtime_max")
+	}
+	if !f.ReleasedAfter.IsZero() && !f.ReleasedBefore.IsZero() && f.ReleasedAfter.After(f.ReleasedBefore) {
+		return fmt.Errorf("released_after must not be later than released_before")
+	}
+
+	return nil
+}
+
+// matches reports whether the movie passes every filter except paging
+func (f MovieFilter) matches(movie *Movie, genreIDs map[int]bool) bool {
+	if f.YearMin > 0 && movie.Year < f.YearMin {
+		return false
+	}
+	if f.YearMax > 0 && movie.Year > f.YearMax {
+		return false
+	}
+	if !f.ReleasedAfter.IsZero() && movie.ReleaseDate.Before(f.ReleasedAfter) {
+		return false
+	}
+	if !f.ReleasedBefore.IsZero() && movie.ReleaseDate.After(f.ReleasedBefore) {
+		return false
+	}
+	if f.RuntimeMin > 0 && movie.Runtime < f.RuntimeMin {
+		return false
+	}
+	if f.RuntimeMax > 0 && movie.Runtime > f.RuntimeMax {
+		return false
+	}
+	if f.RatingMin > 0 && movie.Rating < f.RatingMin {
+		return false
+	}
+	if len(f.MPAARatings) > 0 && !containsString(f.MPAARatings, movie.MPAARating) {
+		return false
+	}
+
+	if f.RequiredGenreID > 0 && !genreIDs[f.RequiredGenreID] {
+		return false
+	}
+
+	if len(f.GenreIDs) > 0 {
+		found := 0
+		for _, id := range f.GenreIDs {
+			if genreIDs[id] {
+				found++
+			}
+		}
+		if f.GenreMatch == "all" && found < len(f.GenreIDs) {
+			return false
+		}
+		if found == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 並び替えの項目の値を返す
+func movieSortValue(movie *Movie, field string) interface{} {
+	switch field {
+	case "title":
+		return movie.Title
+	case "year":
+		return movie.Year
+	case "release_date":
+		return movie.ReleaseDate
+	case "runtime":
+		return movie.Runtime
+	case "rating":
+		return movie.Rating
+	case "created_at":
+		return movie.CreatedAt
+	case "updated_at":
+		return movie.UpdatedAt
+	}
+	return movie.ID
+}
+
+// カーソルの値を並び替えの項目にセットする
+func setMovieSortValue(movie *Movie, field string, value json.RawMessage) error {
+	switch field {
+	case "title":
+		return json.Unmarshal(value, &movie.Title)
+	case "year":
+		return json.Unmarshal(value, &movie.Year)
+	case "release_date":
+		return json.Unmarshal(value, &movie.ReleaseDate)
+	case "runtime":
+		return json.Unmarshal(value, &movie.Runtime)
+	case "rating":
+		return json.Unmarshal(value, &movie.Rating)
+	case "created_at":
+		return json.Unmarshal(value, &movie.CreatedAt)
+	case "updated_at":
+		return json.Unmarshal(value, &movie.UpdatedAt)
+	}
+	return json.Unmarshal(value, &movie.ID)
+}
+
+// (並び替えの項目, id)でaとbを比較する
+func compareMovies(a, b *Movie, sortBy string) int {
+	field, desc := parseSort(sortBy)
+
+	c := 0
+	switch av := movieSortValue(a, field).(type) {
+	case string:
+		c = strings.Compare(av, movieSortValue(b, field).(string))
+	case int:
+		c = compareInts(av, movieSortValue(b, field).(int))
+	case time.Time:
+		bv := movieSortValue(b, field).(time.Time)
+		switch {
+		case av.Before(bv):
+			c = -1
+		case av.After(bv):
+			c = 1
+		}
+	}
+	if c == 0 {
+		c = compareInts(a.ID, b.ID)
+	}
+
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// 同じ名前(大文字小文字を区別しない)のgenreがほかにあるかを確認する
+func (m *DBModel) genreNameExists(ctx context.Context, name string, exceptID int) (bool, error) {
+	query := `select exists(select 1 from genres where lower(genre_name) = lower($1) and id <> $2)`
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, name, exceptID).Scan(&exists)
+	return exists, err
+}
+
+func (m *DBModel) InsertGenre(genre Genre) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	name, err := normalizeGenreName(genre.GenreName)
+	if err != nil {
+		return 0, err
+	}
+
+	exists, err := m.genreNameExists(ctx, name, 0)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, ErrDuplicateGenre
+	}
+
+	stmt := `insert into genres (genre_name, created_at, updated_at) values ($1, $2, $3) returning id`
+
+	var id int
+	err = m.DB.QueryRowContext(ctx, stmt, name, genre.CreatedAt, genre.UpdatedAt).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, ErrDuplicateGenre
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) UpdateGenre(genre Genre) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	name, err := normalizeGenreName(genre.GenreName)
+	if err != nil {
+		return err
+	}
+
+	exists, err := m.genreNameExists(ctx, name, genre.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrDuplicateGenre
+	}
+
+	stmt := `update genres set genre_name = $1, updated_at = $2 where id = $3`
+
+	res, err := m.DB.ExecContext(ctx, stmt, name, genre.UpdatedAt, genre.ID)
+	if isUniqueViolation(err) {
+		return ErrDuplicateGenre
+	}
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// genreを削除する(使用中の場合はreassignToのgenreに付け替える)
+func (m *DBModel) DeleteGenre(id int, reassignTo int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 削除中に紐づけが増えないようにロックする
+	var exists bool
+	err = tx.QueryRowContext(ctx, `select true from genres where id = $1` + m.dialect.rowLock("for update"), id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	var inUse int
+	err = tx.QueryRowContext(ctx, `select count(*) from movies_genres where genre_id = $1`, id).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+
+	if inUse > 0 {
+		if reassignTo == 0 {
+			return ErrGenreInUse
+		}
+		if reassignTo == id {
+			return ErrInvalidReassign
+		}
+
+		err = tx.QueryRowContext(ctx, `select true from genres where id = $1` + m.dialect.rowLock("for update"), reassignTo).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return ErrInvalidReassign
+		}
+		if err != nil {
+			return err
+		}
+
+		// 付け替え先のgenreをすでにもつmovieは重複するので紐づけを削除する
+		stmt := `delete from movies_genres where genre_id = $1
+							and movie_id in (select movie_id from movies_genres where genre_id = $2)`
+		_, err = tx.ExecContext(ctx, stmt, id, reassignTo)
+		if err != nil {
+			return err
+		}
+
+		stmt = `update movies_genres set genre_id = $1, updated_at = $2 where genre_id = $3`
+		_, err = tx.ExecContext(ctx, stmt, reassignTo, time.Now(), id)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from genres where id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// 同じ名前(大文字小文字を区別しない)のgenreがほかにあるかを確認する
+func (m *MemoryModel) genreNameExists(name string, exceptID int) bool {
+	for _, g := range m.genres {
+		if g.ID != exceptID && strings.EqualFold(g.GenreName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryModel) InsertGenre(genre Genre) (int, error) {
+	name, err := normalizeGenreName(genre.GenreName)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.genreNameExists(name, 0) {
+		return 0, ErrDuplicateGenre
+	}
+
+	genre.ID = m.nextGenreID
+	genre.GenreName = name
+	m.nextGenreID++
+
+	m.genres[genre.ID] = genre
+
+	return genre.ID, nil
+}
+
+func (m *MemoryModel) UpdateGenre(genre Genre) error {
+	name, err := normalizeGenreName(genre.GenreName)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.genres[genre.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if m.genreNameExists(name, genre.ID) {
+		return ErrDuplicateGenre
+	}
+
+	current.GenreName = name
+	current.UpdatedAt = genre.UpdatedAt
+	m.genres[genre.ID] = current
+
+	return nil
+}
+
+func (m *MemoryModel) DeleteGenre(id int, reassignTo int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.genres[id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	inUse := false
+	for _, mg := range m.movieGenres {
+		if mg.GenreID == id {
+			inUse = true
+			break
+		}
+	}
+
+	if inUse {
+		if reassignTo == 0 {
+			return ErrGenreInUse
+		}
+		if _, ok := m.genres[reassignTo]; !ok || reassignTo == id {
+			return ErrInvalidReassign
+		}
+
+		for mgID, mg := range m.movieGenres {
+			if mg.GenreID != id {
+				continue
+			}
+			// 付け替え先のgenreをすでにもつmovieは重複するので紐づけを削除する
+			if m.hasGenre(mg.MovieID, reassignTo) {
+				delete(m.movieGenres, mgID)
+				continue
+			}
+			mg.GenreID = reassignTo
+			m.movieGenres[mgID] = mg
+		}
+	}
+
+	delete(m.genres, id)
+
+	// genre_translationsのon delete cascadeに合わせる
+	for key := range m.genreTranslations {
+		if key.ID == id {
+			delete(m.genreTranslations, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrDuplicateGenre is returned when another genre already has the name
+	ErrDuplicateGenre = errors.New("a genre with that name already exists")
+	// ErrGenreInUse is returned when deleting a genre that movies still use
+	ErrGenreInUse = errors.New("genre is still used by movies; pass reassign_to to move them to another genre")
+	// ErrInvalidReassign is returned when the reassign target is the deleted genre or does not exist
+	ErrInvalidReassign = errors.New("reassign_to must be another existing genre")
+	// ErrUnknownGenre is returned when a movie is linked to a genre that does not exist
+	ErrUnknownGenre = errors.New("unknown genre id")
+)
+
+// genre名の前後の空白を取り除いて検証する
+func normalizeGenreName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("genre_name must not be empty")
+	}
+	if len(name) > 255 {
+		return "", errors.New("genre_name must not be longer than 255 bytes")
+	}
+	return name, nil
+}
+
+// 重複したidを取り除く(順序は保つ)
+func uniqueInts(ids []int) []int {
+	seen := make(map[int]bool)
+	unique := []int{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
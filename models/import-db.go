@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// 行をひとつのトランザクションで追加・更新するメソッド(失敗した行はsavepointまで戻して続ける)
+func (m *DBModel) ImportMovies(rows []ImportRow, opts ImportOptions) ([]ImportResult, error) {
+	// 1件ずつの書き込みより時間がかかるので長めにする
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	genreIDs, err := genreIDsTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []ImportResult{}
+
+	for _, row := range rows {
+		_, err = tx.ExecContext(ctx, `savepoint import_row`)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := m.importRowTx(ctx, tx, row, genreIDs, opts.UserID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// この行の書き込みだけを取り消す
+			_, rbErr := tx.ExecContext(ctx, `rollback to savepoint import_row`)
+			if rbErr != nil {
+				return nil, rbErr
+			}
+			results = append(results, FailedImport(row.Line, row.Title, err))
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `release savepoint import_row`)
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.DryRun && result.Status == ImportCreated {
+			// ロールバックするのでidは確定しない
+			result.ID = 0
+		}
+		results = append(results, result)
+	}
+
+	if opts.DryRun {
+		return results, nil
+	}
+
+	return results, tx.Commit()
+}
+
+// すべてのgenreを名前から引けるようにする
+func genreIDsTx(ctx context.Context, tx *sql.Tx) (map[string]int, error) {
+	rows, err := tx.QueryContext(ctx, `select id, genre_name from genres`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var genres []Genre
+	for rows.Next() {
+		var g Genre
+		err := rows.Scan(&g.ID, &g.GenreName)
+		if err != nil {
+			return nil, err
+		}
+		genres = append(genres, g)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return genreIDsByName(genres), nil
+}
+
+// 1行を検証して、既存のmovieがあれば更新し、なければ追加する
+func (m *DBModel) importRowTx(ctx context.Context, tx *sql.Tx, row ImportRow, genreIDs map[string]int, userID int) (ImportResult, error) {
+	movie, err := row.movie(genreIDs)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	// idがなければtitleとyearで既存のmovieを探す
+	var version int
+	if movie.ID > 0 {
+		err = tx.QueryRowContext(ctx, `select version from movies where id = $1 and deleted_at is null` + m.dialect.rowLock("for update"), movie.ID).Scan(&version)
+		if err == sql.ErrNoRows {
+			return ImportResult{}, ErrImportMovieNotFound
+		}
+	} else {
+		query := `select id, version from movies
+					where lower(title) = lower($1) and year = $2 and deleted_at is null
+					order by id limit 1` + m.dialect.rowLock("for update")
+		err = tx.QueryRowContext(ctx, query, movie.Title, movie.Year).Scan(&movie.ID, &version)
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	if movie.ID == 0 {
+		id, err := m.insertMovieTx(ctx, tx, movie, userID)
+		if err != nil {
+			return ImportResult{}, err
+		}
+		return ImportResult{Line: row.Line, Status: ImportCreated, ID: id, Title: movie.Title}, nil
+	}
+
+	movie.Version = version
+	err = m.updateMovieTx(ctx, tx, movie)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	err = insertRevision(ctx, tx, movie.ID, RevisionUpdate, userID)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	return ImportResult{Line: row.Line, Status: ImportUpdated, ID: movie.ID, Title: movie.Title}, nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+func (m *MemoryModel) ImportMovies(rows []ImportRow, opts ImportOptions) ([]ImportResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var genres []Genre
+	for _, g := range m.genres {
+		genres = append(genres, g)
+	}
+	genreIDs := genreIDsByName(genres)
+
+	// dry runで追加する予定のmovie(同じファイルの後の行は更新になる)
+	planned := make(map[string]bool)
+
+	results := []ImportResult{}
+
+	for _, row := range rows {
+		movie, err := row.movie(genreIDs)
+		if err != nil {
+			results = append(results, FailedImport(row.Line, row.Title, err))
+			continue
+		}
+
+		if movie.ID > 0 {
+			current, ok := m.movies[movie.ID]
+			if !ok || current.DeletedAt != nil {
+				results = append(results, FailedImport(row.Line, row.Title, ErrImportMovieNotFound))
+				continue
+			}
+		} else {
+			movie.ID = m.findMovieByTitle(movie.Title, movie.Year)
+		}
+
+		key := fmt.Sprintf("%s|%d", strings.ToLower(movie.Title), movie.Year)
+
+		if movie.ID == 0 && !planned[key] {
+			id := 0
+			if opts.DryRun {
+				planned[key] = true
+			} else {
+				id, err = m.insertMovie(movie, opts.UserID)
+				if err != nil {
+					results = append(results, FailedImport(row.Line, row.Title, err))
+					continue
+				}
+			}
+			results = append(results, ImportResult{Line: row.Line, Status: ImportCreated, ID: id, Title: movie.Title})
+			continue
+		}
+
+		if !opts.DryRun {
+			movie.Version = m.movies[movie.ID].Version
+			err = m.updateMovie(movie)
+			if err != nil {
+				results = append(results, FailedImport(row.Line, row.Title, err))
+				continue
+			}
+			m.recordRevision(movie.ID, RevisionUpdate, opts.UserID)
+		}
+		results = append(results, ImportResult{Line: row.Line, Status: ImportUpdated, ID: movie.ID, Title: movie.Title})
+	}
+
+	return results, nil
+}
+
+// titleとyearが同じmovie(ゴミ箱のものを除く)のうちidが最小のものを返す(なければ0)
+func (m *MemoryModel) findMovieByTitle(title string, year int) int {
+	found := 0
+	for id, movie := range m.movies {
+		if movie.DeletedAt == nil && movie.Year == year && strings.EqualFold(movie.Title, title) {
+			if found == 0 || id < found {
+				found = id
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ImportBatchSize is the number of rows written in one transaction
+const ImportBatchSize = 100
+
+const (
+	// ImportCreated is a row that was inserted as a new movie
+	ImportCreated = "created"
+	// ImportUpdated is a row that replaced an existing movie
+	ImportUpdated = "updated"
+	// ImportFailed is a row that could not be parsed, validated or written
+	ImportFailed = "failed"
+)
+
+// ErrImportMovieNotFound is returned for a row whose id is not an existing movie
+var ErrImportMovieNotFound = errors.New("no movie with that id")
+
+// ImportRow is one movie read from an import file
+type ImportRow struct {
+	// 入力での行番号(エラーの報告に使う)
+	Line int
+	// 0の場合はtitleとyearが同じmovieを更新し、なければ追加する
+	ID int
+	Title string
+	Description string
+	// 2006-01-02の形式
+	ReleaseDate string
+	Runtime int
+	Rating int
+	MPAARating string
+	// genre名(大文字小文字は区別しない)。nilの場合は既存のmovieの紐づけを変更しない
+	Genres []string
+}
+
+// ImportOptions controls how rows are written
+type ImportOptions struct {
+	// trueの場合は書き込まずに結果だけを返す
+	DryRun bool
+	// 変更履歴に記録するユーザー
+	UserID int
+}
+
+// ImportResult is the outcome of one row
+type ImportResult struct {
+	Line int `json:"line"`
+	Status string `json:"status"`
+	// dry runで追加する行は0
+	ID int `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// FailedImport returns the result for a row that could not be imported
+func FailedImport(line int, title string, err error) ImportResult {
+	return ImportResult{Line: line, Status: ImportFailed, Title: title, Error: err.Error()}
+}
+
+// 小文字にしたgenre名からidを引けるようにする
+func genreIDsByName(genres []Genre) map[string]int {
+	ids := make(map[string]int)
+	for _, g := range genres {
+		ids[strings.ToLower(g.GenreName)] = g.ID
+	}
+	return ids
+}
+
+// 行を検証してmovieにする(genreは名前からidに変換する)
+func (row ImportRow) movie(genreIDs map[string]int) (Movie, error) {
+	var movie Movie
+
+	title := strings.TrimSpace(row.Title)
+	if title == "" {
+		return movie, fmt.Errorf("title must not be empty")
+	}
+	if len(title) > 512 {
+		return movie, fmt.Errorf("title must not be longer than 512 bytes")
+	}
+
+	releaseDate, err := time.Parse("2006-01-02", strings.TrimSpace(row.ReleaseDate))
+	if err != nil {
+		return movie, fmt.Errorf("release_date must be a date in the form 2006-01-02")
+	}
+
+	if row.Runtime < 0 {
+		return movie, fmt.Errorf("runtime must not be negative")
+	}
+	if row.Rating < 0 || row.Rating > 5 {
+		return movie, fmt.Errorf("rating must be between 0 and 5")
+	}
+
+	mpaa := strings.TrimSpace(row.MPAARating)
+	if mpaa != "" && !containsString(MPAARatings, mpaa) {
+		return movie, fmt.Errorf("mpaa_rating must be one of %s", strings.Join(MPAARatings, ", "))
+	}
+
+	if row.Genres != nil {
+		movie.GenreIDs = []int{}
+		for _, name := range row.Genres {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			id, ok := genreIDs[strings.ToLower(name)]
+			if !ok {
+				return movie, fmt.Errorf("unknown genre %q", name)
+			}
+			movie.GenreIDs = append(movie.GenreIDs, id)
+		}
+	}
+
+	now := time.Now()
+	movie.ID = row.ID
+	movie.Title = title
+	movie.Description = row.Description
+	movie.ReleaseDate = releaseDate
+	movie.Year = releaseDate.Year()
+	movie.Runtime = row.Runtime
+	movie.Rating = row.Rating
+	movie.MPAARating = mpaa
+	movie.CreatedAt = now
+	movie.UpdatedAt = now
+
+	return movie, nil
+}
@@ -0,0 +1,250 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ユーザーのリストのmovie(ゴミ箱のものを除く)をpositionの順に返すメソッド
+func (m *DBModel) ListEntries(userID int, list string) ([]*ListEntry, error) {
+	if !containsString(UserLists, list) {
+		return nil, ErrUnknownList
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select
+					l.id, l.user_id, l.list, l.movie_id, l.position, l.note, l.created_at, l.updated_at,
+					m.id, m.title, m.description, m.year, m.release_date, m.runtime, m.rating, m.mpaa_rating,
+					m.created_at, m.updated_at, m.version, m.review_count, m.review_score, coalesce(m.poster, '')
+				from
+					user_movie_lists l
+					join movies m on (m.id = l.movie_id)
+				where
+					l.user_id = $1 and l.list = $2 and m.deleted_at is null
+				order by
+					l.position, l.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, list)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*ListEntry{}
+	var movies []*Movie
+
+	for rows.Next() {
+		var e ListEntry
+		var movie Movie
+		err := rows.Scan(
+			&e.ID,
+			&e.UserID,
+			&e.List,
+			&e.MovieID,
+			&e.Position,
+			&e.Note,
+			&e.CreatedAt,
+			&e.UpdatedAt,
+			&movie.ID,
+			&movie.Title,
+			&movie.Description,
+			&movie.Year,
+			&movie.ReleaseDate,
+			&movie.Runtime,
+			&movie.Rating,
+			&movie.MPAARating,
+			&movie.CreatedAt,
+			&movie.UpdatedAt,
+			&movie.Version,
+			&movie.ReviewCount,
+			&movie.ReviewScore,
+			&movie.Poster,
+		)
+		if err != nil {
+			return nil, err
+		}
+		e.Movie = &movie
+		entries = append(entries, &e)
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	err = m.loadGenresBatch(ctx, movies)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// movieをリストの最後に追加する(すでにある場合はnoteだけ更新する)
+func (m *DBModel) SaveListEntry(entry ListEntry) (*ListEntry, error) {
+	err := entry.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err = m.DB.QueryRowContext(ctx, `select true from movies where id = $1 and deleted_at is null`, entry.MovieID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	// noteがnullなら、すでにあるentryのnoteを残す
+	stmt := `insert into user_movie_lists (user_id, list, movie_id, position, note, created_at, updated_at)
+						values ($1, $2, $3,
+							(select coalesce(max(position), 0) + 1 from user_movie_lists where user_id = $1 and list = $2),
+							coalesce($4, ''), $5, $5)
+						on conflict (user_id, list, movie_id) do update set note = coalesce($4, user_movie_lists.note), updated_at = excluded.updated_at
+						returning id, position, note, created_at, updated_at`
+
+	var note interface{} = entry.Note
+	if entry.KeepNote {
+		note = nil
+	}
+
+	saved := entry
+	saved.KeepNote = false
+	err = m.DB.QueryRowContext(ctx, stmt, entry.UserID, entry.List, entry.MovieID, note, time.Now()).Scan(
+		&saved.ID,
+		&saved.Position,
+		&saved.Note,
+		&saved.CreatedAt,
+		&saved.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &saved, nil
+}
+
+func (m *DBModel) RemoveListEntry(userID int, list string, movieID int) error {
+	if !containsString(UserLists, list) {
+		return ErrUnknownList
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `delete from user_movie_lists where user_id = $1 and list = $2 and movie_id = $3`
+
+	res, err := m.DB.ExecContext(ctx, stmt, userID, list, movieID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// リストをmovieIDsの順に並べ替える(ゴミ箱のものを除くリストのmovieをすべて含める)
+func (m *DBModel) ReorderList(userID int, list string, movieIDs []int) error {
+	if !containsString(UserLists, list) {
+		return ErrUnknownList
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `select l.movie_id from user_movie_lists l join movies m on (m.id = l.movie_id)
+						where l.user_id = $1 and l.list = $2 and m.deleted_at is null` + m.dialect.rowLock("for update of l")
+
+	rows, err := tx.QueryContext(ctx, query, userID, list)
+	if err != nil {
+		return err
+	}
+
+	var current []int
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		current = append(current, id)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	if !sameMovieSet(current, movieIDs) {
+		return ErrInvalidOrder
+	}
+
+	now := time.Now()
+	for i, id := range movieIDs {
+		stmt := `update user_movie_lists set position = $1, updated_at = $2 where user_id = $3 and list = $4 and movie_id = $5`
+		_, err = tx.ExecContext(ctx, stmt, i+1, now, userID, list, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// movieIDsのうちユーザーのリストにあるものを返すメソッド
+func (m *DBModel) ListedMovieIDs(userID int, list string, movieIDs []int) (map[int]bool, error) {
+	listed := make(map[int]bool)
+	if len(movieIDs) == 0 {
+		return listed, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{userID, list}
+	var placeholders []string
+	for _, id := range movieIDs {
+		args = append(args, id)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(`select movie_id from user_movie_lists where user_id = $1 and list = $2 and movie_id in (%s)`,
+		strings.Join(placeholders, ", "))
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		listed[id] = true
+	}
+
+	return listed, rows.Err()
+}
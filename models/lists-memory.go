@@ -0,0 +1,160 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// ユーザーのリストのエントリー(ゴミ箱のmovieを除く)をpositionの順に返す(ロックを取得してから呼ぶ)
+func (m *MemoryModel) visibleListEntries(userID int, list string) []ListEntry {
+	var entries []ListEntry
+	for _, e := range m.listEntries {
+		movie, ok := m.movies[e.MovieID]
+		if e.UserID == userID && e.List == list && ok && movie.DeletedAt == nil {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Position != entries[j].Position {
+			return entries[i].Position < entries[j].Position
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	return entries
+}
+
+func (m *MemoryModel) ListEntries(userID int, list string) ([]*ListEntry, error) {
+	if !containsString(UserLists, list) {
+		return nil, ErrUnknownList
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := []*ListEntry{}
+	for _, e := range m.visibleListEntries(userID, list) {
+		e := e
+		e.Movie = m.copyMovie(m.movies[e.MovieID])
+		entries = append(entries, &e)
+	}
+
+	return entries, nil
+}
+
+func (m *MemoryModel) SaveListEntry(entry ListEntry) (*ListEntry, error) {
+	err := entry.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[entry.MovieID]
+	if !ok || movie.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	now := time.Now()
+	maxPosition := 0
+	for id, e := range m.listEntries {
+		if e.UserID != entry.UserID || e.List != entry.List {
+			continue
+		}
+		// すでにある場合はnoteだけ更新する
+		if e.MovieID == entry.MovieID {
+			if !entry.KeepNote {
+				e.Note = entry.Note
+			}
+			e.UpdatedAt = now
+			m.listEntries[id] = e
+			return &e, nil
+		}
+		if e.Position > maxPosition {
+			maxPosition = e.Position
+		}
+	}
+
+	entry.KeepNote = false
+	entry.ID = m.nextListEntryID
+	entry.Position = maxPosition + 1
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	entry.Movie = nil
+	m.nextListEntryID++
+	m.listEntries[entry.ID] = entry
+
+	return &entry, nil
+}
+
+func (m *MemoryModel) RemoveListEntry(userID int, list string, movieID int) error {
+	if !containsString(UserLists, list) {
+		return ErrUnknownList
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, e := range m.listEntries {
+		if e.UserID == userID && e.List == list && e.MovieID == movieID {
+			delete(m.listEntries, id)
+			return nil
+		}
+	}
+
+	return sql.ErrNoRows
+}
+
+func (m *MemoryModel) ReorderList(userID int, list string, movieIDs []int) error {
+	if !containsString(UserLists, list) {
+		return ErrUnknownList
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := m.visibleListEntries(userID, list)
+
+	var current []int
+	byMovie := make(map[int]ListEntry)
+	for _, e := range entries {
+		current = append(current, e.MovieID)
+		byMovie[e.MovieID] = e
+	}
+
+	if !sameMovieSet(current, movieIDs) {
+		return ErrInvalidOrder
+	}
+
+	now := time.Now()
+	for i, id := range movieIDs {
+		e := byMovie[id]
+		e.Position = i + 1
+		e.UpdatedAt = now
+		m.listEntries[e.ID] = e
+	}
+
+	return nil
+}
+
+func (m *MemoryModel) ListedMovieIDs(userID int, list string, movieIDs []int) (map[int]bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wanted := make(map[int]bool)
+	for _, id := range movieIDs {
+		wanted[id] = true
+	}
+
+	listed := make(map[int]bool)
+	for _, e := range m.listEntries {
+		if e.UserID == userID && e.List == list && wanted[e.MovieID] {
+			listed[e.MovieID] = true
+		}
+	}
+
+	return listed, nil
+}
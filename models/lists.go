@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// ListWatchlist is the list of movies the user wants to watch later
+	ListWatchlist = "watchlist"
+	// ListFavorites is the list of the user's favorite movies
+	ListFavorites = "favorites"
+)
+
+// UserLists is the list of per-user movie lists
+var UserLists = []string{ListWatchlist, ListFavorites}
+
+var (
+	// ErrUnknownList is returned for a list name other than watchlist or favorites
+	ErrUnknownList = errors.New("unknown list (allowed: watchlist, favorites)")
+	// ErrInvalidOrder is returned when a reorder does not contain exactly the movies in the list
+	ErrInvalidOrder = errors.New("movie_ids must contain every movie in the list exactly once")
+)
+
+// ListEntry is one movie in a user's watchlist or favorites
+type ListEntry struct {
+	ID int `json:"id"`
+	UserID int `json:"-"`
+	List string `json:"list"`
+	MovieID int `json:"movie_id"`
+	// 小さいほど上に並べる
+	Position int `json:"position"`
+	Note string `json:"note"`
+	// trueなら、すでにリストにあるmovieのnoteを変更しない(リクエストでnoteを省略した場合)
+	KeepNote bool `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Movie *Movie `json:"movie,omitempty"`
+}
+
+// Validate checks the list name and note before the entry is saved
+func (e ListEntry) Validate() error {
+	if !containsString(UserLists, e.List) {
+		return ErrUnknownList
+	}
+	if len(e.Note) > 1000 {
+		return errors.New("note must not be longer than 1000 bytes")
+	}
+	return nil
+}
+
+// 並び替えのidがリストのmovieとちょうど一致するかを確認する
+func sameMovieSet(current []int, ordered []int) bool {
+	if len(current) != len(ordered) || len(uniqueInts(ordered)) != len(ordered) {
+		return false
+	}
+
+	inList := make(map[int]bool)
+	for _, id := range current {
+		inList[id] = true
+	}
+	for _, id := range ordered {
+		if !inList[id] {
+			return false
+		}
+	}
+
+	return true
+}
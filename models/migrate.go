@@ -0,0 +1,341 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// バイナリに埋め込むマイグレーション(NNNN_name.up.sql / NNNN_name.down.sql)
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// SQLite用に書き直したマイグレーション(versionと名前はPostgresのものと同じにする)
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrationFiles embed.FS
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name string
+	Up string
+	Down string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version int `json:"version"`
+	Name string `json:"name"`
+	Applied bool `json:"applied"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Migrator applies the embedded migrations and tracks them in schema_migrations
+type Migrator struct {
+	DB *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator with the migrations embedded in the binary
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{DB: db, migrations: migrations}, nil
+}
+
+// NewSQLiteMigrator returns a migrator with the SQLite versions of the embedded migrations
+func NewSQLiteMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(sqliteMigrationFiles, "migrations/sqlite")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{DB: db, migrations: migrations}, nil
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		// "0001_create_movies.up.sql" -> 1, "create_movies"
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", name)
+		}
+
+		body, err := fs.ReadFile(fsys, dir+"/"+name)
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = mg
+		}
+		if mg.Name != parts[1] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mg.Name, parts[1])
+		}
+
+		if direction == "up" {
+			mg.Up = string(body)
+		} else {
+			mg.Down = string(body)
+		}
+	}
+
+	var migrations []Migration
+	for _, mg := range byVersion {
+		if mg.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up file", mg.Version, mg.Name)
+		}
+		migrations = append(migrations, *mg)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Latest returns the version the binary expects
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	stmt := `create table if not exists schema_migrations (
+		version integer primary key,
+		applied_at timestamp not null default current_timestamp
+	)`
+
+	_, err := m.DB.ExecContext(ctx, stmt)
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	err := m.ensureTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `select version, applied_at from schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// Version returns the highest applied migration version (0 if none)
+func (m *Migrator) Version() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+
+	return version, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var status []MigrationStatus
+	for _, mg := range m.migrations {
+		appliedAt, ok := applied[mg.Version]
+		status = append(status, MigrationStatus{
+			Version: mg.Version,
+			Name: mg.Name,
+			Applied: ok,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return status, nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up() error {
+	return m.Goto(m.Latest())
+}
+
+// Down reverts the most recently applied migration
+func (m *Migrator) Down() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+
+	target := 0
+	for _, mg := range m.migrations {
+		if mg.Version < version {
+			target = mg.Version
+		}
+	}
+
+	return m.Goto(target)
+}
+
+// Goto migrates up or down until the schema is at the given version
+func (m *Migrator) Goto(version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("unknown migration version %d (latest is %d)", version, m.Latest())
+	}
+
+	ctx := context.Background()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 古いものから順に適用する
+	for _, mg := range m.migrations {
+		if mg.Version > version {
+			break
+		}
+		if _, ok := applied[mg.Version]; ok {
+			continue
+		}
+		err := m.run(ctx, mg, true)
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) up: %w", mg.Version, mg.Name, err)
+		}
+	}
+
+	// 新しいものから順に戻す
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mg := m.migrations[i]
+		if mg.Version <= version {
+			break
+		}
+		if _, ok := applied[mg.Version]; !ok {
+			continue
+		}
+		if mg.Down == "" {
+			return fmt.Errorf("migration %d (%s) cannot be reverted", mg.Version, mg.Name)
+		}
+		err := m.run(ctx, mg, false)
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) down: %w", mg.Version, mg.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// マイグレーションとschema_migrationsの更新を1つのトランザクションで実行する
+func (m *Migrator) run(ctx context.Context, mg Migration, up bool) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if up {
+		_, err = tx.ExecContext(ctx, mg.Up)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `insert into schema_migrations (version, applied_at) values ($1, $2)`, mg.Version, time.Now())
+	} else {
+		_, err = tx.ExecContext(ctx, mg.Down)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `delete from schema_migrations where version = $1`, mg.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CheckSchema returns an error when the database is behind the binary
+func (m *Migrator) CheckSchema() error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, st := range status {
+		if !st.Applied {
+			pending = append(pending, fmt.Sprintf("%04d_%s", st.Version, st.Name))
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is behind this binary (pending: %s); run the migrate up command", strings.Join(pending, ", "))
+	}
+
+	return nil
+}
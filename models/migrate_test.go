@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, mg := range migrations {
+		if mg.Version != i+1 {
+			t.Errorf("migration %s has version %d, want %d (versions must be contiguous)", mg.Name, mg.Version, i+1)
+		}
+		if mg.Down == "" {
+			t.Errorf("migration %d (%s) has no down file", mg.Version, mg.Name)
+		}
+	}
+}
+
+func TestSQLiteMigrationsMatch(t *testing.T) {
+	postgres, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := loadMigrations(sqliteMigrationFiles, "migrations/sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("got %d SQLite migrations, want %d", len(sqlite), len(postgres))
+	}
+	for i, mg := range sqlite {
+		if mg.Version != postgres[i].Version || mg.Name != postgres[i].Name {
+			t.Errorf("SQLite migration %d (%s) does not match %d (%s)", mg.Version, mg.Name, postgres[i].Version, postgres[i].Name)
+		}
+		if mg.Down == "" {
+			t.Errorf("SQLite migration %d (%s) has no down file", mg.Version, mg.Name)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+create table if not exists genres (
+	id serial primary key,
+	genre_name varchar(255) not null,
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now()
+);
+
+create table if not exists movies (
+	id serial primary key,
+	title varchar(512) not null,
+	description text not null default '',
+	year integer not null default 0,
+	release_date date,
+	runtime integer not null default 0,
+	rating integer not null default 0,
+	mpaa_rating varchar(10) not null default '',
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now()
+);
+
+create table if not exists movies_genres (
+	id serial primary key,
+	movie_id integer not null references movies (id) on delete cascade,
+	genre_id integer not null references genres (id) on delete cascade,
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now()
+);
+
+create index if not exists movies_genres_movie_id_idx on movies_genres (movie_id);
+create index if not exists movies_genres_genre_id_idx on movies_genres (genre_id);
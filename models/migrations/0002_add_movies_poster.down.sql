@@ -0,0 +1 @@
+alter table movies drop column if exists poster;
@@ -0,0 +1 @@
+alter table movies add column if not exists poster varchar(255);
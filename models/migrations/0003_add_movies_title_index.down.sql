@@ -0,0 +1 @@
+drop index if exists movies_title_id_idx;
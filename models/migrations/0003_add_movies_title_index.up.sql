@@ -0,0 +1 @@
+create index if not exists movies_title_id_idx on movies (title, id);
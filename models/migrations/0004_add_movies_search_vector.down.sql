@@ -0,0 +1,2 @@
+drop index if exists movies_search_vector_idx;
+alter table movies drop column if exists search_vector;
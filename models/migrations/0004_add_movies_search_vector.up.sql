@@ -0,0 +1,7 @@
+alter table movies add column if not exists search_vector tsvector;
+
+update movies set search_vector =
+	setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(description, '')), 'B');
+
+create index if not exists movies_search_vector_idx on movies using gin (search_vector);
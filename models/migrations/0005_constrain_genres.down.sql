@@ -0,0 +1,7 @@
+alter table movies_genres drop constraint if exists movies_genres_movie_id_genre_id_key;
+
+alter table movies_genres drop constraint if exists movies_genres_genre_id_fkey;
+alter table movies_genres add constraint movies_genres_genre_id_fkey
+	foreign key (genre_id) references genres (id) on delete cascade;
+
+drop index if exists genres_genre_name_key;
@@ -0,0 +1,25 @@
+-- 重複したgenre名は同じ名前の最小のidにまとめる
+update movies_genres mg set genre_id = k.keep_id
+from (select id, min(id) over (partition by lower(genre_name)) as keep_id from genres) k
+where mg.genre_id = k.id and k.id <> k.keep_id;
+
+delete from genres g using genres g2
+where lower(g.genre_name) = lower(g2.genre_name) and g.id > g2.id;
+
+create unique index if not exists genres_genre_name_key on genres (lower(genre_name));
+
+-- 存在しないgenreやmovieを指す行と重複した行を削除する
+delete from movies_genres where genre_id not in (select id from genres);
+delete from movies_genres where movie_id not in (select id from movies);
+delete from movies_genres mg using movies_genres mg2
+where mg.movie_id = mg2.movie_id and mg.genre_id = mg2.genre_id and mg.id > mg2.id;
+
+alter table movies_genres drop constraint if exists movies_genres_genre_id_fkey;
+alter table movies_genres add constraint movies_genres_genre_id_fkey
+	foreign key (genre_id) references genres (id) on delete restrict;
+
+alter table movies_genres drop constraint if exists movies_genres_movie_id_fkey;
+alter table movies_genres add constraint movies_genres_movie_id_fkey
+	foreign key (movie_id) references movies (id) on delete cascade;
+
+alter table movies_genres add constraint movies_genres_movie_id_genre_id_key unique (movie_id, genre_id);
@@ -0,0 +1,2 @@
+drop table if exists movie_revisions;
+drop function if exists movie_revisions_immutable();
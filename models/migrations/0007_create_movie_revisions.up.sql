@@ -0,0 +1,21 @@
+create table if not exists movie_revisions (
+	id serial primary key,
+	movie_id integer not null,
+	action varchar(10) not null,
+	snapshot jsonb not null,
+	user_id integer not null,
+	created_at timestamp not null default now()
+);
+
+create index if not exists movie_revisions_movie_id_idx on movie_revisions (movie_id, id);
+
+-- 履歴は書き換えられないようにする
+create or replace function movie_revisions_immutable() returns trigger as $$
+begin
+	raise exception 'movie_revisions rows are immutable';
+end;
+$$ language plpgsql;
+
+create trigger movie_revisions_immutable
+	before update or delete on movie_revisions
+	for each row execute procedure movie_revisions_immutable();
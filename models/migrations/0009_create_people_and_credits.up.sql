@@ -0,0 +1,24 @@
+create table if not exists people (
+	id serial primary key,
+	name varchar(255) not null,
+	biography text not null default '',
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now()
+);
+
+create index if not exists people_name_idx on people (lower(name));
+
+create table if not exists movie_credits (
+	id serial primary key,
+	movie_id integer not null references movies (id) on delete cascade,
+	person_id integer not null references people (id) on delete restrict,
+	role varchar(20) not null,
+	character_name varchar(255) not null default '',
+	billing_order integer not null default 0,
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now(),
+	unique (movie_id, person_id, role)
+);
+
+create index if not exists movie_credits_movie_id_idx on movie_credits (movie_id, billing_order);
+create index if not exists movie_credits_person_id_idx on movie_credits (person_id);
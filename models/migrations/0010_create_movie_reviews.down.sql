@@ -0,0 +1,4 @@
+alter table movies drop column if exists review_score;
+alter table movies drop column if exists review_count;
+drop table if exists movie_review_votes;
+drop table if exists movie_reviews;
@@ -0,0 +1,24 @@
+create table if not exists movie_reviews (
+	id serial primary key,
+	movie_id integer not null references movies (id) on delete cascade,
+	user_id integer not null,
+	score integer not null check (score between 1 and 5),
+	body text not null default '',
+	helpful_count integer not null default 0,
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now(),
+	unique (movie_id, user_id)
+);
+
+create index if not exists movie_reviews_helpful_idx on movie_reviews (movie_id, helpful_count desc, id desc);
+
+create table if not exists movie_review_votes (
+	review_id integer not null references movie_reviews (id) on delete cascade,
+	user_id integer not null,
+	created_at timestamp not null default now(),
+	primary key (review_id, user_id)
+);
+
+-- 一覧で毎回集計しないように件数と平均をmoviesに持つ
+alter table movies add column if not exists review_count integer not null default 0;
+alter table movies add column if not exists review_score numeric(3, 2) not null default 0;
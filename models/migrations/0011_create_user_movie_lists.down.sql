@@ -0,0 +1 @@
+drop table if exists user_movie_lists;
@@ -0,0 +1,13 @@
+create table if not exists user_movie_lists (
+	id serial primary key,
+	user_id integer not null,
+	list varchar(20) not null,
+	movie_id integer not null references movies (id) on delete cascade,
+	position integer not null,
+	note text not null default '',
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now(),
+	unique (user_id, list, movie_id)
+);
+
+create index if not exists user_movie_lists_user_idx on user_movie_lists (user_id, list, position);
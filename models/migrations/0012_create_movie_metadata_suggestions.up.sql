@@ -0,0 +1,17 @@
+create table if not exists movie_metadata_suggestions (
+	id serial primary key,
+	movie_id integer not null references movies (id) on delete cascade,
+	provider varchar(50) not null,
+	external_id varchar(100) not null default '',
+	title varchar(512) not null default '',
+	description text not null default '',
+	release_date date,
+	poster_ref varchar(512) not null default '',
+	status varchar(20) not null default 'pending',
+	accepted_fields varchar(100) not null default '',
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now()
+);
+
+create index if not exists movie_metadata_suggestions_status_idx on movie_metadata_suggestions (status, id);
+create index if not exists movie_metadata_suggestions_movie_idx on movie_metadata_suggestions (movie_id);
@@ -0,0 +1,2 @@
+drop table if exists genre_translations;
+drop table if exists movie_translations;
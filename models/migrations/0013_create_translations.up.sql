@@ -0,0 +1,20 @@
+create table if not exists movie_translations (
+	id serial primary key,
+	movie_id integer not null references movies (id) on delete cascade,
+	locale varchar(10) not null,
+	title varchar(512) not null,
+	description text not null default '',
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now(),
+	unique (movie_id, locale)
+);
+
+create table if not exists genre_translations (
+	id serial primary key,
+	genre_id integer not null references genres (id) on delete cascade,
+	locale varchar(10) not null,
+	genre_name varchar(255) not null,
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now(),
+	unique (genre_id, locale)
+);
@@ -0,0 +1,2 @@
+drop table if exists collection_movies;
+drop table if exists collections;
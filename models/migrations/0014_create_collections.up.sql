@@ -0,0 +1,22 @@
+create table if not exists collections (
+	id serial primary key,
+	name varchar(255) not null,
+	description text not null default '',
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now()
+);
+
+create unique index if not exists collections_name_idx on collections (lower(name));
+
+-- movieは1つのcollectionにだけ入れられる
+create table if not exists collection_movies (
+	id serial primary key,
+	collection_id integer not null references collections (id) on delete cascade,
+	movie_id integer not null references movies (id) on delete cascade,
+	position integer not null,
+	created_at timestamp not null default now(),
+	updated_at timestamp not null default now(),
+	unique (movie_id)
+);
+
+create index if not exists collection_movies_collection_idx on collection_movies (collection_id, position);
@@ -0,0 +1,2 @@
+drop table if exists movie_certifications;
+drop table if exists movie_releases;
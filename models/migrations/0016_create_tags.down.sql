@@ -0,0 +1,3 @@
+drop table if exists movie_tags;
+drop table if exists tag_synonyms;
+drop table if exists tags;
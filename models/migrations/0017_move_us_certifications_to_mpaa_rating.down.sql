@@ -0,0 +1,2 @@
+insert into movie_certifications (movie_id, country, rating, created_at, updated_at)
+	select id, 'US', mpaa_rating, now(), now() from movies where mpaa_rating <> '';
@@ -0,0 +1,6 @@
+-- USの年齢制限はmovies.mpaa_ratingだけに持つ(movie_certificationsと食い違わないようにする)
+update movies set mpaa_rating = c.rating
+	from movie_certifications c
+	where c.movie_id = movies.id and c.country = 'US';
+
+delete from movie_certifications where country = 'US';
@@ -0,0 +1 @@
+alter table movies add column poster varchar(255);
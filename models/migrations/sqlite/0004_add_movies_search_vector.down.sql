@@ -0,0 +1,5 @@
+drop trigger if exists movies_fts_update;
+drop trigger if exists movies_fts_delete;
+drop trigger if exists movies_fts_insert;
+drop table if exists movies_fts;
+alter table movies drop column search_vector;
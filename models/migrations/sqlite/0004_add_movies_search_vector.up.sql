@@ -0,0 +1,23 @@
+-- 全文検索はFTS5のmovies_ftsで行う(search_vectorはPostgresと同じ列にするためだけのもので、常にnull)
+alter table movies add column search_vector text;
+
+create virtual table if not exists movies_fts using fts5(
+	title, description,
+	content = 'movies', content_rowid = 'id', tokenize = 'porter unicode61'
+);
+
+insert into movies_fts (movies_fts) values ('rebuild');
+
+-- moviesの変更をmovies_ftsに反映する
+create trigger if not exists movies_fts_insert after insert on movies begin
+	insert into movies_fts (rowid, title, description) values (new.id, new.title, new.description);
+end;
+
+create trigger if not exists movies_fts_delete after delete on movies begin
+	insert into movies_fts (movies_fts, rowid, title, description) values ('delete', old.id, old.title, old.description);
+end;
+
+create trigger if not exists movies_fts_update after update of title, description on movies begin
+	insert into movies_fts (movies_fts, rowid, title, description) values ('delete', old.id, old.title, old.description);
+	insert into movies_fts (rowid, title, description) values (new.id, new.title, new.description);
+end;
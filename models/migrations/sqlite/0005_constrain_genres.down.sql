@@ -0,0 +1,18 @@
+create table movies_genres_old (
+	id integer primary key autoincrement,
+	movie_id integer not null references movies (id) on delete cascade,
+	genre_id integer not null references genres (id) on delete cascade,
+	created_at timestamp not null default current_timestamp,
+	updated_at timestamp not null default current_timestamp
+);
+
+insert into movies_genres_old (id, movie_id, genre_id, created_at, updated_at)
+select id, movie_id, genre_id, created_at, updated_at from movies_genres;
+
+drop table movies_genres;
+alter table movies_genres_old rename to movies_genres;
+
+create index if not exists movies_genres_movie_id_idx on movies_genres (movie_id);
+create index if not exists movies_genres_genre_id_idx on movies_genres (genre_id);
+
+drop index if exists genres_genre_name_key;
@@ -0,0 +1,31 @@
+-- 重複したgenre名は同じ名前の最小のidにまとめる
+update movies_genres set genre_id = k.keep_id
+from (select id, min(id) over (partition by lower(genre_name)) as keep_id from genres) k
+where movies_genres.genre_id = k.id and k.id <> k.keep_id;
+
+delete from genres
+where exists (select 1 from genres g2 where lower(g2.genre_name) = lower(genres.genre_name) and g2.id < genres.id);
+
+create unique index if not exists genres_genre_name_key on genres (lower(genre_name));
+
+-- SQLiteは制約を変更できないので、movies_genresを作り直す
+create table movies_genres_new (
+	id integer primary key autoincrement,
+	movie_id integer not null references movies (id) on delete cascade,
+	genre_id integer not null references genres (id) on delete restrict,
+	created_at timestamp not null default current_timestamp,
+	updated_at timestamp not null default current_timestamp,
+	unique (movie_id, genre_id)
+);
+
+-- 存在しないgenreやmovieを指す行と重複した行は移さない
+insert into movies_genres_new (id, movie_id, genre_id, created_at, updated_at)
+select id, movie_id, genre_id, created_at, updated_at from movies_genres mg
+where genre_id in (select id from genres) and movie_id in (select id from movies)
+	and not exists (select 1 from movies_genres mg2 where mg2.movie_id = mg.movie_id and mg2.genre_id = mg.genre_id and mg2.id < mg.id);
+
+drop table movies_genres;
+alter table movies_genres_new rename to movies_genres;
+
+create index if not exists movies_genres_movie_id_idx on movies_genres (movie_id);
+create index if not exists movies_genres_genre_id_idx on movies_genres (genre_id);
@@ -0,0 +1,19 @@
+create table if not exists movie_revisions (
+	id integer primary key autoincrement,
+	movie_id integer not null,
+	action varchar(10) not null,
+	snapshot text not null,
+	user_id integer not null,
+	created_at timestamp not null default current_timestamp
+);
+
+create index if not exists movie_revisions_movie_id_idx on movie_revisions (movie_id, id);
+
+-- 履歴は書き換えられないようにする
+create trigger if not exists movie_revisions_immutable_update before update on movie_revisions begin
+	select raise(abort, 'movie_revisions rows are immutable');
+end;
+
+create trigger if not exists movie_revisions_immutable_delete before delete on movie_revisions begin
+	select raise(abort, 'movie_revisions rows are immutable');
+end;
@@ -0,0 +1 @@
+alter table movies add column version integer not null default 1;
@@ -0,0 +1,2 @@
+drop table if exists movie_credits;
+drop table if exists people;
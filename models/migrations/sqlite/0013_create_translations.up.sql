@@ -0,0 +1,20 @@
+create table if not exists movie_translations (
+	id integer primary key autoincrement,
+	movie_id integer not null references movies (id) on delete cascade,
+	locale varchar(10) not null,
+	title varchar(512) not null,
+	description text not null default '',
+	created_at timestamp not null default current_timestamp,
+	updated_at timestamp not null default current_timestamp,
+	unique (movie_id, locale)
+);
+
+create table if not exists genre_translations (
+	id integer primary key autoincrement,
+	genre_id integer not null references genres (id) on delete cascade,
+	locale varchar(10) not null,
+	genre_name varchar(255) not null,
+	created_at timestamp not null default current_timestamp,
+	updated_at timestamp not null default current_timestamp,
+	unique (genre_id, locale)
+);
@@ -0,0 +1,22 @@
+-- 国ごとの公開日(劇場・配信・パッケージ)
+create table if not exists movie_releases (
+	id integer primary key autoincrement,
+	movie_id integer not null references movies (id) on delete cascade,
+	country char(2) not null,
+	release_type varchar(20) not null check (release_type in ('theatrical', 'digital', 'physical')),
+	release_date date not null,
+	created_at timestamp not null default current_timestamp,
+	updated_at timestamp not null default current_timestamp,
+	unique (movie_id, country, release_type)
+);
+
+-- 国ごとの年齢制限(MPAA・映倫・BBFC・FSK)
+create table if not exists movie_certifications (
+	id integer primary key autoincrement,
+	movie_id integer not null references movies (id) on delete cascade,
+	country char(2) not null,
+	rating varchar(20) not null,
+	created_at timestamp not null default current_timestamp,
+	updated_at timestamp not null default current_timestamp,
+	unique (movie_id, country)
+);
@@ -0,0 +1,28 @@
+-- 編集者が自由につけるタグ(slugは名前を小文字にして記号を-にしたもの)
+create table if not exists tags (
+	id integer primary key autoincrement,
+	name varchar(100) not null,
+	slug varchar(100) not null unique,
+	created_at timestamp not null default current_timestamp,
+	updated_at timestamp not null default current_timestamp
+);
+
+-- 同じ意味のタグのslug(統合したタグのslugも残してリンクが切れないようにする)
+create table if not exists tag_synonyms (
+	id integer primary key autoincrement,
+	tag_id integer not null references tags (id) on delete cascade,
+	slug varchar(100) not null unique,
+	created_at timestamp not null default current_timestamp,
+	updated_at timestamp not null default current_timestamp
+);
+
+create table if not exists movie_tags (
+	id integer primary key autoincrement,
+	movie_id integer not null references movies (id) on delete cascade,
+	tag_id integer not null references tags (id) on delete cascade,
+	created_at timestamp not null default current_timestamp,
+	updated_at timestamp not null default current_timestamp,
+	unique (movie_id, tag_id)
+);
+
+create index if not exists movie_tags_tag_idx on movie_tags (tag_id);
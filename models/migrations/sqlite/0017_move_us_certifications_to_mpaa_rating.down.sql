@@ -0,0 +1,2 @@
+insert into movie_certifications (movie_id, country, rating, created_at, updated_at)
+	select id, 'US', mpaa_rating, current_timestamp, current_timestamp from movies where mpaa_rating <> '';
@@ -0,0 +1,5 @@
+-- USの年齢制限はmovies.mpaa_ratingだけに持つ(movie_certificationsと食い違わないようにする)
+update movies set mpaa_rating = (select c.rating from movie_certifications c where c.movie_id = movies.id and c.country = 'US')
+	where exists (select 1 from movie_certifications c where c.movie_id = movies.id and c.country = 'US');
+
+delete from movie_certifications where country = 'US';
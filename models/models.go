@@ -1,38 +1,157 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"time"
 )
 
+// MovieStore is the interface implemented by every storage backend
+type MovieStore interface {
+	Get(id int) (*Movie, error)
+	All(genre ...int) ([]*Movie, error)
+	GetMany(ids []int) ([]*Movie, error)
+	List(filter MovieFilter) (*MoviePage, error)
+	Search(filter SearchFilter) (*SearchPage, error)
+	GenresAll() ([]*Genre, error)
+	InsertGenre(genre Genre) (int, error)
+	UpdateGenre(genre Genre) error
+	DeleteGenre(id int, reassignTo int) error
+	InsertMovie(movie Movie, userID int) (int, error)
+	UpdateMovie(movie Movie, userID int) error
+	DeleteMovie(id int, userID int) error
+	SetPoster(movieID int, key string, userID int) (string, error)
+	Trash() ([]*Movie, error)
+	RestoreMovie(id int, userID int) error
+	PurgeDeleted(before time.Time) (int, []string, error)
+	Revisions(movieID int) ([]*Revision, error)
+	GetRevision(id int) (*Revision, error)
+	RevertMovie(movieID int, revisionID int, userID int) error
+	People() ([]*Person, error)
+	GetPerson(id int) (*Person, error)
+	InsertPerson(person Person) (int, error)
+	UpdatePerson(person Person) error
+	DeletePerson(id int) error
+	Credits(movieID int) ([]*Credit, error)
+	ReplaceCredits(movieID int, credits []Credit) error
+	Filmography(personID int) ([]*FilmographyEntry, error)
+	Reviews(movieID int, filter ReviewFilter) (*ReviewPage, error)
+	GetReview(id int) (*Review, error)
+	InsertReview(review Review) (int, error)
+	UpdateReview(review Review) error
+	DeleteReview(id int, userID int) error
+	VoteReview(id int, userID int, helpful bool) error
+	ListEntries(userID int, list string) ([]*ListEntry, error)
+	SaveListEntry(entry ListEntry) (*ListEntry, error)
+	RemoveListEntry(userID int, list string, movieID int) error
+	ReorderList(userID int, list string, movieIDs []int) error
+	ListedMovieIDs(userID int, list string, movieIDs []int) (map[int]bool, error)
+	Suggestions(status string) ([]*MetadataSuggestion, error)
+	GetSuggestion(id int) (*MetadataSuggestion, error)
+	InsertSuggestion(suggestion MetadataSuggestion) (int, error)
+	ResolveSuggestion(id int, status string, fields []string) error
+	ClaimSuggestion(id int) (*MetadataSuggestion, error)
+	ReleaseSuggestion(id int) error
+	AcceptSuggestion(id int, fields []string, posterKey string, userID int) (string, error)
+	ImportMovies(rows []ImportRow, opts ImportOptions) ([]ImportResult, error)
+	ExportMovies(ctx context.Context, filter MovieFilter, fn func(*Movie) error) error
+	MovieTranslations(movieID int) ([]*MovieTranslation, error)
+	SaveMovieTranslation(translation MovieTranslation) (*MovieTranslation, error)
+	DeleteMovieTranslation(movieID int, locale string) error
+	GenreTranslations(genreID int) ([]*GenreTranslation, error)
+	SaveGenreTranslation(translation GenreTranslation) (*GenreTranslation, error)
+	DeleteGenreTranslation(genreID int, locale string) error
+	LocalizeMovies(movies []*Movie, locales []string) error
+	LocalizeGenres(genres []*Genre, locales []string) error
+	Collections() ([]*Collection, error)
+	GetCollection(id int) (*Collection, error)
+	InsertCollection(collection Collection) (int, error)
+	UpdateCollection(collection Collection) error
+	DeleteCollection(id int) error
+	SetCollectionMovies(id int, movieIDs []int) error
+	LoadCollections(movies []*Movie) error
+	Releases(movieID int) ([]*MovieRelease, []*MovieCertification, error)
+	ReplaceReleases(movieID int, releases []MovieRelease, certifications []MovieCertification) error
+	ApplyRegion(movies []*Movie, region string) error
+	TagCloud(limit int) ([]*Tag, error)
+	GetTag(slug string) (*Tag, error)
+	AddTagSynonym(id int, name string) error
+	MergeTags(id int, into int) error
+	LoadTags(movies []*Movie) error
+	MovieFeatures() ([]*MovieFeatures, error)
+	Primary() MovieStore
+}
+
 // Models is the wrapper for database
 type Models struct {
-	DB DBModel
+	DB MovieStore
 }
 
 // NewModels returns models with db pool
 func NewModels(db *sql.DB) Models {
 	return Models{
-		DB: DBModel{DB: db},
+		DB: &DBModel{DB: db},
 		// DBModel{db}
 	}
 }
 
+// NewModelsWithReplicas returns models that send reads to the replicas and writes to db
+func NewModelsWithReplicas(db *sql.DB, replicas *ReplicaPool) Models {
+	return Models{
+		DB: &DBModel{DB: db, Replicas: replicas},
+	}
+}
+
+// NewMemoryModels returns models backed by an in-memory store
+func NewMemoryModels(store *MemoryModel) Models {
+	return Models{
+		DB: store,
+	}
+}
+
 type Movie struct {
 	ID int `json:"id"`
 	Title string `json:"title"`
 	Description string `json:"description"`
+	// titleとdescriptionを翻訳したときのlocale(元の値の場合は空)
+	Locale string `json:"locale,omitempty"`
 	Year int `json:"year"`
 	ReleaseDate time.Time `json:"release_date"`
 	Runtime int `json:"runtime"`
 	Rating int `json:"rating"`
 	MPAARating string `json:"mpaa_rating"`
+	// ユーザーのレビューの件数と平均スコア(レビューを書き込むたびに更新する)
+	ReviewCount int `json:"review_count"`
+	ReviewScore float64 `json:"review_score"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	MovieGenre map[int]string `json:"genres"`
-	// Poster string `json:"poster"`
+	// 書き込み時はnilならgenreの紐づけを変更しない
+	GenreIDs []int `json:"genre_ids"`
+	// 編集者がつけたタグ(ハンドラーでセットする)
+	Tags []*Tag `json:"tags,omitempty"`
+	// 書き込み時のタグ名(nilならタグの紐づけを変更しない)
+	TagNames []string `json:"-"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// 更新のたびに1ずつ増える(更新時は読み込んだときの値を渡す)
+	Version int `json:"version"`
+	// サインインしているユーザーのwatchlistにあるか(ハンドラーでセットする)
+	InWatchlist bool `json:"in_watchlist"`
+	// ポスター画像のストレージのキー(URLはハンドラーでposter_urlにセットする)
+	Poster string `json:"-"`
+	PosterURL string `json:"poster_url,omitempty"`
+	// regionを指定したときの国(その国の公開日か年齢制限があった場合のみ)とrelease_dateの種類・年齢制限
+	Region string `json:"region,omitempty"`
+	ReleaseType string `json:"release_type,omitempty"`
+	Certification *MovieCertification `json:"certification,omitempty"`
+	// 所属するcollectionと前後のmovie(ハンドラーでセットする)
+	Collection *MovieCollection `json:"collection,omitempty"`
 }
 
+// ErrVersionConflict is returned when a movie was changed after the caller read it
+var ErrVersionConflict = errors.New("movie has been changed by someone else")
+
 type Genre struct {
 	ID int `json:"id"`
 	GenreName string `json:"genre_name"`
@@ -4,11 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
 type DBModel struct {
+	// primary(書き込みとreplicaを使わない読み込み)
 	DB *sql.DB
+	// Get・GetMany・All・List・GenresAll・Searchを振り分けるreplica(設定しない場合はnil)
+	Replicas *ReplicaPool
+	// SQLの方言(ゼロ値はPostgres)
+	dialect dialect
 }
 
 // 指定idのmovieかerrorを返すメソッド(DBModelのポインタレシーバ)
@@ -16,17 +22,15 @@ func (m *DBModel) Get(id int) (*Movie, error) {
 	// 3sでタイムアウトする
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
+	ctx = m.reader(ctx)
 
 	// 指定したIDのmoviesを取得するクエリ
 	query := `select id, title, description, year, release_date, runtime, rating, mpaa_rating,
-							created_at, updated_at from movies where id = $1
+							created_at, updated_at, version, review_count, review_score, coalesce(poster, '') from movies where id = $1 and deleted_at is null
 	`
-	// query := `select id, title, description, year, release_date, runtime, rating, mpaa_rating,
-	// 						created_at, updated_at, coalesce(poster, '') from movies where id = $1
-	// `
 
 	// 指定したidのmoviesを取得する(1行)
-	row := m.DB.QueryRowContext(ctx, query, id)
+	row := m.conn(ctx).QueryRowContext(ctx, query, id)
 
 	var movie Movie
 
@@ -42,14 +46,27 @@ func (m *DBModel) Get(id int) (*Movie, error) {
 		&movie.MPAARating,
 		&movie.CreatedAt,
 		&movie.UpdatedAt,
-		// &movie.Poster,
+		&movie.Version,
+		&movie.ReviewCount,
+		&movie.ReviewScore,
+		&movie.Poster,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	// 指定したmovie_idのgenresを取得するクエリ
-	query = `select
+	// 指定したmovie_idのgenresを取得する
+	err = m.loadGenres(ctx, &movie)
+	if err != nil {
+		return nil, err
+	}
+
+	return &movie, nil
+}
+
+// movieのgenres(MovieGenreとGenreIDs)を読み込む
+func (m *DBModel) loadGenres(ctx context.Context, movie *Movie) error {
+	query := `select
 						mg.id, mg.movie_id, mg.genre_id, g.genre_name
 					from
 						movies_genres mg
@@ -59,10 +76,14 @@ func (m *DBModel) Get(id int) (*Movie, error) {
 	`
 
 	// 指定したmovie_idのgenresを取得する(複数行)
-	rows, _ := m.DB.QueryContext(ctx, query, id)
+	rows, err := m.conn(ctx).QueryContext(ctx, query, movie.ID)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 
 	genres := make(map[int]string)
+	genreIDs := []int{}
 	for rows.Next() {
 		var mg MovieGenre
 		err := rows.Scan(
@@ -72,14 +93,83 @@ func (m *DBModel) Get(id int) (*Movie, error) {
 			&mg.Genre.GenreName,
 		)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		genres[mg.ID] = mg.Genre.GenreName
+		genreIDs = append(genreIDs, mg.GenreID)
 	}
 
 	movie.MovieGenre = genres
+	movie.GenreIDs = genreIDs
 
-	return &movie, nil
+	return rows.Err()
+}
+
+// 1回のクエリで取得するmovieの数
+const genreBatchSize = 500
+
+// 複数のmovieのgenresをまとめて読み込む(genreBatchSize件ごとに1回のクエリ)
+func (m *DBModel) loadGenresBatch(ctx context.Context, movies []*Movie) error {
+	byID := make(map[int]*Movie)
+	for _, movie := range movies {
+		movie.MovieGenre = make(map[int]string)
+		movie.GenreIDs = []int{}
+		byID[movie.ID] = movie
+	}
+
+	for start := 0; start < len(movies); start += genreBatchSize {
+		end := start + genreBatchSize
+		if end > len(movies) {
+			end = len(movies)
+		}
+
+		var args []interface{}
+		var placeholders []string
+		for _, movie := range movies[start:end] {
+			args = append(args, movie.ID)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+
+		query := fmt.Sprintf(`select
+				mg.id, mg.movie_id, mg.genre_id, g.genre_name
+			from
+				movies_genres mg
+				left join genres g on (g.id = mg.genre_id)
+			where
+				mg.movie_id in (%s)
+			order by
+				mg.movie_id, mg.genre_id`, strings.Join(placeholders, ", "))
+
+		rows, err := m.conn(ctx).QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var mg MovieGenre
+			err := rows.Scan(
+				&mg.ID,
+				&mg.MovieID,
+				&mg.GenreID,
+				&mg.Genre.GenreName,
+			)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if movie, ok := byID[mg.MovieID]; ok {
+				movie.MovieGenre[mg.ID] = mg.Genre.GenreName
+				movie.GenreIDs = append(movie.GenreIDs, mg.GenreID)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // すべてのmovieかerrorを返すメソッド(DBModelのポインタレシーバ)
@@ -87,16 +177,20 @@ func (m *DBModel) All(genre ...int) ([]*Movie, error) {
 	// 3sでタイムアウトする
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
+	ctx = m.reader(ctx)
 
-	where := ""
+	// ゴミ箱のmovieは除く
+	where := "where deleted_at is null"
+	var args []interface{}
 	if len(genre) > 0 {
-		where = fmt.Sprintf("where id in (select movie_id from movies_genres where genre_id = %d)", genre[0])
+		where += " and id in (select movie_id from movies_genres where genre_id = $1)"
+		args = append(args, genre[0])
 	}
 
 	query := fmt.Sprintf(`select id, title, description, year, release_date, runtime, rating, mpaa_rating,
-							created_at, updated_at from movies %s order by title`, where)
+							created_at, updated_at, version, review_count, review_score, coalesce(poster, '') from movies %s order by title`, where)
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -112,60 +206,273 @@ func (m *DBModel) All(genre ...int) ([]*Movie, error) {
 			&movie.Description,
 			&movie.Year,
 			&movie.ReleaseDate,
+			&movie.Runtime,
 			&movie.Rating,
+			&movie.MPAARating,
+			&movie.CreatedAt,
+			&movie.UpdatedAt,
+			&movie.Version,
+			&movie.ReviewCount,
+			&movie.ReviewScore,
+			&movie.Poster,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	// genreを取得する前に接続を返す
+	rows.Close()
+
+	// get genres, if any
+	err = m.loadGenresBatch(ctx, movies)
+	if err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
+
+// idsのmovieをidsの順に返すメソッド(見つからないものとゴミ箱のものは飛ばす)
+func (m *DBModel) GetMany(ids []int) ([]*Movie, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// 3sでタイムアウトする
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	ctx = m.reader(ctx)
+
+	var args []interface{}
+	var placeholders []string
+	for _, id := range ids {
+		args = append(args, id)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(`select id, title, description, year, release_date, runtime, rating, mpaa_rating,
+							created_at, updated_at, version, review_count, review_score, coalesce(poster, '') from movies
+							where id in (%s) and deleted_at is null`, strings.Join(placeholders, ", "))
+
+	rows, err := m.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*Movie)
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.Title,
+			&movie.Description,
+			&movie.Year,
+			&movie.ReleaseDate,
 			&movie.Runtime,
+			&movie.Rating,
 			&movie.MPAARating,
 			&movie.CreatedAt,
 			&movie.UpdatedAt,
+			&movie.Version,
+			&movie.ReviewCount,
+			&movie.ReviewScore,
+			&movie.Poster,
 		)
 		if err != nil {
 			return nil, err
 		}
+		byID[movie.ID] = &movie
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	// genreを取得する前に接続を返す
+	rows.Close()
 
-		// get genres, if any
-		genreQuery := `select
-			mg.id, mg.movie_id, mg.genre_id, g.genre_name
-		from
-			movies_genres mg
-			left join genres g on (g.id = mg.genre_id)
-		where
-			mg.movie_id = $1
-		`
-
-		genreRows, _ := m.DB.QueryContext(ctx, genreQuery, movie.ID)
-	
-		genres := make(map[int]string)
-		for genreRows.Next() {
-			var mg MovieGenre
-			err := genreRows.Scan(
-				&mg.ID,
-				&mg.MovieID,
-				&mg.GenreID,
-				&mg.Genre.GenreName,
-			)
-			if err != nil {
-				return nil, err
-			}
-			genres[mg.ID] = mg.Genre.GenreName
+	// idsの順に並べ直す
+	var movies []*Movie
+	for _, id := range ids {
+		if movie, ok := byID[id]; ok {
+			movies = append(movies, movie)
 		}
-		genreRows.Close()
+	}
+
+	err = m.loadGenresBatch(ctx, movies)
+	if err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
+
+// filterの条件をwhere句とorder by句に変換する(値はすべてプレースホルダで渡す)
+func movieFilterSQL(filter MovieFilter, arg func(interface{}) string) (string, string, error) {
+	err := filter.Validate()
+	if err != nil {
+		return "", "", err
+	}
+
+	// ゴミ箱のmovieは除く
+	conditions := []string{"deleted_at is null"}
+
+	if len(filter.GenreIDs) > 0 {
+		var ids []string
+		for _, id := range filter.GenreIDs {
+			ids = append(ids, arg(id))
+		}
+		if filter.GenreMatch == "all" {
+			conditions = append(conditions, fmt.Sprintf(`id in (select movie_id from movies_genres where genre_id in (%s)
+				group by movie_id having count(distinct genre_id) = %s)`, strings.Join(ids, ", "), arg(len(filter.GenreIDs))))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("id in (select movie_id from movies_genres where genre_id in (%s))", strings.Join(ids, ", ")))
+		}
+	}
+
+	if filter.RequiredGenreID > 0 {
+		conditions = append(conditions, "id in (select movie_id from movies_genres where genre_id = "+arg(filter.RequiredGenreID)+")")
+	}
+
+	if filter.TagID > 0 {
+		conditions = append(conditions, "id in (select movie_id from movie_tags where tag_id = "+arg(filter.TagID)+")")
+	}
+
+	if filter.YearMin > 0 {
+		conditions = append(conditions, "year >= "+arg(filter.YearMin))
+	}
+	if filter.YearMax > 0 {
+		conditions = append(conditions, "year <= "+arg(filter.YearMax))
+	}
+	if !filter.ReleasedAfter.IsZero() {
+		conditions = append(conditions, "release_date >= "+arg(filter.ReleasedAfter))
+	}
+	if !filter.ReleasedBefore.IsZero() {
+		conditions = append(conditions, "release_date <= "+arg(filter.ReleasedBefore))
+	}
+	if filter.RuntimeMin > 0 {
+		conditions = append(conditions, "runtime >= "+arg(filter.RuntimeMin))
+	}
+	if filter.RuntimeMax > 0 {
+		conditions = append(conditions, "runtime <= "+arg(filter.RuntimeMax))
+	}
+	if filter.RatingMin > 0 {
+		conditions = append(conditions, "rating >= "+arg(filter.RatingMin))
+	}
+	if len(filter.MPAARatings) > 0 {
+		var ratings []string
+		for _, r := range filter.MPAARatings {
+			ratings = append(ratings, arg(r))
+		}
+		conditions = append(conditions, fmt.Sprintf("mpaa_rating in (%s)", strings.Join(ratings, ", ")))
+	}
+
+	field, desc := parseSort(filter.sort())
+	column := movieSortFields[field]
+	direction, op := "asc", ">"
+	if desc {
+		direction, op = "desc", "<"
+	}
+
+	if filter.Cursor != "" {
+		after, err := decodeCursor(filter.Cursor, filter.sort())
+		if err != nil {
+			return "", "", err
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)", column, op, arg(movieSortValue(after, field)), arg(after.ID)))
+	}
+
+	where := "where " + strings.Join(conditions, " and ")
+
+	orderBy := fmt.Sprintf("order by %s %s, id %s", column, direction, direction)
+	if column == "id" {
+		orderBy = "order by id " + direction
+	}
+
+	return where, orderBy, nil
+}
+
+// filterで絞り込み・並び替えをしてページ分割したmovieを返すメソッド
+func (m *DBModel) List(filter MovieFilter) (*MoviePage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	ctx = m.reader(ctx)
+
+	var args []interface{}
+
+	// プレースホルダ($1, $2...)を追加して番号を返す
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
 
-		movie.MovieGenre = genres
+	where, orderBy, err := movieFilterSQL(filter, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	// 次のページがあるかを判定するために1件多く取得する
+	limit := filter.limit()
+	query := fmt.Sprintf(`select id, title, description, year, release_date, runtime, rating, mpaa_rating,
+							created_at, updated_at, version, review_count, review_score, coalesce(poster, '') from movies %s %s limit %s`, where, orderBy, arg(limit+1))
+
+	rows, err := m.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movies []*Movie
+
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.Title,
+			&movie.Description,
+			&movie.Year,
+			&movie.ReleaseDate,
+			&movie.Runtime,
+			&movie.Rating,
+			&movie.MPAARating,
+			&movie.CreatedAt,
+			&movie.UpdatedAt,
+			&movie.Version,
+			&movie.ReviewCount,
+			&movie.ReviewScore,
+			&movie.Poster,
+		)
+		if err != nil {
+			return nil, err
+		}
 		movies = append(movies, &movie)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
+	err = m.loadGenresBatch(ctx, movies)
+	if err != nil {
+		return nil, err
+	}
 
-	return movies, nil
+	return newMoviePage(movies, limit, filter.sort()), nil
 }
 
 func(m *DBModel) GenresAll() ([]*Genre, error) {
 		// 3sでタイムアウトする
 		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 		defer cancel()
+		ctx = m.reader(ctx)
 
 		query := `select id, genre_name, created_at, updated_at from genres order by genre_name`
 
-		rows, err := m.DB.QueryContext(ctx, query)
+		rows, err := m.conn(ctx).QueryContext(ctx, query)
 		if err != nil {
 			return nil, err
 		}
@@ -190,14 +497,39 @@ func(m *DBModel) GenresAll() ([]*Genre, error) {
 		return genres, nil
 }
 
-func (m *DBModel) InsertMovie(movie Movie) error {
+// movieを追加して新しいidを返す(GenreIDsのgenreとTagNamesのタグも紐づけ、userIDの履歴を記録する)
+func (m *DBModel) InsertMovie(movie Movie, userID int) (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	stmt := `insert into movies (title, description, year, release_date, runtime, rating, mpaa_rating, created_at, updated_at) values ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := m.insertMovieTx(ctx, tx, movie, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// トランザクションの中でmovieを追加してgenreの紐づけと履歴を記録する
+func (m *DBModel) insertMovieTx(ctx context.Context, tx *sql.Tx, movie Movie, userID int) (int, error) {
+	// search_vectorは全文検索用(titleの重みA、descriptionの重みB)
+	stmt := `insert into movies (title, description, year, release_date, runtime, rating, mpaa_rating, created_at, updated_at, search_vector)
+						values ($1, $2, $3, $4, $5, $6, $7, $8, $9, ` + m.dialect.searchVector("$10", "$11") + `) returning id`
 	// stmt := `insert into movies (title, description, year, release_date, runtime, rating, mpaa_rating, created_at, updated_at, poster) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
 
-	_, err := m.DB.ExecContext(ctx, stmt,
+	var id int
+	err := tx.QueryRowContext(ctx, stmt,
 		movie.Title,
 		movie.Description,
 		movie.Year,
@@ -208,28 +540,72 @@ func (m *DBModel) InsertMovie(movie Movie) error {
 		movie.CreatedAt,
 		movie.UpdatedAt,
 		// movie.Poster,
-	)
+		movie.Title,
+		movie.Description,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if movie.GenreIDs != nil {
+		err = replaceMovieGenres(ctx, tx, id, movie.GenreIDs)
+		if err != nil {
+			return 0, err
+		}
+	}
 
+	if movie.TagNames != nil {
+		err = replaceMovieTags(ctx, tx, id, movie.TagNames)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = insertRevision(ctx, tx, id, RevisionInsert, userID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return id, nil
 }
 
-func (m *DBModel) UpdateMovie(movie Movie) error {
+// movieを更新する(GenreIDsやTagNamesがnilでなければgenreやタグの紐づけも置き換え、userIDの履歴を記録する)
+func (m *DBModel) UpdateMovie(movie Movie, userID int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.updateMovieTx(ctx, tx, movie)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(ctx, tx, movie.ID, RevisionUpdate, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// トランザクションの中でmovieの行とgenreの紐づけを更新する
+// (movie.Versionが現在のversionと異なる場合はErrVersionConflictを返す)
+func (m *DBModel) updateMovieTx(ctx context.Context, tx *sql.Tx, movie Movie) error {
 	stmt := `update movies set title = $1, description = $2, year = $3, release_date = $4, 
 						runtime = $5, rating = $6, mpaa_rating = $7, 
-						updated_at = $8 where id = $9`
+						updated_at = $8, search_vector = ` + m.dialect.searchVector("$10", "$11") + `, version = version + 1
+						where id = $9 and version = $12 and deleted_at is null`
 	
 	// stmt := `update movies set title = $1, description = $2, year = $3, release_date = $4, 
 	// 					runtime = $5, rating = $6, mpaa_rating = $7, 
 	// 					updated_at = $8, poster = $9 where id = $10`
 
-	_, err := m.DB.ExecContext(ctx, stmt,
+	res, err := tx.ExecContext(ctx, stmt,
 		movie.Title,
 		movie.Description,
 		movie.Year,
@@ -240,25 +616,155 @@ func (m *DBModel) UpdateMovie(movie Movie) error {
 		movie.UpdatedAt,
 		// movie.Poster,
 		movie.ID,
+		movie.Title,
+		movie.Description,
+		movie.Version,
 	)
+	if err != nil {
+		return err
+	}
 
+	n, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if n == 0 {
+		// 行がないのか、ほかの更新が先に入ったのかを区別する
+		var exists bool
+		err = tx.QueryRowContext(ctx, `select exists(select 1 from movies where id = $1 and deleted_at is null)`, movie.ID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrVersionConflict
+		}
+		return sql.ErrNoRows
+	}
+
+	if movie.GenreIDs != nil {
+		err = replaceMovieGenres(ctx, tx, movie.ID, movie.GenreIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	if movie.TagNames != nil {
+		err = replaceMovieTags(ctx, tx, movie.ID, movie.TagNames)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-func (m *DBModel) DeleteMovie(id int) error {
+// movieのgenreの紐づけをgenreIDsで置き換える
+func replaceMovieGenres(ctx context.Context, tx *sql.Tx, movieID int, genreIDs []int) error {
+	genreIDs = uniqueInts(genreIDs)
+
+	if len(genreIDs) > 0 {
+		var args []interface{}
+		var placeholders []string
+		for _, id := range genreIDs {
+			args = append(args, id)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+
+		var found int
+		query := fmt.Sprintf("select count(*) from genres where id in (%s)", strings.Join(placeholders, ", "))
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&found)
+		if err != nil {
+			return err
+		}
+		if found != len(genreIDs) {
+			return ErrUnknownGenre
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, `delete from movies_genres where movie_id = $1`, movieID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, genreID := range genreIDs {
+		stmt := `insert into movies_genres (movie_id, genre_id, created_at, updated_at) values ($1, $2, $3, $4)`
+		_, err = tx.ExecContext(ctx, stmt, movieID, genreID, now, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// movieをゴミ箱に移す(deleted_atをセットしてuserIDの履歴を記録する)
+func (m *DBModel) DeleteMovie(id int, userID int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	stmt := "delete from movies where id = $1"
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := "update movies set deleted_at = $1, version = version + 1 where id = $2 and deleted_at is null"
 
-	_, err := m.DB.ExecContext(ctx, stmt, id)
+	res, err := tx.ExecContext(ctx, stmt, time.Now(), id)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
\ No newline at end of file
+	// すでに削除済みの場合は履歴を残さない
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	err = insertRevision(ctx, tx, id, RevisionDelete, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// movieのポスターのキーを差し替えて、前のキーを返す(keyが空ならポスターを外す。versionを上げてuserIDの履歴を記録する)
+func (m *DBModel) SetPoster(movieID int, key string, userID int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var old string
+	err = tx.QueryRowContext(ctx, `select coalesce(poster, '') from movies where id = $1 and deleted_at is null` + m.dialect.rowLock("for update"), movieID).Scan(&old)
+	if err != nil {
+		return "", err
+	}
+
+	// ETagが変わるようにversionを上げて、履歴にも残す
+	_, err = tx.ExecContext(ctx, `update movies set poster = nullif($1, ''), version = version + 1, updated_at = $2 where id = $3`, key, time.Now(), movieID)
+	if err != nil {
+		return "", err
+	}
+
+	err = insertRevision(ctx, tx, movieID, RevisionUpdate, userID)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	return old, nil
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// クエリごとに往復の遅延を入れる偽のドライバ(Postgresなしでクエリ回数の差を測る)
+type fakeConnector struct {
+	movies int
+	latency time.Duration
+	queries int64
+	// replicaとして使うときの遅れ(秒)と、接続できない状態
+	lag float64
+	down bool
+	// WAL receiverが切れている(遅れのクエリがnullを返す)
+	disconnected bool
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{c}, nil }
+func (c *fakeConnector) Driver() driver.Driver { return nil }
+
+type fakeConn struct {
+	c *fakeConnector
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.c.down {
+		return nil, errors.New("connection refused")
+	}
+	atomic.AddInt64(&c.c.queries, 1)
+	time.Sleep(c.c.latency)
+
+	rows := &fakeRows{}
+	now := time.Now()
+
+	switch {
+	case strings.Contains(query, "pg_last_wal_replay_lsn"):
+		rows.columns = []string{"lag"}
+		if c.c.disconnected {
+			rows.values = append(rows.values, []driver.Value{nil})
+		} else {
+			rows.values = append(rows.values, []driver.Value{c.c.lag})
+		}
+	case strings.Contains(query, "from movies_genres"), strings.Contains(query, "movies_genres mg"):
+		rows.columns = []string{"id", "movie_id", "genre_id", "genre_name"}
+		// movieごとに2つのgenreを返す
+		for _, arg := range args {
+			id := arg.Value.(int64)
+			rows.values = append(rows.values,
+				[]driver.Value{id * 10, id, int64(1), "Drama"},
+				[]driver.Value{id*10 + 1, id, int64(2), "Crime"},
+			)
+		}
+	case strings.Contains(query, "from movies"):
+		rows.columns = []string{"id", "title", "description", "year", "release_date", "runtime", "rating", "mpaa_rating", "created_at", "updated_at", "version", "review_count", "review_score", "poster"}
+		for i := 1; i <= c.c.movies; i++ {
+			rows.values = append(rows.values, []driver.Value{
+				int64(i), fmt.Sprintf("Movie %05d", i), "description", int64(2000), now, int64(120), int64(3), "PG", now, now, int64(1), int64(0), float64(0), "",
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func newFakeDBModel(movies int, latency time.Duration) (*DBModel, *fakeConnector) {
+	c := &fakeConnector{movies: movies, latency: latency}
+	return &DBModel{DB: sql.OpenDB(c)}, c
+}
+
+func TestAllLoadsGenresInBatches(t *testing.T) {
+	m, c := newFakeDBModel(2000, 0)
+	defer m.DB.Close()
+
+	movies, err := m.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(movies) != 2000 {
+		t.Fatalf("got %d movies, want 2000", len(movies))
+	}
+	if len(movies[1999].MovieGenre) != 2 || fmt.Sprint(movies[1999].GenreIDs) != "[1 2]" {
+		t.Errorf("genres of last movie = %v / %v", movies[1999].MovieGenre, movies[1999].GenreIDs)
+	}
+
+	// moviesの1回 + genreBatchSize件ごとに1回
+	want := int64(1 + (2000+genreBatchSize-1)/genreBatchSize)
+	if c.queries != want {
+		t.Errorf("All ran %d queries, want %d", c.queries, want)
+	}
+}
+
+// 往復200µsのDBで2,000本のmovieのgenresを読み込む
+func BenchmarkLoadGenres(b *testing.B) {
+	const movies = 2000
+	const latency = 200 * time.Microsecond
+
+	b.Run("per-movie", func(b *testing.B) {
+		m, _ := newFakeDBModel(movies, latency)
+		defer m.DB.Close()
+
+		list, err := m.All()
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, movie := range list {
+				err := m.loadGenres(context.Background(), movie)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("batched", func(b *testing.B) {
+		m, _ := newFakeDBModel(movies, latency)
+		defer m.DB.Close()
+
+		list, err := m.All()
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			err := m.loadGenresBatch(context.Background(), list)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
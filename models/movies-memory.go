@@ -0,0 +1,417 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryModel is a thread-safe in-memory implementation of MovieStore
+type MemoryModel struct {
+	mu sync.RWMutex
+	movies map[int]Movie
+	genres map[int]Genre
+	movieGenres map[int]MovieGenre
+	revisions []Revision
+	people map[int]Person
+	credits map[int]Credit
+	reviews map[int]Review
+	reviewVotes map[reviewVote]bool
+	listEntries map[int]ListEntry
+	suggestions map[int]MetadataSuggestion
+	movieTranslations map[translationKey]MovieTranslation
+	genreTranslations map[translationKey]GenreTranslation
+	collections map[int]Collection
+	// collectionのidごとのmovieのid(positionの順)
+	collectionMovies map[int][]int
+	releases map[releaseKey]MovieRelease
+	certifications map[releaseKey]MovieCertification
+	tags map[int]Tag
+	// 別名のslugとタグのid
+	tagSynonyms map[string]int
+	// movieのidごとのタグのid
+	movieTags map[int][]int
+	nextMovieID int
+	nextGenreID int
+	nextMovieGenreID int
+	nextRevisionID int
+	nextPersonID int
+	nextCreditID int
+	nextReviewID int
+	nextListEntryID int
+	nextSuggestionID int
+	nextCollectionID int
+	nextTagID int
+}
+
+// NewMemoryModel returns an empty in-memory store
+func NewMemoryModel() *MemoryModel {
+	return &MemoryModel{
+		movies: make(map[int]Movie),
+		genres: make(map[int]Genre),
+		movieGenres: make(map[int]MovieGenre),
+		people: make(map[int]Person),
+		credits: make(map[int]Credit),
+		reviews: make(map[int]Review),
+		reviewVotes: make(map[reviewVote]bool),
+		listEntries: make(map[int]ListEntry),
+		suggestions: make(map[int]MetadataSuggestion),
+		movieTranslations: make(map[translationKey]MovieTranslation),
+		genreTranslations: make(map[translationKey]GenreTranslation),
+		collections: make(map[int]Collection),
+		collectionMovies: make(map[int][]int),
+		releases: make(map[releaseKey]MovieRelease),
+		certifications: make(map[releaseKey]MovieCertification),
+		tags: make(map[int]Tag),
+		tagSynonyms: make(map[string]int),
+		movieTags: make(map[int][]int),
+		nextMovieID: 1,
+		nextGenreID: 1,
+		nextMovieGenreID: 1,
+		nextRevisionID: 1,
+		nextPersonID: 1,
+		nextCreditID: 1,
+		nextReviewID: 1,
+		nextListEntryID: 1,
+		nextSuggestionID: 1,
+		nextCollectionID: 1,
+		nextTagID: 1,
+	}
+}
+
+// movieの値をコピーしてgenresを割り当てる(呼び出し元がストアの中身を書き換えないようにする)
+func (m *MemoryModel) copyMovie(movie Movie) *Movie {
+	genres := make(map[int]string)
+	genreIDs := []int{}
+	for _, mg := range m.movieGenres {
+		if mg.MovieID == movie.ID {
+			genres[mg.ID] = m.genres[mg.GenreID].GenreName
+			genreIDs = append(genreIDs, mg.GenreID)
+		}
+	}
+	sort.Ints(genreIDs)
+	movie.MovieGenre = genres
+	movie.GenreIDs = genreIDs
+
+	return &movie
+}
+
+func (m *MemoryModel) Get(id int) (*Movie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	movie, ok := m.movies[id]
+	if !ok || movie.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	return m.copyMovie(movie), nil
+}
+
+func (m *MemoryModel) GetMany(ids []int) ([]*Movie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var movies []*Movie
+	for _, id := range ids {
+		movie, ok := m.movies[id]
+		if !ok || movie.DeletedAt != nil {
+			continue
+		}
+		movies = append(movies, m.copyMovie(movie))
+	}
+
+	return movies, nil
+}
+
+func (m *MemoryModel) All(genre ...int) ([]*Movie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var movies []*Movie
+
+	for _, movie := range m.movies {
+		if movie.DeletedAt != nil {
+			continue
+		}
+		if len(genre) > 0 && !m.hasGenre(movie.ID, genre[0]) {
+			continue
+		}
+		movies = append(movies, m.copyMovie(movie))
+	}
+
+	// DBModelと同じくtitle順に並べる
+	sort.Slice(movies, func(i, j int) bool {
+		if movies[i].Title != movies[j].Title {
+			return movies[i].Title < movies[j].Title
+		}
+		return movies[i].ID < movies[j].ID
+	})
+
+	return movies, nil
+}
+
+func (m *MemoryModel) List(filter MovieFilter) (*MoviePage, error) {
+	err := filter.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var after *Movie
+	if filter.Cursor != "" {
+		after, err = decodeCursor(filter.Cursor, filter.sort())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.RLock()
+	var all []*Movie
+	for _, movie := range m.movies {
+		if movie.DeletedAt != nil {
+			continue
+		}
+		genreIDs := make(map[int]bool)
+		for _, mg := range m.movieGenres {
+			if mg.MovieID == movie.ID {
+				genreIDs[mg.GenreID] = true
+			}
+		}
+		movie := movie
+		if filter.TagID > 0 && !m.hasTag(movie.ID, filter.TagID) {
+			continue
+		}
+		if filter.matches(&movie, genreIDs) {
+			all = append(all, m.copyMovie(movie))
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return compareMovies(all[i], all[j], filter.sort()) < 0
+	})
+
+	// カーソルより後ろのmovieをlimit+1件取り出す
+	limit := filter.limit()
+	var movies []*Movie
+	for _, movie := range all {
+		if after != nil && compareMovies(movie, after, filter.sort()) <= 0 {
+			continue
+		}
+		movies = append(movies, movie)
+		if len(movies) > limit {
+			break
+		}
+	}
+
+	return newMoviePage(movies, limit, filter.sort()), nil
+}
+
+func (m *MemoryModel) hasGenre(movieID, genreID int) bool {
+	for _, mg := range m.movieGenres {
+		if mg.MovieID == movieID && mg.GenreID == genreID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryModel) GenresAll() ([]*Genre, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var genres []*Genre
+
+	for _, g := range m.genres {
+		g := g
+		genres = append(genres, &g)
+	}
+
+	sort.Slice(genres, func(i, j int) bool {
+		return genres[i].GenreName < genres[j].GenreName
+	})
+
+	return genres, nil
+}
+
+func (m *MemoryModel) InsertMovie(movie Movie, userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.insertMovie(movie, userID)
+}
+
+// movieを追加してgenreの紐づけと履歴を記録する(ロックを取得してから呼ぶ)
+func (m *MemoryModel) insertMovie(movie Movie, userID int) (int, error) {
+	movie.ID = m.nextMovieID
+
+	// genreを紐づける前にタグを検証しておく(途中で失敗したときに紐づけが残らないようにする)
+	if movie.TagNames != nil {
+		_, err := normalizeTagNames(movie.TagNames)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if movie.GenreIDs != nil {
+		err := m.replaceMovieGenres(movie.ID, movie.GenreIDs)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if movie.TagNames != nil {
+		err := m.replaceMovieTags(movie.ID, movie.TagNames)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	m.nextMovieID++
+	movie.Version = 1
+	movie.ReviewCount = 0
+	movie.ReviewScore = 0
+	movie.MovieGenre = nil
+	movie.GenreIDs = nil
+	movie.Tags = nil
+	movie.TagNames = nil
+	m.movies[movie.ID] = movie
+
+	m.recordRevision(movie.ID, RevisionInsert, userID)
+
+	return movie.ID, nil
+}
+
+func (m *MemoryModel) UpdateMovie(movie Movie, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.updateMovie(movie)
+	if err != nil {
+		return err
+	}
+
+	m.recordRevision(movie.ID, RevisionUpdate, userID)
+
+	return nil
+}
+
+// movieとgenreの紐づけを更新する(ロックを取得してから呼ぶ)
+func (m *MemoryModel) updateMovie(movie Movie) error {
+	current, ok := m.movies[movie.ID]
+	if !ok || current.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+	if movie.Version != current.Version {
+		return ErrVersionConflict
+	}
+
+	if movie.TagNames != nil {
+		_, err := normalizeTagNames(movie.TagNames)
+		if err != nil {
+			return err
+		}
+	}
+
+	if movie.GenreIDs != nil {
+		err := m.replaceMovieGenres(movie.ID, movie.GenreIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	if movie.TagNames != nil {
+		err := m.replaceMovieTags(movie.ID, movie.TagNames)
+		if err != nil {
+			return err
+		}
+	}
+
+	movie.CreatedAt = current.CreatedAt
+	movie.DeletedAt = current.DeletedAt
+	movie.ReviewCount = current.ReviewCount
+	movie.ReviewScore = current.ReviewScore
+	movie.Poster = current.Poster
+	movie.Version = current.Version + 1
+	movie.MovieGenre = nil
+	movie.GenreIDs = nil
+	movie.Tags = nil
+	movie.TagNames = nil
+	m.movies[movie.ID] = movie
+
+	return nil
+}
+
+// movieのgenreの紐づけをgenreIDsで置き換える(ロックを取得してから呼ぶ)
+func (m *MemoryModel) replaceMovieGenres(movieID int, genreIDs []int) error {
+	genreIDs = uniqueInts(genreIDs)
+
+	for _, id := range genreIDs {
+		if _, ok := m.genres[id]; !ok {
+			return ErrUnknownGenre
+		}
+	}
+
+	for mgID, mg := range m.movieGenres {
+		if mg.MovieID == movieID {
+			delete(m.movieGenres, mgID)
+		}
+	}
+
+	for _, genreID := range genreIDs {
+		mg := MovieGenre{
+			ID: m.nextMovieGenreID,
+			MovieID: movieID,
+			GenreID: genreID,
+		}
+		m.nextMovieGenreID++
+		m.movieGenres[mg.ID] = mg
+	}
+
+	return nil
+}
+
+// movieをゴミ箱に移す(deleted_atをセットする)
+func (m *MemoryModel) DeleteMovie(id int, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[id]
+	if !ok || movie.DeletedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	movie.DeletedAt = &now
+	movie.Version++
+	m.movies[id] = movie
+
+	m.recordRevision(id, RevisionDelete, userID)
+
+	return nil
+}
+
+func (m *MemoryModel) SetPoster(movieID int, key string, userID int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[movieID]
+	if !ok || movie.DeletedAt != nil {
+		return "", sql.ErrNoRows
+	}
+
+	old := movie.Poster
+	movie.Poster = key
+	movie.Version++
+	movie.UpdatedAt = time.Now()
+	m.movies[movieID] = movie
+	m.recordRevision(movieID, RevisionUpdate, userID)
+
+	return old, nil
+}
+
+// Primary returns the store itself (the memory store has no replicas)
+func (m *MemoryModel) Primary() MovieStore {
+	return m
+}
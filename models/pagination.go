@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	// DefaultPageLimit is used when the client does not ask for a page size
+	DefaultPageLimit = 20
+	// MaxPageLimit is the largest page a client may request
+	MaxPageLimit = 100
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// MoviePage is one page of movies in (sort field, id) order
+type MoviePage struct {
+	Movies []*Movie `json:"movies"`
+	NextCursor string `json:"next_cursor"`
+	HasMore bool `json:"has_more"`
+}
+
+// カーソルの中身(並び替えの項目と最後に返したmovieの値とid)
+type movieCursor struct {
+	Sort string `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID int `json:"id"`
+}
+
+func encodeCursor(movie *Movie, sort string) string {
+	field, _ := parseSort(sort)
+	value, _ := json.Marshal(movieSortValue(movie, field))
+	js, _ := json.Marshal(movieCursor{Sort: sort, Value: value, ID: movie.ID})
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// カーソルをデコードして、並び替えの項目に値をセットしたmovieを返す
+func decodeCursor(cursor string, sort string) (*Movie, error) {
+	js, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c movieCursor
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.ID <= 0 || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+
+	field, _ := parseSort(sort)
+	movie := Movie{ID: c.ID}
+	err = setMovieSortValue(&movie, field, c.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &movie, nil
+}
+
+// limitを補正する
+func (f MovieFilter) limit() int {
+	if f.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if f.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return f.Limit
+}
+
+// limit+1件取得した結果からページを組み立てる
+func newMoviePage(movies []*Movie, limit int, sort string) *MoviePage {
+	page := &MoviePage{Movies: movies}
+
+	if len(movies) > limit {
+		page.Movies = movies[:limit]
+		page.HasMore = true
+		page.NextCursor = encodeCursor(page.Movies[limit-1], sort)
+	}
+
+	if page.Movies == nil {
+		page.Movies = []*Movie{}
+	}
+
+	return page
+}
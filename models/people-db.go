@@ -0,0 +1,296 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// すべての人を名前順に返すメソッド
+func (m *DBModel) People() ([]*Person, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select id, name, biography, created_at, updated_at from people order by name, id`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	people := []*Person{}
+
+	for rows.Next() {
+		var p Person
+		err := rows.Scan(
+			&p.ID,
+			&p.Name,
+			&p.Biography,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		people = append(people, &p)
+	}
+
+	return people, rows.Err()
+}
+
+func (m *DBModel) GetPerson(id int) (*Person, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select id, name, biography, created_at, updated_at from people where id = $1`
+
+	var p Person
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&p.ID,
+		&p.Name,
+		&p.Biography,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (m *DBModel) InsertPerson(person Person) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	name, err := normalizePersonName(person.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt := `insert into people (name, biography, created_at, updated_at) values ($1, $2, $3, $4) returning id`
+
+	var id int
+	err = m.DB.QueryRowContext(ctx, stmt, name, person.Biography, person.CreatedAt, person.UpdatedAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (m *DBModel) UpdatePerson(person Person) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	name, err := normalizePersonName(person.Name)
+	if err != nil {
+		return err
+	}
+
+	stmt := `update people set name = $1, biography = $2, updated_at = $3 where id = $4`
+
+	res, err := m.DB.ExecContext(ctx, stmt, name, person.Biography, person.UpdatedAt, person.ID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// 人を削除する(creditが残っている場合はErrPersonInUseを返す)
+func (m *DBModel) DeletePerson(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `select true from people where id = $1` + m.dialect.rowLock("for update"), id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	// ゴミ箱のmovieのcreditも含める(復元できるように)
+	var inUse int
+	err = tx.QueryRowContext(ctx, `select count(*) from movie_credits where person_id = $1`, id).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse > 0 {
+		return ErrPersonInUse
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from people where id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// movieのcreditsをbilling_order順に返すメソッド
+func (m *DBModel) Credits(movieID int) ([]*Credit, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select
+					c.id, c.movie_id, c.person_id, p.name, c.role, c.character_name, c.billing_order
+				from
+					movie_credits c
+					join people p on (p.id = c.person_id)
+				where
+					c.movie_id = $1
+				order by
+					c.billing_order, c.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		var c Credit
+		err := rows.Scan(
+			&c.ID,
+			&c.MovieID,
+			&c.PersonID,
+			&c.Name,
+			&c.Role,
+			&c.CharacterName,
+			&c.BillingOrder,
+		)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, &c)
+	}
+
+	return credits, rows.Err()
+}
+
+// movieのcreditsをcreditsで置き換える
+func (m *DBModel) ReplaceCredits(movieID int, credits []Credit) error {
+	err := validateCredits(credits)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `select true from movies where id = $1 and deleted_at is null` + m.dialect.rowLock("for update"), movieID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	personIDs := []int{}
+	for _, c := range credits {
+		personIDs = append(personIDs, c.PersonID)
+	}
+	personIDs = uniqueInts(personIDs)
+
+	if len(personIDs) > 0 {
+		var args []interface{}
+		var placeholders []string
+		for _, id := range personIDs {
+			args = append(args, id)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+
+		var found int
+		query := fmt.Sprintf("select count(*) from people where id in (%s)", strings.Join(placeholders, ", "))
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&found)
+		if err != nil {
+			return err
+		}
+		if found != len(personIDs) {
+			return ErrUnknownPerson
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from movie_credits where movie_id = $1`, movieID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, c := range credits {
+		stmt := `insert into movie_credits (movie_id, person_id, role, character_name, billing_order, created_at, updated_at)
+							values ($1, $2, $3, $4, $5, $6, $7)`
+		_, err = tx.ExecContext(ctx, stmt, movieID, c.PersonID, c.Role, c.CharacterName, c.BillingOrder, now, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// 人が参加したmovie(ゴミ箱のものを除く)を公開日の新しい順に返すメソッド
+func (m *DBModel) Filmography(personID int) ([]*FilmographyEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select
+					m.id, m.title, m.year, m.release_date, c.role, c.character_name, c.billing_order
+				from
+					movie_credits c
+					join movies m on (m.id = c.movie_id)
+				where
+					c.person_id = $1 and m.deleted_at is null
+				order by
+					m.release_date desc, m.id, c.billing_order, c.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, personID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*FilmographyEntry{}
+
+	for rows.Next() {
+		var e FilmographyEntry
+		err := rows.Scan(
+			&e.MovieID,
+			&e.Title,
+			&e.Year,
+			&e.ReleaseDate,
+			&e.Role,
+			&e.CharacterName,
+			&e.BillingOrder,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,209 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+)
+
+func (m *MemoryModel) People() ([]*Person, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	people := []*Person{}
+	for _, p := range m.people {
+		p := p
+		people = append(people, &p)
+	}
+
+	sort.Slice(people, func(i, j int) bool {
+		if people[i].Name != people[j].Name {
+			return people[i].Name < people[j].Name
+		}
+		return people[i].ID < people[j].ID
+	})
+
+	return people, nil
+}
+
+func (m *MemoryModel) GetPerson(id int) (*Person, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.people[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return &p, nil
+}
+
+func (m *MemoryModel) InsertPerson(person Person) (int, error) {
+	name, err := normalizePersonName(person.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	person.ID = m.nextPersonID
+	person.Name = name
+	m.nextPersonID++
+
+	m.people[person.ID] = person
+
+	return person.ID, nil
+}
+
+func (m *MemoryModel) UpdatePerson(person Person) error {
+	name, err := normalizePersonName(person.Name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.people[person.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	current.Name = name
+	current.Biography = person.Biography
+	current.UpdatedAt = person.UpdatedAt
+	m.people[person.ID] = current
+
+	return nil
+}
+
+func (m *MemoryModel) DeletePerson(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.people[id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	for _, c := range m.credits {
+		if c.PersonID == id {
+			return ErrPersonInUse
+		}
+	}
+
+	delete(m.people, id)
+
+	return nil
+}
+
+func (m *MemoryModel) Credits(movieID int) ([]*Credit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	credits := []*Credit{}
+	for _, c := range m.credits {
+		if c.MovieID == movieID {
+			c := c
+			c.Name = m.people[c.PersonID].Name
+			credits = append(credits, &c)
+		}
+	}
+
+	sort.Slice(credits, func(i, j int) bool {
+		if credits[i].BillingOrder != credits[j].BillingOrder {
+			return credits[i].BillingOrder < credits[j].BillingOrder
+		}
+		return credits[i].ID < credits[j].ID
+	})
+
+	return credits, nil
+}
+
+func (m *MemoryModel) ReplaceCredits(movieID int, credits []Credit) error {
+	err := validateCredits(credits)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[movieID]
+	if !ok || movie.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+
+	for _, c := range credits {
+		if _, ok := m.people[c.PersonID]; !ok {
+			return ErrUnknownPerson
+		}
+	}
+
+	for id, c := range m.credits {
+		if c.MovieID == movieID {
+			delete(m.credits, id)
+		}
+	}
+
+	for _, c := range credits {
+		c.ID = m.nextCreditID
+		c.MovieID = movieID
+		c.Name = ""
+		m.nextCreditID++
+		m.credits[c.ID] = c
+	}
+
+	return nil
+}
+
+func (m *MemoryModel) Filmography(personID int) ([]*FilmographyEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type entry struct {
+		FilmographyEntry
+		creditID int
+	}
+
+	var found []entry
+	for _, c := range m.credits {
+		movie, ok := m.movies[c.MovieID]
+		if c.PersonID != personID || !ok || movie.DeletedAt != nil {
+			continue
+		}
+		found = append(found, entry{
+			FilmographyEntry: FilmographyEntry{
+				MovieID: movie.ID,
+				Title: movie.Title,
+				Year: movie.Year,
+				ReleaseDate: movie.ReleaseDate,
+				Role: c.Role,
+				CharacterName: c.CharacterName,
+				BillingOrder: c.BillingOrder,
+			},
+			creditID: c.ID,
+		})
+	}
+
+	// 公開日の新しい順
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if !a.ReleaseDate.Equal(b.ReleaseDate) {
+			return a.ReleaseDate.After(b.ReleaseDate)
+		}
+		if a.MovieID != b.MovieID {
+			return a.MovieID < b.MovieID
+		}
+		if a.BillingOrder != b.BillingOrder {
+			return a.BillingOrder < b.BillingOrder
+		}
+		return a.creditID < b.creditID
+	})
+
+	entries := []*FilmographyEntry{}
+	for i := range found {
+		entries = append(entries, &found[i].FilmographyEntry)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CreditRoles is the list of accepted credit roles
+var CreditRoles = []string{"director", "writer", "producer", "actor", "composer", "cinematographer", "editor"}
+
+var (
+	// ErrUnknownPerson is returned when a credit refers to a person that does not exist
+	ErrUnknownPerson = errors.New("unknown person id")
+	// ErrPersonInUse is returned when deleting a person who is still credited on movies
+	ErrPersonInUse = errors.New("person is still credited on movies")
+)
+
+type Person struct {
+	ID int `json:"id"`
+	Name string `json:"name"`
+	Biography string `json:"biography"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// Credit is one person's role on a movie
+type Credit struct {
+	ID int `json:"id"`
+	MovieID int `json:"-"`
+	PersonID int `json:"person_id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+	// actorのみ
+	CharacterName string `json:"character_name,omitempty"`
+	BillingOrder int `json:"billing_order"`
+}
+
+// FilmographyEntry is one credit of a person together with the movie
+type FilmographyEntry struct {
+	MovieID int `json:"movie_id"`
+	Title string `json:"title"`
+	Year int `json:"year"`
+	ReleaseDate time.Time `json:"release_date"`
+	Role string `json:"role"`
+	CharacterName string `json:"character_name,omitempty"`
+	BillingOrder int `json:"billing_order"`
+}
+
+// 名前の前後の空白を取り除いて検証する
+func normalizePersonName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name must not be empty")
+	}
+	if len(name) > 255 {
+		return "", errors.New("name must not be longer than 255 bytes")
+	}
+	return name, nil
+}
+
+// creditsの値を検証する(同じ人の同じroleは1つまで)
+func validateCredits(credits []Credit) error {
+	seen := make(map[string]bool)
+
+	for i, c := range credits {
+		if c.PersonID <= 0 {
+			return fmt.Errorf("credit %d: invalid person id %d", i, c.PersonID)
+		}
+		if !containsString(CreditRoles, c.Role) {
+			return fmt.Errorf("credit %d: unknown role %q (allowed: %s)", i, c.Role, strings.Join(CreditRoles, ", "))
+		}
+		if c.CharacterName != "" && c.Role != "actor" {
+			return fmt.Errorf("credit %d: character_name is only allowed for actors", i)
+		}
+		if len(c.CharacterName) > 255 {
+			return fmt.Errorf("credit %d: character_name must not be longer than 255 bytes", i)
+		}
+		if c.BillingOrder < 0 {
+			return fmt.Errorf("credit %d: billing_order must not be negative", i)
+		}
+
+		key := fmt.Sprintf("%d/%s", c.PersonID, c.Role)
+		if seen[key] {
+			return fmt.Errorf("credit %d: person %d is already credited as %s", i, c.PersonID, c.Role)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// movieの国ごとの公開日と年齢制限を返すメソッド
+func (m *DBModel) Releases(movieID int) ([]*MovieRelease, []*MovieCertification, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var mpaaRating string
+	err := m.DB.QueryRowContext(ctx, `select mpaa_rating from movies where id = $1 and deleted_at is null`, movieID).Scan(&mpaaRating)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	releases, certifications, err := m.releasesFor(ctx, []int{movieID}, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return releases[movieID], withUSCertification(certifications[movieID], movieID, mpaaRating), nil
+}
+
+// movieIDsの公開日と年齢制限をmovieごとに返す(countryが空でない場合はその国のみ)
+func (m *DBModel) releasesFor(ctx context.Context, movieIDs []int, country string) (map[int][]*MovieRelease, map[int][]*MovieCertification, error) {
+	var args []interface{}
+	var placeholders []string
+	for _, id := range movieIDs {
+		args = append(args, id)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	where := fmt.Sprintf("movie_id in (%s)", strings.Join(placeholders, ", "))
+	if country != "" {
+		args = append(args, country)
+		where += fmt.Sprintf(" and country = $%d", len(args))
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `select movie_id, country, release_type, release_date from movie_releases where `+where, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	releases := make(map[int][]*MovieRelease)
+	for rows.Next() {
+		var r MovieRelease
+		err := rows.Scan(&r.MovieID, &r.Country, &r.Type, &r.ReleaseDate)
+		if err != nil {
+			return nil, nil, err
+		}
+		releases[r.MovieID] = append(releases[r.MovieID], &r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
+	for _, list := range releases {
+		sortReleases(list)
+	}
+
+	rows, err = m.DB.QueryContext(ctx, `select movie_id, country, rating from movie_certifications where `+where+` order by country`, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	certifications := make(map[int][]*MovieCertification)
+	for rows.Next() {
+		var c MovieCertification
+		err := rows.Scan(&c.MovieID, &c.Country, &c.Rating)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.System = CertificationSystems[c.Country].Name
+		certifications[c.MovieID] = append(certifications[c.MovieID], &c)
+	}
+
+	return releases, certifications, rows.Err()
+}
+
+// movieの公開日と年齢制限をすべて置き換える
+func (m *DBModel) ReplaceReleases(movieID int, releases []MovieRelease, certifications []MovieCertification) error {
+	err := validateReleases(releases, certifications)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var mpaaRating string
+	err = tx.QueryRowContext(ctx, `select mpaa_rating from movies where id = $1 and deleted_at is null` + m.dialect.rowLock("for update"), movieID).Scan(&mpaaRating)
+	if err != nil {
+		return err
+	}
+
+	certifications, err = withoutUSCertification(certifications, mpaaRating)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from movie_releases where movie_id = $1`, movieID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `delete from movie_certifications where movie_id = $1`, movieID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, r := range releases {
+		stmt := `insert into movie_releases (movie_id, country, release_type, release_date, created_at, updated_at) values ($1, $2, $3, $4, $5, $6)`
+		_, err = tx.ExecContext(ctx, stmt, movieID, r.Country, r.Type, r.ReleaseDate, now, now)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range certifications {
+		stmt := `insert into movie_certifications (movie_id, country, rating, created_at, updated_at) values ($1, $2, $3, $4, $5)`
+		_, err = tx.ExecContext(ctx, stmt, movieID, c.Country, c.Rating, now, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// regionの公開日と年齢制限をmovieにセットする
+func (m *DBModel) ApplyRegion(movies []*Movie, region string) error {
+	if region == "" || len(movies) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var ids []int
+	for _, movie := range movies {
+		ids = append(ids, movie.ID)
+	}
+
+	releases, certifications, err := m.releasesFor(ctx, ids, region)
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		var certification *MovieCertification
+		if list := certifications[movie.ID]; len(list) > 0 {
+			certification = list[0]
+		}
+		applyRegion(movie, region, releases[movie.ID], certification)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+)
+
+type releaseKey struct {
+	MovieID int
+	Country string
+	Type string
+}
+
+func (m *MemoryModel) Releases(movieID int) ([]*MovieRelease, []*MovieCertification, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	movie, ok := m.movies[movieID]
+	if !ok || movie.DeletedAt != nil {
+		return nil, nil, sql.ErrNoRows
+	}
+
+	releases, certifications := m.releasesFor(movieID, "")
+	return releases, withUSCertification(certifications, movieID, movie.MPAARating), nil
+}
+
+func (m *MemoryModel) releasesFor(movieID int, country string) ([]*MovieRelease, []*MovieCertification) {
+	var releases []*MovieRelease
+	for key, r := range m.releases {
+		if key.MovieID == movieID && (country == "" || key.Country == country) {
+			r := r
+			releases = append(releases, &r)
+		}
+	}
+	sortReleases(releases)
+
+	var certifications []*MovieCertification
+	for key, c := range m.certifications {
+		if key.MovieID == movieID && (country == "" || key.Country == country) {
+			c := c
+			certifications = append(certifications, &c)
+		}
+	}
+	sort.Slice(certifications, func(i, j int) bool {
+		return certifications[i].Country < certifications[j].Country
+	})
+
+	return releases, certifications
+}
+
+func (m *MemoryModel) ReplaceReleases(movieID int, releases []MovieRelease, certifications []MovieCertification) error {
+	err := validateReleases(releases, certifications)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[movieID]
+	if !ok || movie.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+
+	certifications, err = withoutUSCertification(certifications, movie.MPAARating)
+	if err != nil {
+		return err
+	}
+
+	m.deleteReleases(movieID)
+
+	for _, r := range releases {
+		r.MovieID = movieID
+		m.releases[releaseKey{movieID, r.Country, r.Type}] = r
+	}
+	for _, c := range certifications {
+		c.MovieID = movieID
+		m.certifications[releaseKey{MovieID: movieID, Country: c.Country}] = c
+	}
+
+	return nil
+}
+
+// movie_releasesとmovie_certificationsのon delete cascadeに合わせてmovieの公開日と年齢制限を削除する
+func (m *MemoryModel) deleteReleases(movieID int) {
+	for key := range m.releases {
+		if key.MovieID == movieID {
+			delete(m.releases, key)
+		}
+	}
+	for key := range m.certifications {
+		if key.MovieID == movieID {
+			delete(m.certifications, key)
+		}
+	}
+}
+
+func (m *MemoryModel) ApplyRegion(movies []*Movie, region string) error {
+	if region == "" {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, movie := range movies {
+		releases, certifications := m.releasesFor(movie.ID, region)
+		var certification *MovieCertification
+		if len(certifications) > 0 {
+			certification = certifications[0]
+		}
+		applyRegion(movie, region, releases, certification)
+	}
+
+	return nil
+}
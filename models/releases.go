@@ -0,0 +1,183 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Regions is the list of countries release dates and certifications can be stored for
+var Regions = []string{"US", "JP", "GB", "DE"}
+
+// ReleaseTypes is the list of release kinds, in the order used to pick a movie's regional release date
+var ReleaseTypes = []string{"theatrical", "digital", "physical"}
+
+// CertificationSystem is a country's rating board and the ratings it gives
+type CertificationSystem struct {
+	Name string
+	Ratings []string
+}
+
+// CertificationSystems maps each region to its rating board
+var CertificationSystems = map[string]CertificationSystem{
+	"US": {Name: "MPAA", Ratings: MPAARatings},
+	"JP": {Name: "EIRIN", Ratings: []string{"G", "PG12", "R15+", "R18+"}},
+	"GB": {Name: "BBFC", Ratings: []string{"U", "PG", "12A", "12", "15", "18", "R18"}},
+	"DE": {Name: "FSK", Ratings: []string{"0", "6", "12", "16", "18"}},
+}
+
+// ErrUnsupportedRegion is returned for a country other than the supported ones
+var ErrUnsupportedRegion = fmt.Errorf("unsupported region (allowed: %s)", strings.Join(Regions, ", "))
+
+// MovieRelease is the date a movie was released in one country in one form
+type MovieRelease struct {
+	MovieID int `json:"movie_id"`
+	Country string `json:"country"`
+	Type string `json:"type"`
+	ReleaseDate time.Time `json:"release_date"`
+}
+
+// MovieCertification is the rating a movie was given in one country
+type MovieCertification struct {
+	MovieID int `json:"movie_id"`
+	Country string `json:"country"`
+	// 国から決まる(保存はしない)
+	System string `json:"system"`
+	Rating string `json:"rating"`
+}
+
+// NormalizeRegion returns the upper-case country code, or ErrUnsupportedRegion
+func NormalizeRegion(region string) (string, error) {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if !containsString(Regions, region) {
+		return "", fmt.Errorf("region %q: %w", region, ErrUnsupportedRegion)
+	}
+	return region, nil
+}
+
+// 国と種類の組み合わせが重複していないかを確認する
+func validateReleases(releases []MovieRelease, certifications []MovieCertification) error {
+	seen := make(map[string]bool)
+	for i := range releases {
+		r := &releases[i]
+		country, err := NormalizeRegion(r.Country)
+		if err != nil {
+			return err
+		}
+		r.Country = country
+		if !containsString(ReleaseTypes, r.Type) {
+			return fmt.Errorf("release type must be one of %s", strings.Join(ReleaseTypes, ", "))
+		}
+		if r.ReleaseDate.IsZero() {
+			return errors.New("release_date is required")
+		}
+		key := r.Country + "/" + r.Type
+		if seen[key] {
+			return fmt.Errorf("%s %s release appears more than once", r.Country, r.Type)
+		}
+		seen[key] = true
+	}
+
+	for i := range certifications {
+		c := &certifications[i]
+		country, err := NormalizeRegion(c.Country)
+		if err != nil {
+			return err
+		}
+		c.Country = country
+		system := CertificationSystems[country]
+		c.Rating = strings.ToUpper(strings.TrimSpace(c.Rating))
+		if !containsString(system.Ratings, c.Rating) {
+			return fmt.Errorf("%s rating must be one of %s", system.Name, strings.Join(system.Ratings, ", "))
+		}
+		c.System = system.Name
+		if seen[c.Country] {
+			return fmt.Errorf("%s certification appears more than once", c.Country)
+		}
+		seen[c.Country] = true
+	}
+
+	return nil
+}
+
+// 国、種類の順に並べる
+func sortReleases(releases []*MovieRelease) {
+	sort.Slice(releases, func(i, j int) bool {
+		if releases[i].Country != releases[j].Country {
+			return releases[i].Country < releases[j].Country
+		}
+		return releaseTypeOrder(releases[i].Type) < releaseTypeOrder(releases[j].Type)
+	})
+}
+
+func releaseTypeOrder(t string) int {
+	for i, v := range ReleaseTypes {
+		if v == t {
+			return i
+		}
+	}
+	return len(ReleaseTypes)
+}
+
+// USの年齢制限はmovies.mpaa_ratingだけに持ち、movie_certificationsには保存しない
+// USの年齢制限はmpaa_ratingと同じ場合だけ受け付け、取り除いた残りを返す(変更はeditmovieで行う)
+func withoutUSCertification(certifications []MovieCertification, mpaaRating string) ([]MovieCertification, error) {
+	var rest []MovieCertification
+	for _, c := range certifications {
+		if c.Country != "US" {
+			rest = append(rest, c)
+			continue
+		}
+		if c.Rating != mpaaRating {
+			return nil, fmt.Errorf("US rating is the movie's mpaa_rating (%q); change it by editing the movie", mpaaRating)
+		}
+	}
+	return rest, nil
+}
+
+// mpaa_ratingをUSの年齢制限として返す(mpaa_ratingが空の場合はnil)
+func mpaaCertification(movieID int, mpaaRating string) *MovieCertification {
+	if mpaaRating == "" {
+		return nil
+	}
+	return &MovieCertification{MovieID: movieID, Country: "US", System: CertificationSystems["US"].Name, Rating: mpaaRating}
+}
+
+// mpaa_ratingから作ったUSの年齢制限を加えて国の順に並べる
+func withUSCertification(certifications []*MovieCertification, movieID int, mpaaRating string) []*MovieCertification {
+	if c := mpaaCertification(movieID, mpaaRating); c != nil {
+		certifications = append(certifications, c)
+	}
+	sort.Slice(certifications, func(i, j int) bool {
+		return certifications[i].Country < certifications[j].Country
+	})
+	return certifications
+}
+
+// regionの公開日と年齢制限をmovieにセットする
+// 公開日は劇場・配信・パッケージの順で最初にあるものを使い、ない場合は元のrelease_dateのままにする
+// USの年齢制限はmpaa_ratingを使う
+// 絞り込みと並び替えはregionに関係なく元のrelease_dateとmpaa_ratingで行う(regionはレスポンスの表示だけに使う)
+func applyRegion(movie *Movie, region string, releases []*MovieRelease, certification *MovieCertification) {
+	var picked *MovieRelease
+	for _, r := range releases {
+		if picked == nil || releaseTypeOrder(r.Type) < releaseTypeOrder(picked.Type) {
+			picked = r
+		}
+	}
+	if picked != nil {
+		movie.Region = region
+		movie.ReleaseDate = picked.ReleaseDate
+		movie.ReleaseType = picked.Type
+	}
+
+	if region == "US" {
+		certification = mpaaCertification(movie.ID, movie.MPAARating)
+	}
+	if certification != nil {
+		movie.Region = region
+		movie.Certification = certification
+	}
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaPool round-robins reads across the read replicas that are reachable and caught up
+type ReplicaPool struct {
+	replicas []*replica
+	next uint32
+	// これより遅れているreplicaは使わない
+	maxLag time.Duration
+}
+
+type replica struct {
+	db *sql.DB
+	// 1なら読み込みに使える(Checkで更新する)
+	healthy int32
+}
+
+// NewReplicaPool returns a pool of replicas; every replica is unused until Check finds it healthy
+func NewReplicaPool(dbs []*sql.DB, maxLag time.Duration) *ReplicaPool {
+	p := &ReplicaPool{maxLag: maxLag}
+	for _, db := range dbs {
+		p.replicas = append(p.replicas, &replica{db: db})
+	}
+	return p
+}
+
+// 受信したWALをすべて適用済みなら0、そうでなければ最後に適用したトランザクションからの秒数
+// (replicaでない場合は0)
+// WAL receiverが切れていると受信も適用も止まってLSNが一致するので、streamingでなければnullにする
+// (pg_stat_wal_receiverのstatusはsuperuserかpg_read_all_statsのroleにしか見えないので、replicaの接続ユーザーに必要)
+const replicaLagQuery = `select case
+					when not pg_is_in_recovery() then 0
+					when not exists (select 1 from pg_stat_wal_receiver where status = 'streaming') then null
+					when pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() then 0
+					else coalesce(extract(epoch from now() - pg_last_xact_replay_timestamp()), 0)
+				end`
+
+// Check pings every replica and marks the ones that answer and are within maxLag as healthy
+func (p *ReplicaPool) Check(ctx context.Context) {
+	for _, r := range p.replicas {
+		healthy := int32(0)
+
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		var lag sql.NullFloat64
+		err := r.db.QueryRowContext(ctx, replicaLagQuery).Scan(&lag)
+		cancel()
+		if err == nil && lag.Valid && time.Duration(lag.Float64*float64(time.Second)) <= p.maxLag {
+			healthy = 1
+		}
+
+		atomic.StoreInt32(&r.healthy, healthy)
+	}
+}
+
+// Run calls Check every interval until ctx is done
+func (p *ReplicaPool) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Healthy returns the number of replicas reads can currently go to
+func (p *ReplicaPool) Healthy() int {
+	n := 0
+	for _, r := range p.replicas {
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			n++
+		}
+	}
+	return n
+}
+
+// 使えるreplicaを順番に返す(ない場合はnil)
+func (p *ReplicaPool) pick() *sql.DB {
+	var healthy []*sql.DB
+	for _, r := range p.replicas {
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			healthy = append(healthy, r.db)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	n := atomic.AddUint32(&p.next, 1)
+	return healthy[int(n%uint32(len(healthy)))]
+}
+
+type readerContextKey struct{}
+
+// 読み込みだけのメソッドの接続を選んでctxに入れる(loadGenresなども同じ接続を使う)
+func (m *DBModel) reader(ctx context.Context) context.Context {
+	if m.Replicas == nil {
+		return ctx
+	}
+	db := m.Replicas.pick()
+	if db == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, readerContextKey{}, db)
+}
+
+// readerで選んだ接続(ない場合はprimary)
+func (m *DBModel) conn(ctx context.Context) *sql.DB {
+	if db, ok := ctx.Value(readerContextKey{}).(*sql.DB); ok {
+		return db
+	}
+	return m.DB
+}
+
+// Primary returns a store that sends every query to the primary (to read a write that was just made)
+func (m *DBModel) Primary() MovieStore {
+	return &DBModel{DB: m.DB, dialect: m.dialect}
+}
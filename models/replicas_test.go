@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestReplicaRouting(t *testing.T) {
+	primary := &fakeConnector{movies: 1}
+	replicas := []*fakeConnector{{movies: 1}, {movies: 1}, {movies: 1, lag: 30}}
+
+	var dbs []*sql.DB
+	for _, c := range replicas {
+		dbs = append(dbs, sql.OpenDB(c))
+	}
+	pool := NewReplicaPool(dbs, 5*time.Second)
+	m := &DBModel{DB: sql.OpenDB(primary), Replicas: pool}
+
+	reset := func() {
+		primary.queries = 0
+		for _, c := range replicas {
+			c.queries = 0
+		}
+	}
+
+	// Checkの前はどのreplicaも使わない
+	_, err := m.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary.queries != 2 {
+		t.Errorf("before Check: primary ran %d queries, want 2", primary.queries)
+	}
+
+	pool.Check(context.Background())
+	if pool.Healthy() != 2 {
+		t.Fatalf("healthy replicas = %d, want 2 (the third one lags)", pool.Healthy())
+	}
+
+	// movieとgenresのクエリは同じreplicaに送り、Getごとに次のreplicaを使う
+	reset()
+	for i := 0; i < 4; i++ {
+		_, err := m.Get(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if primary.queries != 0 || replicas[0].queries != 4 || replicas[1].queries != 4 || replicas[2].queries != 0 {
+		t.Errorf("queries: primary %d, replicas %d %d %d, want 0, 4 4 0",
+			primary.queries, replicas[0].queries, replicas[1].queries, replicas[2].queries)
+	}
+
+	// Primaryは書き込んだ直後の読み込みに使う
+	reset()
+	_, err = m.Primary().Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary.queries != 2 {
+		t.Errorf("Primary().Get: primary ran %d queries, want 2", primary.queries)
+	}
+
+	// 接続できなくなったreplicaは外し、すべて使えなければprimaryに戻す
+	replicas[0].down = true
+	pool.Check(context.Background())
+	if pool.Healthy() != 1 {
+		t.Errorf("healthy replicas after one went down = %d, want 1", pool.Healthy())
+	}
+	replicas[1].down = true
+	pool.Check(context.Background())
+	reset()
+	_, err = m.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary.queries != 2 {
+		t.Errorf("all replicas down: primary ran %d queries, want 2", primary.queries)
+	}
+}
+
+func TestReplicaDisconnectedReceiver(t *testing.T) {
+	// WAL receiverが切れたreplicaはLSNが一致していても遅れが分からないので使わない
+	replicas := []*fakeConnector{{movies: 1}, {movies: 1, disconnected: true}}
+
+	var dbs []*sql.DB
+	for _, c := range replicas {
+		dbs = append(dbs, sql.OpenDB(c))
+	}
+	pool := NewReplicaPool(dbs, 5*time.Second)
+
+	pool.Check(context.Background())
+	if pool.Healthy() != 1 {
+		t.Fatalf("healthy replicas = %d, want 1 (the second one is disconnected)", pool.Healthy())
+	}
+
+	// 再接続すれば戻す
+	replicas[1].disconnected = false
+	pool.Check(context.Background())
+	if pool.Healthy() != 2 {
+		t.Errorf("healthy replicas after reconnecting = %d, want 2", pool.Healthy())
+	}
+}
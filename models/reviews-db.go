@@ -0,0 +1,310 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const reviewColumns = `id, movie_id, user_id, score, body, helpful_count, created_at, updated_at`
+
+func scanReview(scan func(dest ...interface{}) error) (*Review, error) {
+	var r Review
+	err := scan(
+		&r.ID,
+		&r.MovieID,
+		&r.UserID,
+		&r.Score,
+		&r.Body,
+		&r.HelpfulCount,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// movieの行をロックする(同じmovieのレビューの書き込みを直列にして集計がずれないようにする)
+func (m *DBModel) lockMovie(ctx context.Context, tx *sql.Tx, movieID int) error {
+	var exists bool
+	return tx.QueryRowContext(ctx, `select true from movies where id = $1 and deleted_at is null` + m.dialect.rowLock("for update"), movieID).Scan(&exists)
+}
+
+// moviesのレビューの件数と平均スコアを集計し直す
+func refreshReviewStats(ctx context.Context, tx *sql.Tx, movieID int) error {
+	stmt := `update movies set
+						review_count = (select count(*) from movie_reviews where movie_id = $1),
+						review_score = coalesce((select round(avg(score), 2) from movie_reviews where movie_id = $1), 0)
+					where id = $1`
+	_, err := tx.ExecContext(ctx, stmt, movieID)
+	return err
+}
+
+// movieのレビューをfilterの順にページ分割して返すメソッド
+func (m *DBModel) Reviews(movieID int, filter ReviewFilter) (*ReviewPage, error) {
+	err := filter.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err = m.DB.QueryRowContext(ctx, `select true from movies where id = $1 and deleted_at is null`, movieID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	sort := filter.sort()
+	limit := filter.limit()
+	args := []interface{}{movieID}
+
+	cursor := ""
+	orderBy := "order by id desc"
+	if sort == ReviewSortHelpful {
+		orderBy = "order by helpful_count desc, id desc"
+	}
+
+	if filter.Cursor != "" {
+		c, err := decodeReviewCursor(filter.Cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		if sort == ReviewSortHelpful {
+			args = append(args, c.Helpful, c.ID)
+			cursor = "and (helpful_count < $2 or (helpful_count = $2 and id < $3))"
+		} else {
+			args = append(args, c.ID)
+			cursor = "and id < $2"
+		}
+	}
+
+	args = append(args, limit+1)
+	query := fmt.Sprintf(`select %s from movie_reviews where movie_id = $1 %s %s limit $%d`, reviewColumns, cursor, orderBy, len(args))
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []*Review
+	for rows.Next() {
+		r, err := scanReview(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newReviewPage(reviews, limit, sort), nil
+}
+
+func (m *DBModel) GetReview(id int) (*Review, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select ` + reviewColumns + ` from movie_reviews where id = $1`
+
+	return scanReview(m.DB.QueryRowContext(ctx, query, id).Scan)
+}
+
+// レビューを追加して新しいidを返す(1人1movieにつき1件まで)
+func (m *DBModel) InsertReview(review Review) (int, error) {
+	err := review.Validate()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = m.lockMovie(ctx, tx, review.MovieID)
+	if err != nil {
+		return 0, err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `select exists(select 1 from movie_reviews where movie_id = $1 and user_id = $2)`,
+		review.MovieID, review.UserID).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, ErrDuplicateReview
+	}
+
+	stmt := `insert into movie_reviews (movie_id, user_id, score, body, created_at, updated_at)
+						values ($1, $2, $3, $4, $5, $6) returning id`
+
+	var id int
+	err = tx.QueryRowContext(ctx, stmt,
+		review.MovieID,
+		review.UserID,
+		review.Score,
+		review.Body,
+		review.CreatedAt,
+		review.UpdatedAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = refreshReviewStats(ctx, tx, review.MovieID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// 書き込む前に、レビューが存在してuserIDのものであることを確認してmovieをロックする
+func (m *DBModel) reviewForWrite(ctx context.Context, tx *sql.Tx, id int, userID int) (int, error) {
+	var movieID, owner int
+	err := tx.QueryRowContext(ctx, `select movie_id, user_id from movie_reviews where id = $1`, id).Scan(&movieID, &owner)
+	if err != nil {
+		return 0, err
+	}
+	if owner != userID {
+		return 0, ErrReviewNotOwned
+	}
+
+	err = m.lockMovie(ctx, tx, movieID)
+	if err != nil {
+		return 0, err
+	}
+
+	return movieID, nil
+}
+
+// レビューのスコアと本文を更新する(本人のみ)
+func (m *DBModel) UpdateReview(review Review) error {
+	err := review.Validate()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	movieID, err := m.reviewForWrite(ctx, tx, review.ID, review.UserID)
+	if err != nil {
+		return err
+	}
+
+	stmt := `update movie_reviews set score = $1, body = $2, updated_at = $3 where id = $4`
+
+	_, err = tx.ExecContext(ctx, stmt, review.Score, review.Body, review.UpdatedAt, review.ID)
+	if err != nil {
+		return err
+	}
+
+	err = refreshReviewStats(ctx, tx, movieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// レビューを削除する(本人のみ)
+func (m *DBModel) DeleteReview(id int, userID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	movieID, err := m.reviewForWrite(ctx, tx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from movie_reviews where id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	err = refreshReviewStats(ctx, tx, movieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// レビューに「参考になった」の票を入れる(helpfulがfalseなら取り消す)
+func (m *DBModel) VoteReview(id int, userID int, helpful bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var owner int
+	err = tx.QueryRowContext(ctx, `select user_id from movie_reviews where id = $1` + m.dialect.rowLock("for update"), id).Scan(&owner)
+	if err != nil {
+		return err
+	}
+	if owner == userID {
+		return ErrOwnReviewVote
+	}
+
+	stmt := `insert into movie_review_votes (review_id, user_id, created_at) values ($1, $2, $3) on conflict do nothing`
+	delta := 1
+	args := []interface{}{id, userID, time.Now()}
+	if !helpful {
+		stmt = `delete from movie_review_votes where review_id = $1 and user_id = $2`
+		delta = -1
+		args = args[:2]
+	}
+
+	res, err := tx.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	// 同じ票を2回入れても(取り消しても)件数は変えない
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		_, err = tx.ExecContext(ctx, `update movie_reviews set helpful_count = helpful_count + $1 where id = $2`, delta, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
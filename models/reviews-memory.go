@@ -0,0 +1,221 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// レビューへの票(review id, user id)
+type reviewVote struct {
+	ReviewID int
+	UserID int
+}
+
+// moviesのレビューの件数と平均スコアを集計し直す(ロックを取得してから呼ぶ)
+func (m *MemoryModel) refreshReviewStats(movieID int) {
+	movie, ok := m.movies[movieID]
+	if !ok {
+		return
+	}
+
+	total, count := 0, 0
+	for _, r := range m.reviews {
+		if r.MovieID == movieID {
+			total += r.Score
+			count++
+		}
+	}
+
+	movie.ReviewCount = count
+	movie.ReviewScore = averageScore(total, count)
+	m.movies[movieID] = movie
+}
+
+func (m *MemoryModel) Reviews(movieID int, filter ReviewFilter) (*ReviewPage, error) {
+	err := filter.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	movie, ok := m.movies[movieID]
+	if !ok || movie.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	sortBy := filter.sort()
+
+	var after *reviewCursor
+	if filter.Cursor != "" {
+		after, err = decodeReviewCursor(filter.Cursor, sortBy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// (helpful_count,) idの降順で比較する
+	before := func(a *Review, helpful, id int) bool {
+		if sortBy == ReviewSortHelpful && a.HelpfulCount != helpful {
+			return a.HelpfulCount > helpful
+		}
+		return a.ID > id
+	}
+
+	var reviews []*Review
+	for _, r := range m.reviews {
+		if r.MovieID != movieID {
+			continue
+		}
+		r := r
+		if after != nil && !before(&Review{ID: after.ID, HelpfulCount: after.Helpful}, r.HelpfulCount, r.ID) {
+			continue
+		}
+		reviews = append(reviews, &r)
+	}
+
+	sort.Slice(reviews, func(i, j int) bool {
+		return before(reviews[i], reviews[j].HelpfulCount, reviews[j].ID)
+	})
+
+	limit := filter.limit()
+	if len(reviews) > limit+1 {
+		reviews = reviews[:limit+1]
+	}
+
+	return newReviewPage(reviews, limit, sortBy), nil
+}
+
+func (m *MemoryModel) GetReview(id int) (*Review, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	r, ok := m.reviews[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return &r, nil
+}
+
+func (m *MemoryModel) InsertReview(review Review) (int, error) {
+	err := review.Validate()
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[review.MovieID]
+	if !ok || movie.DeletedAt != nil {
+		return 0, sql.ErrNoRows
+	}
+
+	for _, r := range m.reviews {
+		if r.MovieID == review.MovieID && r.UserID == review.UserID {
+			return 0, ErrDuplicateReview
+		}
+	}
+
+	review.ID = m.nextReviewID
+	review.HelpfulCount = 0
+	m.nextReviewID++
+	m.reviews[review.ID] = review
+
+	m.refreshReviewStats(review.MovieID)
+
+	return review.ID, nil
+}
+
+// レビューが存在してuserIDのものであることを確認する(ロックを取得してから呼ぶ)
+func (m *MemoryModel) reviewForWrite(id int, userID int) (Review, error) {
+	r, ok := m.reviews[id]
+	if !ok {
+		return r, sql.ErrNoRows
+	}
+	if r.UserID != userID {
+		return r, ErrReviewNotOwned
+	}
+	return r, nil
+}
+
+func (m *MemoryModel) UpdateReview(review Review) error {
+	err := review.Validate()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, err := m.reviewForWrite(review.ID, review.UserID)
+	if err != nil {
+		return err
+	}
+
+	current.Score = review.Score
+	current.Body = review.Body
+	current.UpdatedAt = review.UpdatedAt
+	m.reviews[review.ID] = current
+
+	m.refreshReviewStats(current.MovieID)
+
+	return nil
+}
+
+func (m *MemoryModel) DeleteReview(id int, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, err := m.reviewForWrite(id, userID)
+	if err != nil {
+		return err
+	}
+
+	delete(m.reviews, id)
+	m.deleteReviewVotes(id)
+
+	m.refreshReviewStats(current.MovieID)
+
+	return nil
+}
+
+// レビューへの票を削除する(ロックを取得してから呼ぶ)
+func (m *MemoryModel) deleteReviewVotes(reviewID int) {
+	for v := range m.reviewVotes {
+		if v.ReviewID == reviewID {
+			delete(m.reviewVotes, v)
+		}
+	}
+}
+
+func (m *MemoryModel) VoteReview(id int, userID int, helpful bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.reviews[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if r.UserID == userID {
+		return ErrOwnReviewVote
+	}
+
+	vote := reviewVote{ReviewID: id, UserID: userID}
+	if helpful == m.reviewVotes[vote] {
+		return nil
+	}
+
+	if helpful {
+		m.reviewVotes[vote] = true
+		r.HelpfulCount++
+	} else {
+		delete(m.reviewVotes, vote)
+		r.HelpfulCount--
+	}
+	m.reviews[id] = r
+
+	return nil
+}
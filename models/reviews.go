@@ -0,0 +1,135 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	// ReviewSortNewest lists the most recent reviews first
+	ReviewSortNewest = "newest"
+	// ReviewSortHelpful lists the reviews with the most helpful votes first
+	ReviewSortHelpful = "helpful"
+)
+
+var (
+	// ErrDuplicateReview is returned when the user has already reviewed the movie
+	ErrDuplicateReview = errors.New("you have already reviewed this movie")
+	// ErrReviewNotOwned is returned when a user changes someone else's review
+	ErrReviewNotOwned = errors.New("you can only change your own review")
+	// ErrOwnReviewVote is returned when a user votes for their own review
+	ErrOwnReviewVote = errors.New("you cannot vote for your own review")
+)
+
+// Review is one user's score and text for a movie
+type Review struct {
+	ID int `json:"id"`
+	MovieID int `json:"movie_id"`
+	UserID int `json:"user_id"`
+	Score int `json:"score"`
+	Body string `json:"body"`
+	HelpfulCount int `json:"helpful_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReviewFilter holds the sorting and paging options for listing reviews
+type ReviewFilter struct {
+	// "newest"または"helpful"
+	Sort string
+	Limit int
+	Cursor string
+}
+
+// ReviewPage is one page of reviews
+type ReviewPage struct {
+	Reviews []*Review `json:"reviews"`
+	NextCursor string `json:"next_cursor"`
+	HasMore bool `json:"has_more"`
+}
+
+// Validate checks the review before it is saved
+func (r Review) Validate() error {
+	if r.Score < 1 || r.Score > 5 {
+		return errors.New("score must be between 1 and 5")
+	}
+	if len(r.Body) > 10000 {
+		return errors.New("body must not be longer than 10000 bytes")
+	}
+	return nil
+}
+
+func (f ReviewFilter) sort() string {
+	if f.Sort == "" {
+		return ReviewSortNewest
+	}
+	return f.Sort
+}
+
+// Validate checks the sort option
+func (f ReviewFilter) Validate() error {
+	switch f.sort() {
+	case ReviewSortNewest, ReviewSortHelpful:
+		return nil
+	}
+	return errors.New("sort must be newest or helpful")
+}
+
+func (f ReviewFilter) limit() int {
+	return MovieFilter{Limit: f.Limit}.limit()
+}
+
+// カーソルの中身(idは作成順なので、newestはidだけで並べられる)
+type reviewCursor struct {
+	Sort string `json:"s"`
+	Helpful int `json:"h,omitempty"`
+	ID int `json:"id"`
+}
+
+func encodeReviewCursor(review *Review, sort string) string {
+	js, _ := json.Marshal(reviewCursor{Sort: sort, Helpful: review.HelpfulCount, ID: review.ID})
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeReviewCursor(cursor string, sort string) (*reviewCursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c reviewCursor
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.ID <= 0 || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// limit+1件取得した結果からページを組み立てる
+func newReviewPage(reviews []*Review, limit int, sort string) *ReviewPage {
+	page := &ReviewPage{Reviews: reviews}
+
+	if len(reviews) > limit {
+		page.Reviews = reviews[:limit]
+		page.HasMore = true
+		page.NextCursor = encodeReviewCursor(page.Reviews[limit-1], sort)
+	}
+
+	if page.Reviews == nil {
+		page.Reviews = []*Review{}
+	}
+
+	return page
+}
+
+// 平均スコアを小数点以下2桁に丸める(DBのnumeric(3, 2)に合わせる)
+func averageScore(total, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return math.Round(float64(total)/float64(count)*100) / 100
+}
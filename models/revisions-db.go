@@ -0,0 +1,198 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// トランザクションの中でmovieの現在の状態を読み込む(ゴミ箱のmovieも含む)
+func movieSnapshotTx(ctx context.Context, tx *sql.Tx, id int) (MovieSnapshot, error) {
+	query := `select title, description, year, release_date, runtime, rating, mpaa_rating, coalesce(poster, ''), deleted_at
+							from movies where id = $1`
+
+	var movie Movie
+	var deletedAt sql.NullTime
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&movie.Title,
+		&movie.Description,
+		&movie.Year,
+		&movie.ReleaseDate,
+		&movie.Runtime,
+		&movie.Rating,
+		&movie.MPAARating,
+		&movie.Poster,
+		&deletedAt,
+	)
+	if err != nil {
+		return MovieSnapshot{}, err
+	}
+	if deletedAt.Valid {
+		movie.DeletedAt = &deletedAt.Time
+	}
+
+	rows, err := tx.QueryContext(ctx, `select genre_id from movies_genres where movie_id = $1`, id)
+	if err != nil {
+		return MovieSnapshot{}, err
+	}
+	defer rows.Close()
+
+	movie.GenreIDs = []int{}
+	for rows.Next() {
+		var genreID int
+		err := rows.Scan(&genreID)
+		if err != nil {
+			return MovieSnapshot{}, err
+		}
+		movie.GenreIDs = append(movie.GenreIDs, genreID)
+	}
+	if err = rows.Err(); err != nil {
+		return MovieSnapshot{}, err
+	}
+	rows.Close()
+
+	rows, err = tx.QueryContext(ctx, `select t.name from movie_tags mt join tags t on t.id = mt.tag_id where mt.movie_id = $1`, id)
+	if err != nil {
+		return MovieSnapshot{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return MovieSnapshot{}, err
+		}
+		movie.TagNames = append(movie.TagNames, name)
+	}
+	if err = rows.Err(); err != nil {
+		return MovieSnapshot{}, err
+	}
+
+	return snapshotOf(&movie), nil
+}
+
+// 変更後のmovieの状態を履歴に記録する
+func insertRevision(ctx context.Context, tx *sql.Tx, movieID int, action string, userID int) error {
+	snapshot, err := movieSnapshotTx(ctx, tx, movieID)
+	if err != nil {
+		return err
+	}
+
+	js, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	stmt := `insert into movie_revisions (movie_id, action, snapshot, user_id, created_at) values ($1, $2, $3, $4, $5)`
+
+	_, err = tx.ExecContext(ctx, stmt, movieID, action, string(js), userID, time.Now())
+	return err
+}
+
+func scanRevision(scan func(dest ...interface{}) error) (*Revision, error) {
+	var r Revision
+	var snapshot []byte
+	err := scan(
+		&r.ID,
+		&r.MovieID,
+		&r.Action,
+		&snapshot,
+		&r.UserID,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(snapshot, &r.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// movieの履歴を新しい順に返すメソッド
+func (m *DBModel) Revisions(movieID int) ([]*Revision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select id, movie_id, action, snapshot, user_id, created_at
+							from movie_revisions where movie_id = $1 order by id desc`
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		r, err := scanRevision(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// 指定idの履歴を返すメソッド
+func (m *DBModel) GetRevision(id int) (*Revision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select id, movie_id, action, snapshot, user_id, created_at
+							from movie_revisions where id = $1`
+
+	return scanRevision(m.DB.QueryRowContext(ctx, query, id).Scan)
+}
+
+// movieを指定した履歴の状態に戻す(戻したこと自体も履歴に記録する)
+func (m *DBModel) RevertMovie(movieID int, revisionID int, userID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `select id, movie_id, action, snapshot, user_id, created_at
+							from movie_revisions where id = $1`
+
+	revision, err := scanRevision(tx.QueryRowContext(ctx, query, revisionID).Scan)
+	if err != nil {
+		return err
+	}
+	if revision.MovieID != movieID {
+		return ErrRevisionMismatch
+	}
+
+	// ゴミ箱のmovieは戻せない
+	var version int
+	err = tx.QueryRowContext(ctx, `select version from movies where id = $1 and deleted_at is null` + m.dialect.rowLock("for update"), movieID).Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	movie := Movie{ID: movieID, UpdatedAt: time.Now(), Version: version}
+	revision.Snapshot.apply(&movie)
+
+	err = m.updateMovieTx(ctx, tx, movie)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(ctx, tx, movieID, RevisionRevert, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 変更後のmovieの状態を履歴に記録する(ロックを取得してから呼ぶ)
+func (m *MemoryModel) recordRevision(movieID int, action string, userID int) {
+	movie := m.copyMovie(m.movies[movieID])
+	for _, id := range m.movieTags[movieID] {
+		movie.TagNames = append(movie.TagNames, m.tags[id].Name)
+	}
+
+	m.revisions = append(m.revisions, Revision{
+		ID: m.nextRevisionID,
+		MovieID: movieID,
+		Action: action,
+		Snapshot: snapshotOf(movie),
+		UserID: userID,
+		CreatedAt: time.Now(),
+	})
+	m.nextRevisionID++
+}
+
+func (m *MemoryModel) Revisions(movieID int) ([]*Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revisions := []*Revision{}
+
+	// 新しい順に返す
+	for i := len(m.revisions) - 1; i >= 0; i-- {
+		if m.revisions[i].MovieID == movieID {
+			r := m.revisions[i]
+			revisions = append(revisions, &r)
+		}
+	}
+
+	return revisions, nil
+}
+
+func (m *MemoryModel) GetRevision(id int) (*Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.revisions {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryModel) RevertMovie(movieID int, revisionID int, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var revision *Revision
+	for i := range m.revisions {
+		if m.revisions[i].ID == revisionID {
+			revision = &m.revisions[i]
+			break
+		}
+	}
+	if revision == nil {
+		return sql.ErrNoRows
+	}
+	if revision.MovieID != movieID {
+		return ErrRevisionMismatch
+	}
+
+	// ゴミ箱のmovieは戻せない
+	current, ok := m.movies[movieID]
+	if !ok || current.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+
+	movie := current
+	movie.UpdatedAt = time.Now()
+	revision.Snapshot.apply(&movie)
+
+	err := m.updateMovie(movie)
+	if err != nil {
+		return err
+	}
+
+	m.recordRevision(movieID, RevisionRevert, userID)
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// 履歴に記録する操作
+const (
+	RevisionInsert = "insert"
+	RevisionUpdate = "update"
+	RevisionDelete = "delete"
+	RevisionRestore = "restore"
+	RevisionRevert = "revert"
+)
+
+// ErrRevisionMismatch is returned when a revision does not belong to the movie
+var ErrRevisionMismatch = errors.New("revision does not belong to this movie")
+
+// MovieSnapshot is the full state of a movie recorded in a revision
+type MovieSnapshot struct {
+	Title string `json:"title"`
+	Description string `json:"description"`
+	Year int `json:"year"`
+	ReleaseDate time.Time `json:"release_date"`
+	Runtime int `json:"runtime"`
+	Rating int `json:"rating"`
+	MPAARating string `json:"mpaa_rating"`
+	GenreIDs []int `json:"genre_ids"`
+	// タグ名(タグを記録する前の履歴ではnil)
+	Tags []string `json:"tags"`
+	// ポスターのキー。差し替えた画像はストレージから消すので、RevertMovieでは戻さない
+	Poster string `json:"poster"`
+	Deleted bool `json:"deleted"`
+}
+
+// Revision is an immutable record of one change to a movie
+type Revision struct {
+	ID int `json:"id"`
+	MovieID int `json:"movie_id"`
+	Action string `json:"action"`
+	Snapshot MovieSnapshot `json:"snapshot"`
+	UserID int `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RevisionChange is one field that differs between two revisions
+type RevisionChange struct {
+	Field string `json:"field"`
+	From interface{} `json:"from"`
+	To interface{} `json:"to"`
+}
+
+func snapshotOf(movie *Movie) MovieSnapshot {
+	genreIDs := append([]int{}, movie.GenreIDs...)
+	sort.Ints(genreIDs)
+	tags := append([]string{}, movie.TagNames...)
+	sort.Strings(tags)
+
+	return MovieSnapshot{
+		Title: movie.Title,
+		Description: movie.Description,
+		Year: movie.Year,
+		ReleaseDate: movie.ReleaseDate,
+		Runtime: movie.Runtime,
+		Rating: movie.Rating,
+		MPAARating: movie.MPAARating,
+		GenreIDs: genreIDs,
+		Tags: tags,
+		Poster: movie.Poster,
+		Deleted: movie.DeletedAt != nil,
+	}
+}
+
+// apply copies the snapshot's fields onto the movie
+func (s MovieSnapshot) apply(movie *Movie) {
+	movie.Title = s.Title
+	movie.Description = s.Description
+	movie.Year = s.Year
+	movie.ReleaseDate = s.ReleaseDate
+	movie.Runtime = s.Runtime
+	movie.Rating = s.Rating
+	movie.MPAARating = s.MPAARating
+	movie.GenreIDs = append([]int{}, s.GenreIDs...)
+	// タグを記録していない古い履歴では今のタグを残す
+	if s.Tags != nil {
+		movie.TagNames = append([]string{}, s.Tags...)
+	}
+}
+
+// DiffRevisions lists the snapshot fields that changed from a to b
+func DiffRevisions(a, b *Revision) []RevisionChange {
+	// JSONのキーごとに比較する
+	var from, to map[string]interface{}
+	js, _ := json.Marshal(a.Snapshot)
+	json.Unmarshal(js, &from)
+	js, _ = json.Marshal(b.Snapshot)
+	json.Unmarshal(js, &to)
+
+	var fields []string
+	for field := range from {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	changes := []RevisionChange{}
+	for _, field := range fields {
+		// 片方の履歴にしかない項目(あとから記録するようになったもの)は比べられない
+		if _, ok := to[field]; !ok {
+			continue
+		}
+		f, _ := json.Marshal(from[field])
+		t, _ := json.Marshal(to[field])
+		if string(f) != string(t) {
+			changes = append(changes, RevisionChange{Field: field, From: from[field], To: to[field]})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ts_headlineのオプション
+const headlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// FTS5のhighlightとsnippetで一致した部分を囲む文字(エスケープしたあとに<b></b>にする)
+const (
+	ftsStartSel = "\x01"
+	ftsStopSel = "\x02"
+)
+
+// html.EscapeStringと同じようにエスケープするSQLの式(ts_headlineでタグとして扱われないようにする)
+func htmlEscapeSQL(expr string) string {
+	return fmt.Sprintf(`replace(replace(replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`, expr)
+}
+
+// titleとdescriptionを全文検索してrank順に返すメソッド
+func (m *DBModel) Search(filter SearchFilter) (*SearchPage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	ctx = m.reader(ctx)
+
+	if strings.TrimSpace(filter.Query) == "" {
+		return nil, ErrEmptySearch
+	}
+
+	args := []interface{}{filter.Query}
+	if m.dialect == dialectSQLite {
+		args[0] = ftsQuery(filter.Query)
+		if args[0] == "" {
+			return nil, ErrEmptySearch
+		}
+	}
+
+	cursor := ""
+	if filter.Cursor != "" {
+		c, err := decodeSearchCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, c.Rank, c.ID)
+		cursor = "and (r.rank < $2 or (r.rank = $2 and r.id > $3))"
+	}
+
+	limit := filter.limit()
+	args = append(args, limit+1)
+
+	// rankで絞り込んでから、返す行だけハイライトをつくる
+	query := searchQuery(m.dialect, cursor, len(args))
+
+	rows, err := m.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*SearchResult
+
+	for rows.Next() {
+		var movie Movie
+		var result SearchResult
+		err := rows.Scan(
+			&movie.ID,
+			&movie.Title,
+			&movie.Description,
+			&movie.Year,
+			&movie.ReleaseDate,
+			&movie.Runtime,
+			&movie.Rating,
+			&movie.MPAARating,
+			&movie.CreatedAt,
+			&movie.UpdatedAt,
+			&movie.Version,
+			&movie.ReviewCount,
+			&movie.ReviewScore,
+			&movie.Poster,
+			&result.Rank,
+			&result.TitleHighlight,
+			&result.Snippet,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if m.dialect == dialectSQLite {
+			result.TitleHighlight = ftsHighlightHTML(result.TitleHighlight)
+			result.Snippet = ftsHighlightHTML(result.Snippet)
+		}
+		result.Movie = &movie
+		results = append(results, &result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	movies := make([]*Movie, len(results))
+	for i, result := range results {
+		movies[i] = result.Movie
+	}
+	err = m.loadGenresBatch(ctx, movies)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSearchPage(results, limit), nil
+}
+
+// 検索のクエリ(cursorはrankとidの条件、limitArgはlimitのプレースホルダの番号)
+func searchQuery(d dialect, cursor string, limitArg int) string {
+	if d == dialectSQLite {
+		// movies_ftsのbm25は小さいほど近いので、符号を反転してrankにする(titleの重み1、descriptionの重み0.4)
+		return fmt.Sprintf(`
+			with ranked as (
+				select
+					r.id, r.rank, r.title_highlight, r.snippet
+				from
+					(select movies_fts.rowid as id, -bm25(movies_fts, 1.0, 0.4) as rank,
+							highlight(movies_fts, 0, char(1), char(2)) as title_highlight,
+							snippet(movies_fts, 1, char(1), char(2), '...', 35) as snippet
+						from movies_fts join movies on (movies.id = movies_fts.rowid)
+						where movies_fts match $1 and movies.deleted_at is null) r
+				where
+					true %s
+				order by
+					r.rank desc, r.id
+				limit $%d
+			)
+			select
+				m.id, m.title, m.description, m.year, m.release_date, m.runtime, m.rating, m.mpaa_rating,
+				m.created_at, m.updated_at, m.version, m.review_count, m.review_score, coalesce(m.poster, ''), ranked.rank,
+				ranked.title_highlight,
+				ranked.snippet
+			from
+				ranked
+				join movies m on (m.id = ranked.id)
+			order by
+				ranked.rank desc, m.id`, cursor, limitArg)
+	}
+
+	return fmt.Sprintf(`
+		with q as (select websearch_to_tsquery('english', $1) as query),
+		ranked as (
+			select
+				r.id, r.rank
+			from
+				(select id, ts_rank(search_vector, q.query)::float8 as rank
+					from movies, q where search_vector @@ q.query and deleted_at is null) r
+			where
+				true %s
+			order by
+				r.rank desc, r.id
+			limit $%d
+		)
+		select
+			m.id, m.title, m.description, m.year, m.release_date, m.runtime, m.rating, m.mpaa_rating,
+			m.created_at, m.updated_at, m.version, m.review_count, m.review_score, coalesce(m.poster, ''), ranked.rank,
+			ts_headline('english', %s, q.query, '%s'),
+			ts_headline('english', %s, q.query, '%s')
+		from
+			ranked
+			join movies m on (m.id = ranked.id)
+			cross join q
+		order by
+			ranked.rank desc, m.id`, cursor, limitArg, htmlEscapeSQL("m.title"), headlineOptions, htmlEscapeSQL("m.description"), headlineOptions)
+}
+
+// 検索語をFTS5のクエリにする(記号はFTS5の構文になるので、単語ごとに引用符で囲んでANDにする)
+func ftsQuery(query string) string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		// "batman's"のsのような1文字は、Postgresと同じように検索語にしない
+		runes := []rune(word)
+		if len(runes) == 1 && unicode.IsLetter(runes[0]) {
+			continue
+		}
+		terms = append(terms, `"`+word+`"`)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// FTS5のハイライトをエスケープして、一致した部分を<b></b>で囲む
+func ftsHighlightHTML(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, ftsStartSel, "<b>")
+	return strings.ReplaceAll(s, ftsStopSel, "</b>")
+}
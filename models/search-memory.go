@@ -0,0 +1,145 @@
+package models
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// スニペットに含める単語数
+const snippetWords = 35
+
+// 英数字以外で区切って小文字の単語にする
+func searchTerms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// 単語が検索語のいずれかに一致するか(前方一致で語形変化をおおまかに吸収する)
+func matchesTerm(word string, terms []string) bool {
+	word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+	for _, term := range terms {
+		if word != "" && strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// 一致した単語を<b></b>で囲む(HTMLとして表示できるように単語はエスケープする)
+func highlight(s string, terms []string, maxWords int) string {
+	words := strings.Fields(s)
+
+	start := 0
+	if maxWords > 0 && len(words) > maxWords {
+		for i, w := range words {
+			if matchesTerm(w, terms) {
+				start = i - 3
+				break
+			}
+		}
+		if start < 0 {
+			start = 0
+		}
+		if start+maxWords > len(words) {
+			start = len(words) - maxWords
+		}
+		words = words[start : start+maxWords]
+	}
+
+	out := make([]string, len(words))
+	for i, w := range words {
+		if matchesTerm(w, terms) {
+			out[i] = "<b>" + html.EscapeString(w) + "</b>"
+		} else {
+			out[i] = html.EscapeString(w)
+		}
+	}
+
+	return strings.Join(out, " ")
+}
+
+func (m *MemoryModel) Search(filter SearchFilter) (*SearchPage, error) {
+	terms := searchTerms(filter.Query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearch
+	}
+
+	var after *searchCursor
+	if filter.Cursor != "" {
+		c, err := decodeSearchCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = c
+	}
+
+	all, err := m.All()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*SearchResult
+
+	for _, movie := range all {
+		titleWords := searchTerms(movie.Title)
+		descWords := searchTerms(movie.Description)
+
+		// すべての検索語がtitleかdescriptionに含まれるものだけを返す
+		rank := 0.0
+		matched := true
+		for _, term := range terms {
+			hits := 0.0
+			for _, w := range titleWords {
+				if strings.HasPrefix(w, term) {
+					hits += 1.0
+				}
+			}
+			for _, w := range descWords {
+				if strings.HasPrefix(w, term) {
+					hits += 0.4
+				}
+			}
+			if hits == 0 {
+				matched = false
+				break
+			}
+			rank += hits
+		}
+		if !matched {
+			continue
+		}
+
+		results = append(results, &SearchResult{
+			Movie: movie,
+			Rank: rank,
+			TitleHighlight: highlight(movie.Title, terms, 0),
+			Snippet: highlight(movie.Description, terms, snippetWords),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Movie.ID < results[j].Movie.ID
+	})
+
+	limit := filter.limit()
+	var page []*SearchResult
+	for _, result := range results {
+		if after != nil && (result.Rank > after.Rank || (result.Rank == after.Rank && result.Movie.ID <= after.ID)) {
+			continue
+		}
+		page = append(page, result)
+		if len(page) > limit {
+			break
+		}
+	}
+
+	return newSearchPage(page, limit), nil
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrEmptySearch is returned when the search query has no terms
+var ErrEmptySearch = errors.New("search query must not be empty")
+
+// SearchFilter holds the query and paging options for a full-text search
+type SearchFilter struct {
+	Query string
+	Limit int
+	Cursor string
+}
+
+// SearchResult is one movie matched by a full-text search
+type SearchResult struct {
+	Movie *Movie `json:"movie"`
+	Rank float64 `json:"rank"`
+	// 一致した単語を<b></b>で囲んだHTML(それ以外の文字はエスケープ済み)
+	// langを指定しても翻訳前の元のtitleとdescriptionからつくる
+	TitleHighlight string `json:"title_highlight"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchPage is one page of search results ordered by rank
+type SearchPage struct {
+	Results []*SearchResult `json:"results"`
+	NextCursor string `json:"next_cursor"`
+	HasMore bool `json:"has_more"`
+}
+
+// 検索結果のカーソル(rankとidの組)
+type searchCursor struct {
+	Rank float64
+	ID int
+}
+
+// 検索語をsearch_vectorの式にする(titleの重みA、descriptionの重みB)
+func searchVectorSQL(title, description string) string {
+	return fmt.Sprintf(`setweight(to_tsvector('english', coalesce(%s, '')), 'A') || setweight(to_tsvector('english', coalesce(%s, '')), 'B')`, title, description)
+}
+
+func (f SearchFilter) limit() int {
+	return MovieFilter{Limit: f.Limit}.limit()
+}
+
+func encodeSearchCursor(result *SearchResult) string {
+	value, _ := json.Marshal(result.Rank)
+	js, _ := json.Marshal(movieCursor{Sort: "rank", Value: value, ID: result.Movie.ID})
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeSearchCursor(cursor string) (*searchCursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c movieCursor
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.ID <= 0 || c.Sort != "rank" {
+		return nil, ErrInvalidCursor
+	}
+
+	var sc searchCursor
+	sc.ID = c.ID
+	err = json.Unmarshal(c.Value, &sc.Rank)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &sc, nil
+}
+
+// limit+1件取得した結果からページを組み立てる
+func newSearchPage(results []*SearchResult, limit int) *SearchPage {
+	page := &SearchPage{Results: results}
+
+	if len(results) > limit {
+		page.Results = results[:limit]
+		page.HasMore = true
+		page.NextCursor = encodeSearchCursor(page.Results[limit-1])
+	}
+
+	if page.Results == nil {
+		page.Results = []*SearchResult{}
+	}
+
+	return page
+}
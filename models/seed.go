@@ -0,0 +1,178 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// 開発用の初期データ
+var seedGenres = []string{
+	"Drama",
+	"Crime",
+	"Action",
+	"Comic Book",
+	"Sci-Fi",
+	"Mystery",
+	"Adventure",
+	"Comedy",
+	"Romance",
+}
+
+type seedMovie struct {
+	Movie Movie
+	Genres []string
+	// billing_orderは並び順
+	Credits []seedCredit
+}
+
+type seedCredit struct {
+	Name string
+	Role string
+	CharacterName string
+}
+
+var seedMovies = []seedMovie{
+	{
+		Movie: Movie{
+			Title: "The Shawshank Redemption",
+			Description: "Two imprisoned men bond over a number of years",
+			ReleaseDate: time.Date(1994, 10, 14, 0, 0, 0, 0, time.UTC),
+			Runtime: 142,
+			Rating: 5,
+			MPAARating: "R",
+		},
+		Genres: []string{"Drama", "Crime"},
+		Credits: []seedCredit{
+			{"Frank Darabont", "director", ""},
+			{"Frank Darabont", "writer", ""},
+			{"Tim Robbins", "actor", "Andy Dufresne"},
+			{"Morgan Freeman", "actor", "Ellis Boyd 'Red' Redding"},
+		},
+	},
+	{
+		Movie: Movie{
+			Title: "The Godfather",
+			Description: "The aging patriarch of an organized crime dynasty transfers control to his son",
+			ReleaseDate: time.Date(1972, 3, 24, 0, 0, 0, 0, time.UTC),
+			Runtime: 175,
+			Rating: 5,
+			MPAARating: "R",
+		},
+		Genres: []string{"Drama", "Crime"},
+		Credits: []seedCredit{
+			{"Francis Ford Coppola", "director", ""},
+			{"Marlon Brando", "actor", "Vito Corleone"},
+			{"Al Pacino", "actor", "Michael Corleone"},
+		},
+	},
+	{
+		Movie: Movie{
+			Title: "The Dark Knight",
+			Description: "The menace known as the Joker wreaks havoc on Gotham City",
+			ReleaseDate: time.Date(2008, 7, 18, 0, 0, 0, 0, time.UTC),
+			Runtime: 152,
+			Rating: 5,
+			MPAARating: "PG-13",
+		},
+		Genres: []string{"Action", "Crime", "Comic Book"},
+		Credits: []seedCredit{
+			{"Christopher Nolan", "director", ""},
+			{"Christian Bale", "actor", "Bruce Wayne"},
+			{"Heath Ledger", "actor", "Joker"},
+		},
+	},
+	{
+		Movie: Movie{
+			Title: "American Psycho",
+			Description: "A wealthy New York investment banking executive hides his alternate psychopathic ego",
+			ReleaseDate: time.Date(2000, 4, 14, 0, 0, 0, 0, time.UTC),
+			Runtime: 102,
+			Rating: 4,
+			MPAARating: "R",
+		},
+		Genres: []string{"Mystery", "Drama"},
+		Credits: []seedCredit{
+			{"Mary Harron", "director", ""},
+			{"Christian Bale", "actor", "Patrick Bateman"},
+		},
+	},
+}
+
+// Seed loads the development data set into the memory store
+func (m *MemoryModel) Seed() error {
+	return seed(m)
+}
+
+// Seed loads the development data set when the database has no genres yet
+func (m *DBModel) Seed() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// 2回目以降の起動では何もしない
+	var count int
+	err := m.DB.QueryRowContext(ctx, `select count(*) from genres`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return seed(m)
+}
+
+// ストアのメソッドで初期データを追加する
+func seed(m MovieStore) error {
+	genreIDs := make(map[string]int)
+	for _, name := range seedGenres {
+		now := time.Now()
+		id, err := m.InsertGenre(Genre{GenreName: name, CreatedAt: now, UpdatedAt: now})
+		if err != nil {
+			return err
+		}
+		genreIDs[name] = id
+	}
+
+	personIDs := make(map[string]int)
+
+	for _, s := range seedMovies {
+		movie := s.Movie
+		movie.Year = movie.ReleaseDate.Year()
+		movie.CreatedAt = time.Now()
+		movie.UpdatedAt = time.Now()
+
+		movie.GenreIDs = []int{}
+		for _, name := range s.Genres {
+			movie.GenreIDs = append(movie.GenreIDs, genreIDs[name])
+		}
+
+		id, err := m.InsertMovie(movie, 0)
+		if err != nil {
+			return err
+		}
+
+		var credits []Credit
+		for i, c := range s.Credits {
+			if _, ok := personIDs[c.Name]; !ok {
+				now := time.Now()
+				personIDs[c.Name], err = m.InsertPerson(Person{Name: c.Name, CreatedAt: now, UpdatedAt: now})
+				if err != nil {
+					return err
+				}
+			}
+			credits = append(credits, Credit{
+				PersonID: personIDs[c.Name],
+				Role: c.Role,
+				CharacterName: c.CharacterName,
+				BillingOrder: i + 1,
+			})
+		}
+
+		err = m.ReplaceCredits(id, credits)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+
+	// cgoを使わないSQLiteのドライバ
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteのDSNの接頭辞(sqlite:movies.db、sqlite::memory:)
+const sqliteScheme = "sqlite:"
+
+// IsSQLiteDSN reports whether the DSN selects the SQLite backend
+func IsSQLiteDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, sqliteScheme)
+}
+
+// OpenSQLite opens the database named by a sqlite: DSN
+func OpenSQLite(dsn string) (*sql.DB, error) {
+	name := strings.TrimPrefix(dsn, sqliteScheme)
+
+	// foreign keyの制約は接続ごとに有効にする
+	// 書き込めるのは1つの接続だけなので、トランザクションは最初から書き込みのロックを取って待たせる
+	// 時刻は文字列の順に並ぶ形式で書き込む
+	params := "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate&_time_format=sqlite"
+	if strings.Contains(name, "?") {
+		name += "&" + params
+	} else {
+		name += "?" + params
+	}
+
+	db, err := sql.Open("sqlite", name)
+	if err != nil {
+		return nil, err
+	}
+
+	// :memory:は接続ごとに別のDBになるので、接続を1つにする
+	if strings.HasPrefix(name, ":memory:") {
+		db.SetMaxOpenConns(1)
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// NewSQLiteModel returns a store backed by a SQLite database
+func NewSQLiteModel(db *sql.DB) *DBModel {
+	return &DBModel{DB: db, dialect: dialectSQLite}
+}
@@ -0,0 +1,524 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	db, err := OpenSQLite("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := NewSQLiteMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewSQLiteModel(db)
+	// 2回目は何もしない
+	for i := 0; i < 2; i++ {
+		err = m.Seed()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	movies, err := m.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movies) != len(seedMovies) {
+		t.Fatalf("got %d movies, want %d", len(movies), len(seedMovies))
+	}
+
+	movie, err := m.Get(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "The Dark Knight" || len(movie.MovieGenre) != 3 || movie.ReleaseDate.Year() != 2008 {
+		t.Errorf("movie 3 = %+v", movie)
+	}
+
+	// idsの順に返し、ないidは飛ばす
+	movies, err = m.GetMany([]int{4, 999, 1, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movies) != 3 || movies[0].ID != 4 || movies[1].ID != 1 || movies[2].ID != 3 || len(movies[2].MovieGenre) != 3 {
+		t.Errorf("GetMany = %+v, want movies 4, 1, 3", movies)
+	}
+
+	// 更新がmovies_ftsに反映される
+	movie.Description = "Batman raises the stakes in his war on crime"
+	err = m.UpdateMovie(*movie, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := m.Search(SearchFilter{Query: "batman's war"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Results) != 1 || page.Results[0].Movie.ID != 3 || !strings.Contains(page.Results[0].Snippet, "<b>Batman</b>") {
+		t.Errorf("search results = %+v", page.Results)
+	}
+
+	_, err = m.InsertMovie(Movie{Title: "<i>Heist</i>", Description: "Tom & Jerry"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err = m.Search(SearchFilter{Query: "heist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Results) != 1 || page.Results[0].TitleHighlight != "&lt;i&gt;<b>Heist</b>&lt;/i&gt;" || page.Results[0].Snippet != "Tom &amp; Jerry" {
+		t.Errorf("escaped search results = %+v", page.Results)
+	}
+
+	_, err = m.Search(SearchFilter{Query: "--"})
+	if err != ErrEmptySearch {
+		t.Errorf("symbols only: err = %v, want %v", err, ErrEmptySearch)
+	}
+
+	// 最後まで戻してからもう一度適用できる
+	err = migrator.Goto(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// マイグレーションと初期データを入れたSQLiteのmodel(同時に書き込めるようにファイルに作る)
+func newSQLiteTestModel(t *testing.T) *DBModel {
+	t.Helper()
+
+	db, err := OpenSQLite("sqlite:" + filepath.Join(t.TempDir(), "movies.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := NewSQLiteMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewSQLiteModel(db)
+	err = m.Seed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// Postgresと書き方が違うクエリ(行ロック、トリガー、savepoint)をSQLiteで確かめる
+func TestSQLiteDialect(t *testing.T) {
+	tests := []struct {
+		name string
+		run func(t *testing.T, m *DBModel)
+	}{
+		{"review aggregates", testSQLiteReviewAggregates},
+		{"concurrent reviews", testSQLiteConcurrentReviews},
+		{"tag merge", testSQLiteTagMerge},
+		{"import savepoints", testSQLiteImport},
+		{"revision triggers", testSQLiteRevisions},
+		{"suggestion accept", testSQLiteAcceptSuggestion},
+		{"US certification migration", testSQLiteUSCertification},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newSQLiteTestModel(t))
+		})
+	}
+}
+
+func testSQLiteReviewAggregates(t *testing.T, m *DBModel) {
+	wantStats := func(count int, score float64) {
+		t.Helper()
+		movie, err := m.Get(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if movie.ReviewCount != count || movie.ReviewScore != score {
+			t.Errorf("review_count, review_score = %d, %v, want %d, %v", movie.ReviewCount, movie.ReviewScore, count, score)
+		}
+	}
+
+	now := time.Now()
+	first, err := m.InsertReview(Review{MovieID: 1, UserID: 1, Score: 5, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := m.InsertReview(Review{MovieID: 1, UserID: 2, Score: 2, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStats(2, 3.5)
+
+	_, err = m.InsertReview(Review{MovieID: 1, UserID: 1, Score: 1, CreatedAt: now, UpdatedAt: now})
+	if err != ErrDuplicateReview {
+		t.Errorf("second review by the same user: err = %v, want %v", err, ErrDuplicateReview)
+	}
+
+	err = m.UpdateReview(Review{ID: first, UserID: 2, Score: 1, UpdatedAt: now})
+	if err != ErrReviewNotOwned {
+		t.Errorf("update by another user: err = %v, want %v", err, ErrReviewNotOwned)
+	}
+
+	err = m.UpdateReview(Review{ID: second, UserID: 2, Score: 4, UpdatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStats(2, 4.5)
+
+	err = m.DeleteReview(first, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStats(1, 4)
+}
+
+func testSQLiteConcurrentReviews(t *testing.T, m *DBModel) {
+	// 同じmovieへの書き込みは順番に行われ、集計が失われない
+	const users = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, users)
+	for i := 1; i <= users; i++ {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			now := time.Now()
+			_, err := m.InsertReview(Review{MovieID: 2, UserID: userID, Score: userID%5 + 1, CreatedAt: now, UpdatedAt: now})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	movie, err := m.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.ReviewCount != users {
+		t.Errorf("review_count = %d, want %d", movie.ReviewCount, users)
+	}
+}
+
+func testSQLiteTagMerge(t *testing.T, m *DBModel) {
+	release := time.Date(2001, 12, 7, 0, 0, 0, 0, time.UTC)
+	for i, tags := range [][]string{{"Heist", "Crime"}, {"Caper", "Crime"}, {"Caper", "Heist"}} {
+		_, err := m.InsertMovie(Movie{Title: fmt.Sprintf("Ocean's %d", i+11), ReleaseDate: release, TagNames: tags}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	heist, err := m.GetTag("heist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caper, err := m.GetTag("caper")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.MergeTags(caper.ID, caper.ID)
+	if err != ErrTagMergeSelf {
+		t.Errorf("merge into itself: err = %v, want %v", err, ErrTagMergeSelf)
+	}
+
+	err = m.MergeTags(caper.ID, heist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 統合したタグのslugは統合先を指す
+	tag, err := m.GetTag("caper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.ID != heist.ID || len(tag.Synonyms) != 1 || tag.Synonyms[0] != "caper" {
+		t.Errorf("caper after merge = %+v", tag)
+	}
+
+	// 両方のタグがついていたmovieは1回だけ数える
+	tags, err := m.TagCloud(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for _, tag := range tags {
+		counts[tag.Slug] = tag.MovieCount
+	}
+	if len(counts) != 2 || counts["heist"] != 3 || counts["crime"] != 2 {
+		t.Errorf("tag cloud = %v, want heist 3, crime 2", counts)
+	}
+}
+
+func testSQLiteImport(t *testing.T, m *DBModel) {
+	rows := []ImportRow{
+		{Line: 2, ID: 1, Title: "The Shawshank Redemption (Remastered)", ReleaseDate: "1994-10-14", Runtime: 142, Rating: 5, Genres: []string{"drama"}},
+		{Line: 3, ID: 999, Title: "Missing", ReleaseDate: "2000-01-01"},
+		{Line: 4, Title: "Memento", ReleaseDate: "2000-10-11", Runtime: 113, Rating: 4, Genres: []string{"Drama", "Unknown"}},
+		{Line: 5, Title: "Inception", ReleaseDate: "2010-07-16", Runtime: 148, Rating: 5, Genres: []string{"Drama"}},
+	}
+
+	// dry runでは何も書き込まない
+	before, err := m.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.ImportMovies(rows, ImportOptions{DryRun: true, UserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := m.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("dry run: %d movies, want %d", len(after), len(before))
+	}
+
+	// 失敗した行だけを取り消して、ほかの行は書き込む
+	results, err := m.ImportMovies(rows, ImportOptions{UserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, r := range results {
+		statuses = append(statuses, r.Status)
+	}
+	want := []string{ImportUpdated, ImportFailed, ImportFailed, ImportCreated}
+	if strings.Join(statuses, ",") != strings.Join(want, ",") {
+		t.Fatalf("statuses = %v, want %v (%+v)", statuses, want, results)
+	}
+	if results[1].Error != ErrImportMovieNotFound.Error() {
+		t.Errorf("unknown id: error = %q", results[1].Error)
+	}
+
+	movie, err := m.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != rows[0].Title || len(movie.MovieGenre) != 1 {
+		t.Errorf("updated movie = %+v", movie)
+	}
+	movie, err = m.Get(results[3].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "Inception" || movie.ReleaseDate.Year() != 2010 {
+		t.Errorf("created movie = %+v", movie)
+	}
+
+	after, err = m.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before)+1 {
+		t.Errorf("%d movies after import, want %d", len(after), len(before)+1)
+	}
+}
+
+func testSQLiteRevisions(t *testing.T, m *DBModel) {
+	movie, err := m.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	title := movie.Title
+	movie.Title = "Renamed"
+	movie.TagNames = []string{"Prison"}
+	err = m.UpdateMovie(*movie, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := m.Revisions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].Action != RevisionUpdate || revisions[0].Snapshot.Title != "Renamed" || strings.Join(revisions[0].Snapshot.Tags, ",") != "Prison" {
+		t.Fatalf("revisions = %+v", revisions)
+	}
+
+	// 履歴はトリガーで書き換えられない
+	for _, stmt := range []string{
+		`update movie_revisions set action = 'x' where movie_id = 1`,
+		`delete from movie_revisions where movie_id = 1`,
+	} {
+		_, err = m.DB.Exec(stmt)
+		if err == nil || !strings.Contains(err.Error(), "immutable") {
+			t.Errorf("%s: err = %v, want immutable", stmt, err)
+		}
+	}
+
+	err = m.RevertMovie(1, revisions[1].ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	movie, err = m.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != title {
+		t.Errorf("title after revert = %q, want %q", movie.Title, title)
+	}
+
+	err = m.RevertMovie(2, revisions[1].ID, 3)
+	if !errors.Is(err, ErrRevisionMismatch) {
+		t.Errorf("revision of another movie: err = %v, want %v", err, ErrRevisionMismatch)
+	}
+
+	revisions, err = m.Revisions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// タグのなかった状態に戻る
+	if len(revisions) != 3 || revisions[0].Action != RevisionRevert || revisions[0].UserID != 3 || len(revisions[0].Snapshot.Tags) != 0 {
+		t.Errorf("revisions after revert = %+v", revisions)
+	}
+}
+
+func testSQLiteAcceptSuggestion(t *testing.T, m *DBModel) {
+	release := time.Date(1972, 3, 15, 0, 0, 0, 0, time.UTC)
+	id, err := m.InsertSuggestion(MetadataSuggestion{MovieID: 2, Provider: "fake", Description: "An aging patriarch", ReleaseDate: &release, PosterRef: "/godfather.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.ClaimSuggestion(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.ClaimSuggestion(id)
+	if err != ErrSuggestionResolved {
+		t.Errorf("second claim: err = %v, want %v", err, ErrSuggestionResolved)
+	}
+
+	old, err := m.AcceptSuggestion(id, []string{"description", "release_date", "poster"}, "posters/2-abc.png", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old != "" {
+		t.Errorf("old poster = %q, want none", old)
+	}
+
+	// 1つのトランザクションで反映してversionは1つだけ上がる
+	movie, err := m.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if movie.Description != "An aging patriarch" || !movie.ReleaseDate.Equal(release) || movie.Year != 1972 || movie.Poster != "posters/2-abc.png" || movie.Version != 2 || len(movie.MovieGenre) != 2 {
+		t.Errorf("accepted movie = %+v", movie)
+	}
+	revisions, err := m.Revisions(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].UserID != 4 || revisions[0].Snapshot.Poster != "posters/2-abc.png" {
+		t.Errorf("revisions = %+v", revisions)
+	}
+
+	s, err := m.GetSuggestion(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != SuggestionAccepted || len(s.AcceptedFields) != 3 {
+		t.Errorf("suggestion = %+v", s)
+	}
+
+	_, err = m.AcceptSuggestion(id, []string{"description"}, "", 4)
+	if err != ErrSuggestionResolved {
+		t.Errorf("accept twice: err = %v, want %v", err, ErrSuggestionResolved)
+	}
+	err = m.ReleaseSuggestion(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.ClaimSuggestion(id)
+	if err != ErrSuggestionResolved {
+		t.Errorf("claim accepted suggestion: err = %v, want %v", err, ErrSuggestionResolved)
+	}
+}
+
+func testSQLiteUSCertification(t *testing.T, m *DBModel) {
+	migrator, err := NewSQLiteMigrator(m.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 0017より前はUSの年齢制限をmovie_certificationsにも持っていた
+	err = migrator.Goto(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.DB.Exec(`update movie_certifications set rating = 'NC-17' where movie_id = 1 and country = 'US'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	err = m.DB.QueryRow(`select count(*) from movie_certifications where country = 'US'`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("US certifications left = %d, want 0", count)
+	}
+
+	_, certifications, err := m.Releases(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certifications) != 1 || certifications[0].Country != "US" || certifications[0].Rating != "NC-17" {
+		t.Errorf("certifications = %+v, want US NC-17 from mpaa_rating", certifications)
+	}
+
+	err = m.ReplaceReleases(1, nil, []MovieCertification{{Country: "US", Rating: "R"}})
+	if err == nil {
+		t.Error("US certification different from mpaa_rating was accepted")
+	}
+	err = m.ReplaceReleases(1, nil, []MovieCertification{{Country: "US", Rating: "NC-17"}, {Country: "JP", Rating: "R15+"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, certifications, err = m.Releases(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certifications) != 2 || certifications[0].Country != "JP" || certifications[1].Rating != "NC-17" {
+		t.Errorf("certifications after replace = %+v", certifications)
+	}
+}
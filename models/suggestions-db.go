@@ -0,0 +1,277 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const suggestionColumns = `id, movie_id, provider, external_id, title, description, release_date, poster_ref,
+					status, accepted_fields, created_at, updated_at`
+
+// 1行をMetadataSuggestionに読み込む
+func scanSuggestion(row interface{ Scan(...interface{}) error }) (*MetadataSuggestion, error) {
+	var s MetadataSuggestion
+	var releaseDate sql.NullTime
+	var accepted string
+
+	err := row.Scan(
+		&s.ID,
+		&s.MovieID,
+		&s.Provider,
+		&s.ExternalID,
+		&s.Title,
+		&s.Description,
+		&releaseDate,
+		&s.PosterRef,
+		&s.Status,
+		&accepted,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if releaseDate.Valid {
+		s.ReleaseDate = &releaseDate.Time
+	}
+	s.AcceptedFields = splitFields(accepted)
+
+	return &s, nil
+}
+
+// 状態ごとの提案(ゴミ箱のmovieのものを除く)を古い順に返すメソッド(statusが空の場合はすべて)
+func (m *DBModel) Suggestions(status string) ([]*MetadataSuggestion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select ` + suggestionColumns + `
+				from movie_metadata_suggestions
+				where ($1 = '' or status = $1)
+					and movie_id in (select id from movies where deleted_at is null)
+				order by id`
+
+	rows, err := m.DB.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*MetadataSuggestion{}
+
+	for rows.Next() {
+		s, err := scanSuggestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions, rows.Err()
+}
+
+func (m *DBModel) GetSuggestion(id int) (*MetadataSuggestion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select ` + suggestionColumns + ` from movie_metadata_suggestions where id = $1`
+
+	return scanSuggestion(m.DB.QueryRowContext(ctx, query, id))
+}
+
+// 提案を保存するメソッド(同じmovieの未確認の提案は新しいもので置き換える)
+func (m *DBModel) InsertSuggestion(s MetadataSuggestion) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = m.lockMovie(ctx, tx, s.MovieID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from movie_metadata_suggestions where movie_id = $1 and status = $2`, s.MovieID, SuggestionPending)
+	if err != nil {
+		return 0, err
+	}
+
+	var releaseDate sql.NullTime
+	if s.ReleaseDate != nil {
+		releaseDate = sql.NullTime{Time: *s.ReleaseDate, Valid: true}
+	}
+
+	stmt := `insert into movie_metadata_suggestions
+						(movie_id, provider, external_id, title, description, release_date, poster_ref, status, created_at, updated_at)
+						values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+						returning id`
+
+	var id int
+	err = tx.QueryRowContext(ctx, stmt,
+		s.MovieID,
+		s.Provider,
+		s.ExternalID,
+		s.Title,
+		s.Description,
+		releaseDate,
+		s.PosterRef,
+		SuggestionPending,
+		time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// 未確認の提案を採用済み(fieldsを記録する)か却下にするメソッド
+func (m *DBModel) ResolveSuggestion(id int, status string, fields []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `update movie_metadata_suggestions set status = $1, accepted_fields = $2, updated_at = $3
+					where id = $4 and status = $5`
+
+	result, err := m.DB.ExecContext(ctx, stmt, status, joinFields(fields), time.Now(), id, SuggestionPending)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// 存在しないのか、すでに確認済みなのかを区別する
+		_, err = m.GetSuggestion(id)
+		if err != nil {
+			return err
+		}
+		return ErrSuggestionResolved
+	}
+
+	return nil
+}
+
+// 未確認の提案を採用中にして返すメソッド(同じ提案を同時に採用できないようにする)
+func (m *DBModel) ClaimSuggestion(id int) (*MetadataSuggestion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	stmt := `update movie_metadata_suggestions set status = $1, updated_at = $2
+					where id = $3 and (status = $4 or (status = $1 and updated_at < $5))
+					returning ` + suggestionColumns
+
+	s, err := scanSuggestion(m.DB.QueryRowContext(ctx, stmt, SuggestionAccepting, now, id, SuggestionPending, now.Add(-suggestionClaimTimeout)))
+	if errors.Is(err, sql.ErrNoRows) {
+		// 存在しないのか、すでに確認済み(採用中)なのかを区別する
+		_, err = m.GetSuggestion(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrSuggestionResolved
+	}
+
+	return s, err
+}
+
+// 採用中の提案を未確認に戻すメソッド(採用に失敗したときに呼ぶ)
+func (m *DBModel) ReleaseSuggestion(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `update movie_metadata_suggestions set status = $1, updated_at = $2 where id = $3 and status = $4`
+
+	_, err := m.DB.ExecContext(ctx, stmt, SuggestionPending, time.Now(), id, SuggestionAccepting)
+	return err
+}
+
+// 採用中の提案のfieldsをmovieに反映して、採用済みにするメソッド
+// posterKeyが空でなければポスターも差し替え、前のポスターのキーを返す
+func (m *DBModel) AcceptSuggestion(id int, fields []string, posterKey string, userID int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	query := `select ` + suggestionColumns + ` from movie_metadata_suggestions where id = $1` + m.dialect.rowLock("for update")
+
+	s, err := scanSuggestion(tx.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return "", err
+	}
+	if s.Status != SuggestionAccepting {
+		return "", ErrSuggestionResolved
+	}
+
+	var version int
+	var oldPoster string
+	err = tx.QueryRowContext(ctx, `select version, coalesce(poster, '') from movies where id = $1 and deleted_at is null` + m.dialect.rowLock("for update"), s.MovieID).Scan(&version, &oldPoster)
+	if err != nil {
+		return "", err
+	}
+
+	// 1回の採用でversionは1つだけ上げる
+	versionSet := "version = version + 1"
+	if containsString(fields, "description") || containsString(fields, "release_date") {
+		versionSet = "version = version"
+
+		snapshot, err := movieSnapshotTx(ctx, tx, s.MovieID)
+		if err != nil {
+			return "", err
+		}
+
+		// genreとタグの紐づけは変えない
+		movie := Movie{ID: s.MovieID, UpdatedAt: time.Now(), Version: version}
+		snapshot.apply(&movie)
+		movie.GenreIDs = nil
+		movie.TagNames = nil
+		s.apply(&movie, fields)
+
+		err = m.updateMovieTx(ctx, tx, movie)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if posterKey != "" {
+		_, err = tx.ExecContext(ctx, `update movies set poster = $1, ` + versionSet + `, updated_at = $2 where id = $3`, posterKey, time.Now(), s.MovieID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = insertRevision(ctx, tx, s.MovieID, RevisionUpdate, userID)
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `update movie_metadata_suggestions set status = $1, accepted_fields = $2, updated_at = $3 where id = $4`
+
+	_, err = tx.ExecContext(ctx, stmt, SuggestionAccepted, joinFields(fields), time.Now(), id)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	if posterKey == "" {
+		return "", nil
+	}
+	return oldPoster, nil
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// 呼び出し元が書き換えてもストアに影響しないようにコピーする
+func copySuggestion(s MetadataSuggestion) *MetadataSuggestion {
+	if s.ReleaseDate != nil {
+		d := *s.ReleaseDate
+		s.ReleaseDate = &d
+	}
+	s.AcceptedFields = append([]string{}, s.AcceptedFields...)
+	return &s
+}
+
+func (m *MemoryModel) Suggestions(status string) ([]*MetadataSuggestion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	suggestions := []*MetadataSuggestion{}
+	for _, s := range m.suggestions {
+		movie, ok := m.movies[s.MovieID]
+		if !ok || movie.DeletedAt != nil {
+			continue
+		}
+		if status == "" || s.Status == status {
+			suggestions = append(suggestions, copySuggestion(s))
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].ID < suggestions[j].ID
+	})
+
+	return suggestions, nil
+}
+
+func (m *MemoryModel) GetSuggestion(id int) (*MetadataSuggestion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.suggestions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return copySuggestion(s), nil
+}
+
+func (m *MemoryModel) InsertSuggestion(s MetadataSuggestion) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[s.MovieID]
+	if !ok || movie.DeletedAt != nil {
+		return 0, sql.ErrNoRows
+	}
+
+	// 同じmovieの未確認の提案は新しいもので置き換える
+	for id, existing := range m.suggestions {
+		if existing.MovieID == s.MovieID && existing.Status == SuggestionPending {
+			delete(m.suggestions, id)
+		}
+	}
+
+	s = *copySuggestion(s)
+	s.ID = m.nextSuggestionID
+	s.Status = SuggestionPending
+	s.AcceptedFields = []string{}
+	s.CreatedAt = time.Now()
+	s.UpdatedAt = s.CreatedAt
+	m.suggestions[s.ID] = s
+	m.nextSuggestionID++
+
+	return s.ID, nil
+}
+
+func (m *MemoryModel) ResolveSuggestion(id int, status string, fields []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.suggestions[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if s.Status != SuggestionPending {
+		return ErrSuggestionResolved
+	}
+
+	s.Status = status
+	s.AcceptedFields = append([]string{}, fields...)
+	s.UpdatedAt = time.Now()
+	m.suggestions[id] = s
+
+	return nil
+}
+
+func (m *MemoryModel) ClaimSuggestion(id int) (*MetadataSuggestion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.suggestions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stale := s.Status == SuggestionAccepting && s.UpdatedAt.Before(time.Now().Add(-suggestionClaimTimeout))
+	if s.Status != SuggestionPending && !stale {
+		return nil, ErrSuggestionResolved
+	}
+
+	s.Status = SuggestionAccepting
+	s.UpdatedAt = time.Now()
+	m.suggestions[id] = s
+
+	return copySuggestion(s), nil
+}
+
+func (m *MemoryModel) ReleaseSuggestion(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.suggestions[id]
+	if ok && s.Status == SuggestionAccepting {
+		s.Status = SuggestionPending
+		s.UpdatedAt = time.Now()
+		m.suggestions[id] = s
+	}
+
+	return nil
+}
+
+func (m *MemoryModel) AcceptSuggestion(id int, fields []string, posterKey string, userID int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.suggestions[id]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	if s.Status != SuggestionAccepting {
+		return "", ErrSuggestionResolved
+	}
+
+	current, ok := m.movies[s.MovieID]
+	if !ok || current.DeletedAt != nil {
+		return "", sql.ErrNoRows
+	}
+
+	movie := current
+	movie.UpdatedAt = time.Now()
+	s.apply(&movie, fields)
+	err := m.updateMovie(movie)
+	if err != nil {
+		return "", err
+	}
+
+	old := ""
+	if posterKey != "" {
+		movie := m.movies[s.MovieID]
+		old = movie.Poster
+		movie.Poster = posterKey
+		m.movies[s.MovieID] = movie
+	}
+
+	m.recordRevision(s.MovieID, RevisionUpdate, userID)
+
+	s.Status = SuggestionAccepted
+	s.AcceptedFields = append([]string{}, fields...)
+	s.UpdatedAt = time.Now()
+	m.suggestions[id] = s
+
+	return old, nil
+}
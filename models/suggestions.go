@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// SuggestionPending is a suggestion nobody has reviewed yet
+	SuggestionPending = "pending"
+	// SuggestionAccepting is a suggestion an editor is accepting right now
+	SuggestionAccepting = "accepting"
+	// SuggestionAccepted is a suggestion whose values were applied to the movie
+	SuggestionAccepted = "accepted"
+	// SuggestionRejected is a suggestion that was discarded
+	SuggestionRejected = "rejected"
+)
+
+// SuggestionStatuses is the list of suggestion states
+var SuggestionStatuses = []string{SuggestionPending, SuggestionAccepting, SuggestionAccepted, SuggestionRejected}
+
+// 採用の途中で止まった(プロセスが落ちたなど)提案は、この時間が過ぎたらもう一度採用できる
+const suggestionClaimTimeout = time.Minute
+
+// SuggestionFields is the list of movie fields a suggestion can change
+var SuggestionFields = []string{"description", "release_date", "poster"}
+
+var (
+	// ErrSuggestionResolved is returned when a suggestion was already accepted or rejected
+	ErrSuggestionResolved = errors.New("suggestion has already been accepted or rejected")
+	// ErrUnknownSuggestionField is returned for a field other than description, release_date or poster
+	ErrUnknownSuggestionField = errors.New("unknown field (allowed: description, release_date, poster)")
+)
+
+// MetadataSuggestion is metadata an external provider found for a movie, waiting for an editor to accept or reject it
+type MetadataSuggestion struct {
+	ID int `json:"id"`
+	MovieID int `json:"movie_id"`
+	// 例: "tmdb"
+	Provider string `json:"provider"`
+	ExternalID string `json:"external_id"`
+	// プロバイダーでのタイトル(正しい作品かを確認するため)
+	Title string `json:"title"`
+	Description string `json:"description"`
+	ReleaseDate *time.Time `json:"release_date"`
+	// プロバイダーごとの画像の参照(採用したときにダウンロードする)
+	PosterRef string `json:"poster_ref"`
+	Status string `json:"status"`
+	// 採用したフィールド(pending, rejectedの場合は空)
+	AcceptedFields []string `json:"accepted_fields"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Changes returns the fields whose suggested value differs from the movie
+func (s MetadataSuggestion) Changes(movie *Movie) []string {
+	changes := []string{}
+	if s.Description != "" && s.Description != movie.Description {
+		changes = append(changes, "description")
+	}
+	if s.ReleaseDate != nil && !s.ReleaseDate.Equal(movie.ReleaseDate) {
+		changes = append(changes, "release_date")
+	}
+	// すでにあるポスターは自動では提案しない(明示的に指定すれば差し替えられる)
+	if s.PosterRef != "" && movie.Poster == "" {
+		changes = append(changes, "poster")
+	}
+	return changes
+}
+
+// fieldsの提案の値をmovieに反映する(posterは除く)
+func (s MetadataSuggestion) apply(movie *Movie, fields []string) {
+	if containsString(fields, "description") {
+		movie.Description = s.Description
+	}
+	if containsString(fields, "release_date") && s.ReleaseDate != nil {
+		movie.ReleaseDate = *s.ReleaseDate
+		movie.Year = movie.ReleaseDate.Year()
+	}
+}
+
+// ValidateFields checks that the fields can be accepted from the suggestion
+func (s MetadataSuggestion) ValidateFields(fields []string) error {
+	for _, f := range fields {
+		if !containsString(SuggestionFields, f) {
+			return fmt.Errorf("%w: %q", ErrUnknownSuggestionField, f)
+		}
+		if (f == "description" && s.Description == "") || (f == "release_date" && s.ReleaseDate == nil) || (f == "poster" && s.PosterRef == "") {
+			return fmt.Errorf("suggestion has no value for %s", f)
+		}
+	}
+	return nil
+}
+
+// 採用したフィールドをカンマ区切りで保存する
+func joinFields(fields []string) string {
+	return strings.Join(fields, ",")
+}
+
+func splitFields(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,294 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// slugか別名のslugからタグのidを探す
+func resolveTagTx(ctx context.Context, tx *sql.Tx, slug string) (int, error) {
+	query := `select id from tags where slug = $1
+				union all
+				select tag_id from tag_synonyms where slug = $1
+				limit 1`
+
+	var id int
+	err := tx.QueryRowContext(ctx, query, slug).Scan(&id)
+	return id, err
+}
+
+// movieのタグをnamesで置き換える(ないタグは作る)
+func replaceMovieTags(ctx context.Context, tx *sql.Tx, movieID int, names []string) error {
+	names, err := normalizeTagNames(names)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var tagIDs []int
+	for _, name := range names {
+		id, err := resolveTagTx(ctx, tx, TagSlug(name))
+		if errors.Is(err, sql.ErrNoRows) {
+			stmt := `insert into tags (name, slug, created_at, updated_at) values ($1, $2, $3, $4) returning id`
+			err = tx.QueryRowContext(ctx, stmt, name, TagSlug(name), now, now).Scan(&id)
+		}
+		if err != nil {
+			return err
+		}
+		tagIDs = append(tagIDs, id)
+	}
+	// 別名どうしが同じタグになる場合がある
+	tagIDs = uniqueInts(tagIDs)
+
+	_, err = tx.ExecContext(ctx, `delete from movie_tags where movie_id = $1`, movieID)
+	if err != nil {
+		return err
+	}
+
+	for _, tagID := range tagIDs {
+		stmt := `insert into movie_tags (movie_id, tag_id, created_at, updated_at) values ($1, $2, $3, $4)`
+		_, err = tx.ExecContext(ctx, stmt, movieID, tagID, now, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// movieがついているタグを件数の多い順に返すメソッド(limitが0ならすべて)
+func (m *DBModel) TagCloud(limit int) ([]*Tag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select
+					t.id, t.name, t.slug, count(*)
+				from
+					tags t
+					join movie_tags mt on (mt.tag_id = t.id)
+					join movies m on (m.id = mt.movie_id)
+				where
+					m.deleted_at is null
+				group by
+					t.id
+				order by
+					count(*) desc, t.slug`
+
+	var args []interface{}
+	if limit > 0 {
+		query += ` limit $1`
+		args = append(args, limit)
+	}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+
+	for rows.Next() {
+		var t Tag
+		err := rows.Scan(&t.ID, &t.Name, &t.Slug, &t.MovieCount)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, &t)
+	}
+
+	return tags, rows.Err()
+}
+
+// slug(別名のslugでもよい)のタグを別名と一緒に返すメソッド
+func (m *DBModel) GetTag(slug string) (*Tag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select id, name, slug, created_at, updated_at from tags
+				where id = (select id from tags where slug = $1 union all select tag_id from tag_synonyms where slug = $1 limit 1)`
+
+	var t Tag
+	err := m.DB.QueryRowContext(ctx, query, TagSlug(slug)).Scan(
+		&t.ID,
+		&t.Name,
+		&t.Slug,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `select slug from tag_synonyms where tag_id = $1 order by slug`, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s string
+		err := rows.Scan(&s)
+		if err != nil {
+			return nil, err
+		}
+		t.Synonyms = append(t.Synonyms, s)
+	}
+
+	return &t, rows.Err()
+}
+
+// nameのslugをタグの別名にする(すでにほかのタグのslugや別名の場合はErrTagExistsを返す)
+func (m *DBModel) AddTagSynonym(id int, name string) error {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return err
+	}
+	slug := TagSlug(name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `select true from tags where id = $1` + m.dialect.rowLock("for update"), id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	current, err := resolveTagTx(ctx, tx, slug)
+	if err == nil {
+		if current == id {
+			return nil
+		}
+		return ErrTagExists
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `insert into tag_synonyms (tag_id, slug, created_at, updated_at) values ($1, $2, $3, $4)`, id, slug, now, now)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// タグidをintoにまとめる(movieの紐づけと別名を移し、idのslugはintoの別名として残す)
+func (m *DBModel) MergeTags(id int, into int) error {
+	if id == into {
+		return ErrTagMergeSelf
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `select id, slug from tags where id in ($1, $2)` + m.dialect.rowLock("for update"), id, into)
+	if err != nil {
+		return err
+	}
+	slugs := make(map[int]string)
+	for rows.Next() {
+		var tagID int
+		var slug string
+		err := rows.Scan(&tagID, &slug)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		slugs[tagID] = slug
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if len(slugs) != 2 {
+		return sql.ErrNoRows
+	}
+
+	now := time.Now()
+	stmts := []struct {
+		query string
+		args []interface{}
+	}{
+		{`insert into movie_tags (movie_id, tag_id, created_at, updated_at)
+				select movie_id, $1, $2, $2 from movie_tags where tag_id = $3
+				on conflict (movie_id, tag_id) do nothing`, []interface{}{into, now, id}},
+		{`update tag_synonyms set tag_id = $1, updated_at = $2 where tag_id = $3`, []interface{}{into, now, id}},
+		{`delete from tags where id = $1`, []interface{}{id}},
+		{`insert into tag_synonyms (tag_id, slug, created_at, updated_at) values ($1, $2, $3, $3)`, []interface{}{into, slugs[id], now}},
+		{`update tags set updated_at = $1 where id = $2`, []interface{}{now, into}},
+	}
+	for _, s := range stmts {
+		_, err = tx.ExecContext(ctx, s.query, s.args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// movieのタグを名前順にセットする
+func (m *DBModel) LoadTags(movies []*Movie) error {
+	if len(movies) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var args []interface{}
+	var placeholders []string
+	for _, movie := range movies {
+		args = append(args, movie.ID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(`select mt.movie_id, t.id, t.name, t.slug from movie_tags mt join tags t on (t.id = mt.tag_id)
+				where mt.movie_id in (%s) order by lower(t.name), t.id`, strings.Join(placeholders, ", "))
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tags := make(map[int][]*Tag)
+	for rows.Next() {
+		var movieID int
+		var t Tag
+		err := rows.Scan(&movieID, &t.ID, &t.Name, &t.Slug)
+		if err != nil {
+			return err
+		}
+		tags[movieID] = append(tags[movieID], &t)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		movie.Tags = tags[movie.ID]
+	}
+
+	return nil
+}
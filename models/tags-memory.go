@@ -0,0 +1,198 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+)
+
+// slugか別名のslugからタグのidを探す(ロックを取得してから呼ぶ)
+func (m *MemoryModel) resolveTag(slug string) (int, bool) {
+	for _, t := range m.tags {
+		if t.Slug == slug {
+			return t.ID, true
+		}
+	}
+	id, ok := m.tagSynonyms[slug]
+	return id, ok
+}
+
+func (m *MemoryModel) hasTag(movieID int, tagID int) bool {
+	return containsInt(m.movieTags[movieID], tagID)
+}
+
+// movieのタグをnamesで置き換える(ロックを取得してから呼ぶ)
+func (m *MemoryModel) replaceMovieTags(movieID int, names []string) error {
+	names, err := normalizeTagNames(names)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var tagIDs []int
+	for _, name := range names {
+		slug := TagSlug(name)
+		id, ok := m.resolveTag(slug)
+		if !ok {
+			id = m.nextTagID
+			m.nextTagID++
+			m.tags[id] = Tag{ID: id, Name: name, Slug: slug, CreatedAt: now, UpdatedAt: now}
+		}
+		tagIDs = append(tagIDs, id)
+	}
+
+	m.movieTags[movieID] = uniqueInts(tagIDs)
+
+	return nil
+}
+
+func (m *MemoryModel) TagCloud(limit int) ([]*Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for movieID, tagIDs := range m.movieTags {
+		movie, ok := m.movies[movieID]
+		if !ok || movie.DeletedAt != nil {
+			continue
+		}
+		for _, id := range tagIDs {
+			counts[id]++
+		}
+	}
+
+	tags := []*Tag{}
+	for id, n := range counts {
+		t := m.tags[id]
+		tags = append(tags, &Tag{ID: t.ID, Name: t.Name, Slug: t.Slug, MovieCount: n})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].MovieCount != tags[j].MovieCount {
+			return tags[i].MovieCount > tags[j].MovieCount
+		}
+		return tags[i].Slug < tags[j].Slug
+	})
+
+	if limit > 0 && len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	return tags, nil
+}
+
+func (m *MemoryModel) GetTag(slug string) (*Tag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.resolveTag(TagSlug(slug))
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	t := m.tags[id]
+	t.Synonyms = nil
+	for s, tagID := range m.tagSynonyms {
+		if tagID == id {
+			t.Synonyms = append(t.Synonyms, s)
+		}
+	}
+	sort.Strings(t.Synonyms)
+
+	return &t, nil
+}
+
+func (m *MemoryModel) AddTagSynonym(id int, name string) error {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return err
+	}
+	slug := TagSlug(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tags[id]; !ok {
+		return sql.ErrNoRows
+	}
+
+	if current, ok := m.resolveTag(slug); ok {
+		if current == id {
+			return nil
+		}
+		return ErrTagExists
+	}
+
+	m.tagSynonyms[slug] = id
+
+	return nil
+}
+
+func (m *MemoryModel) MergeTags(id int, into int) error {
+	if id == into {
+		return ErrTagMergeSelf
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	source, ok := m.tags[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	target, ok := m.tags[into]
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	for movieID, tagIDs := range m.movieTags {
+		if !containsInt(tagIDs, id) {
+			continue
+		}
+		var replaced []int
+		for _, tagID := range tagIDs {
+			if tagID == id {
+				tagID = into
+			}
+			replaced = append(replaced, tagID)
+		}
+		m.movieTags[movieID] = uniqueInts(replaced)
+	}
+
+	for s, tagID := range m.tagSynonyms {
+		if tagID == id {
+			m.tagSynonyms[s] = into
+		}
+	}
+	m.tagSynonyms[source.Slug] = into
+	delete(m.tags, id)
+
+	target.UpdatedAt = time.Now()
+	m.tags[into] = target
+
+	return nil
+}
+
+func (m *MemoryModel) LoadTags(movies []*Movie) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, movie := range movies {
+		var tags []*Tag
+		for _, id := range m.movieTags[movie.ID] {
+			t := m.tags[id]
+			tags = append(tags, &Tag{ID: t.ID, Name: t.Name, Slug: t.Slug})
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			a, b := strings.ToLower(tags[i].Name), strings.ToLower(tags[j].Name)
+			if a != b {
+				return a < b
+			}
+			return tags[i].ID < tags[j].ID
+		})
+		movie.Tags = tags
+	}
+
+	return nil
+}
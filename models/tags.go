@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MaxMovieTags is the largest number of tags one movie can have
+const MaxMovieTags = 50
+
+var (
+	// ErrTagExists is returned when a synonym is already the slug of another tag
+	ErrTagExists = errors.New("a tag with that name already exists; merge the tags instead")
+	// ErrTagMergeSelf is returned when merging a tag into itself
+	ErrTagMergeSelf = errors.New("cannot merge a tag into itself")
+)
+
+// Tag is a free-form label editors attach to movies
+type Tag struct {
+	ID int `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+	// このタグに読み替える別のslug
+	Synonyms []string `json:"synonyms,omitempty"`
+	// ゴミ箱を除いたmovieの件数(TagCloudのみ)
+	MovieCount int `json:"movie_count,omitempty"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// "  Based on  a TRUE story " -> "Based on a TRUE story"
+func normalizeTagName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", errors.New("tag must not be empty")
+	}
+	if len(name) > 100 {
+		return "", errors.New("tag must not be longer than 100 bytes")
+	}
+	if TagSlug(name) == "" {
+		return "", errors.New("tag must contain a letter or digit")
+	}
+	return name, nil
+}
+
+// TagSlug folds case and replaces everything except letters and digits with "-"
+// ("Time-Travel!" と "time travel" は同じ "time-travel" になる)
+func TagSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// タグ名を正規化して、同じslugになるものをまとめる(順序は保つ)
+func normalizeTagNames(names []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name, err := normalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		slug := TagSlug(name)
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		normalized = append(normalized, name)
+	}
+	if len(normalized) > MaxMovieTags {
+		return nil, fmt.Errorf("a movie must not have more than %d tags", MaxMovieTags)
+	}
+	return normalized, nil
+}
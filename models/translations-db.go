@@ -0,0 +1,333 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// movieのすべての翻訳をlocaleの順に返すメソッド
+func (m *DBModel) MovieTranslations(movieID int) ([]*MovieTranslation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, `select true from movies where id = $1 and deleted_at is null`, movieID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `select movie_id, locale, title, description, created_at, updated_at
+				from movie_translations where movie_id = $1 order by locale`
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := []*MovieTranslation{}
+
+	for rows.Next() {
+		var t MovieTranslation
+		err := rows.Scan(
+			&t.MovieID,
+			&t.Locale,
+			&t.Title,
+			&t.Description,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		translations = append(translations, &t)
+	}
+
+	return translations, rows.Err()
+}
+
+// movieの翻訳を追加するか、同じlocaleのものを置き換えるメソッド
+func (m *DBModel) SaveMovieTranslation(t MovieTranslation) (*MovieTranslation, error) {
+	err := t.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err = m.DB.QueryRowContext(ctx, `select true from movies where id = $1 and deleted_at is null`, t.MovieID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `insert into movie_translations (movie_id, locale, title, description, created_at, updated_at)
+						values ($1, $2, $3, $4, $5, $5)
+						on conflict (movie_id, locale) do update
+							set title = excluded.title, description = excluded.description, updated_at = excluded.updated_at
+						returning created_at, updated_at`
+
+	err = m.DB.QueryRowContext(ctx, stmt, t.MovieID, t.Locale, t.Title, t.Description, time.Now()).Scan(&t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (m *DBModel) DeleteMovieTranslation(movieID int, locale string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, `delete from movie_translations where movie_id = $1 and locale = $2`, movieID, locale)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// genreのすべての翻訳をlocaleの順に返すメソッド
+func (m *DBModel) GenreTranslations(genreID int) ([]*GenreTranslation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, `select true from genres where id = $1`, genreID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `select genre_id, locale, genre_name, created_at, updated_at
+				from genre_translations where genre_id = $1 order by locale`
+
+	rows, err := m.DB.QueryContext(ctx, query, genreID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := []*GenreTranslation{}
+
+	for rows.Next() {
+		var t GenreTranslation
+		err := rows.Scan(
+			&t.GenreID,
+			&t.Locale,
+			&t.GenreName,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		translations = append(translations, &t)
+	}
+
+	return translations, rows.Err()
+}
+
+// genreの翻訳を追加するか、同じlocaleのものを置き換えるメソッド
+func (m *DBModel) SaveGenreTranslation(t GenreTranslation) (*GenreTranslation, error) {
+	err := t.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err = m.DB.QueryRowContext(ctx, `select true from genres where id = $1`, t.GenreID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `insert into genre_translations (genre_id, locale, genre_name, created_at, updated_at)
+						values ($1, $2, $3, $4, $4)
+						on conflict (genre_id, locale) do update
+							set genre_name = excluded.genre_name, updated_at = excluded.updated_at
+						returning created_at, updated_at`
+
+	err = m.DB.QueryRowContext(ctx, stmt, t.GenreID, t.Locale, t.GenreName, time.Now()).Scan(&t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (m *DBModel) DeleteGenreTranslation(genreID int, locale string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, `delete from genre_translations where genre_id = $1 and locale = $2`, genreID, locale)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// moviesのtitle・description・genre名を、localesの順に見つかった翻訳で置き換えるメソッド
+func (m *DBModel) LocalizeMovies(movies []*Movie, locales []string) error {
+	chain := translationChain(locales)
+	if len(chain) == 0 || len(movies) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var ids, localeArgs []string
+	for _, movie := range movies {
+		ids = append(ids, arg(movie.ID))
+	}
+	for _, l := range chain {
+		localeArgs = append(localeArgs, arg(l))
+	}
+	in := fmt.Sprintf("(%s)", strings.Join(ids, ", "))
+	inLocales := fmt.Sprintf("(%s)", strings.Join(localeArgs, ", "))
+
+	query := `select movie_id, locale, title, description from movie_translations
+				where movie_id in ` + in + ` and locale in ` + inLocales
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	translations := make(map[int]map[string]MovieTranslation)
+	for rows.Next() {
+		var t MovieTranslation
+		err := rows.Scan(&t.MovieID, &t.Locale, &t.Title, &t.Description)
+		if err != nil {
+			return err
+		}
+		if translations[t.MovieID] == nil {
+			translations[t.MovieID] = make(map[string]MovieTranslation)
+		}
+		translations[t.MovieID][t.Locale] = t
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	// genre名はmovies_genresのidごとに引けるようにする
+	query = `select mg.id, gt.locale, gt.genre_name
+				from movies_genres mg
+				join genre_translations gt on (gt.genre_id = mg.genre_id)
+				where mg.movie_id in ` + in + ` and gt.locale in ` + inLocales
+
+	rows, err = m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names := make(map[int]map[string]string)
+	for rows.Next() {
+		var mgID int
+		var locale, name string
+		err := rows.Scan(&mgID, &locale, &name)
+		if err != nil {
+			return err
+		}
+		if names[mgID] == nil {
+			names[mgID] = make(map[string]string)
+		}
+		names[mgID][locale] = name
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		applyMovieTranslation(movie, translations[movie.ID], chain)
+		applyGenreTranslations(movie, names, chain)
+	}
+
+	return nil
+}
+
+// genresの名前を、localesの順に見つかった翻訳で置き換えるメソッド
+func (m *DBModel) LocalizeGenres(genres []*Genre, locales []string) error {
+	chain := translationChain(locales)
+	if len(chain) == 0 || len(genres) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var args []interface{}
+	var placeholders []string
+	for _, l := range chain {
+		args = append(args, l)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	query := fmt.Sprintf(`select genre_id, locale, genre_name from genre_translations where locale in (%s)`, strings.Join(placeholders, ", "))
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names := make(map[int]map[string]string)
+	for rows.Next() {
+		var genreID int
+		var locale, name string
+		err := rows.Scan(&genreID, &locale, &name)
+		if err != nil {
+			return err
+		}
+		if names[genreID] == nil {
+			names[genreID] = make(map[string]string)
+		}
+		names[genreID][locale] = name
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, g := range genres {
+		for _, locale := range chain {
+			if name, ok := names[g.ID][locale]; ok {
+				g.GenreName = name
+				break
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// movieとgenreの翻訳のキー
+type translationKey struct {
+	ID int
+	Locale string
+}
+
+func (m *MemoryModel) MovieTranslations(movieID int) ([]*MovieTranslation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	movie, ok := m.movies[movieID]
+	if !ok || movie.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	translations := []*MovieTranslation{}
+	for key, t := range m.movieTranslations {
+		if key.ID == movieID {
+			t := t
+			translations = append(translations, &t)
+		}
+	}
+
+	sort.Slice(translations, func(i, j int) bool {
+		return translations[i].Locale < translations[j].Locale
+	})
+
+	return translations, nil
+}
+
+func (m *MemoryModel) SaveMovieTranslation(t MovieTranslation) (*MovieTranslation, error) {
+	err := t.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[t.MovieID]
+	if !ok || movie.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	key := translationKey{t.MovieID, t.Locale}
+	t.UpdatedAt = time.Now()
+	t.CreatedAt = t.UpdatedAt
+	if current, ok := m.movieTranslations[key]; ok {
+		t.CreatedAt = current.CreatedAt
+	}
+	m.movieTranslations[key] = t
+
+	return &t, nil
+}
+
+func (m *MemoryModel) DeleteMovieTranslation(movieID int, locale string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := translationKey{movieID, locale}
+	if _, ok := m.movieTranslations[key]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.movieTranslations, key)
+
+	return nil
+}
+
+func (m *MemoryModel) GenreTranslations(genreID int) ([]*GenreTranslation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.genres[genreID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	translations := []*GenreTranslation{}
+	for key, t := range m.genreTranslations {
+		if key.ID == genreID {
+			t := t
+			translations = append(translations, &t)
+		}
+	}
+
+	sort.Slice(translations, func(i, j int) bool {
+		return translations[i].Locale < translations[j].Locale
+	})
+
+	return translations, nil
+}
+
+func (m *MemoryModel) SaveGenreTranslation(t GenreTranslation) (*GenreTranslation, error) {
+	err := t.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.genres[t.GenreID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	key := translationKey{t.GenreID, t.Locale}
+	t.UpdatedAt = time.Now()
+	t.CreatedAt = t.UpdatedAt
+	if current, ok := m.genreTranslations[key]; ok {
+		t.CreatedAt = current.CreatedAt
+	}
+	m.genreTranslations[key] = t
+
+	return &t, nil
+}
+
+func (m *MemoryModel) DeleteGenreTranslation(genreID int, locale string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := translationKey{genreID, locale}
+	if _, ok := m.genreTranslations[key]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.genreTranslations, key)
+
+	return nil
+}
+
+func (m *MemoryModel) LocalizeMovies(movies []*Movie, locales []string) error {
+	chain := translationChain(locales)
+	if len(chain) == 0 {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, movie := range movies {
+		translations := make(map[string]MovieTranslation)
+		for _, locale := range chain {
+			if t, ok := m.movieTranslations[translationKey{movie.ID, locale}]; ok {
+				translations[locale] = t
+			}
+		}
+		applyMovieTranslation(movie, translations, chain)
+
+		names := make(map[int]map[string]string)
+		for mgID := range movie.MovieGenre {
+			mg, ok := m.movieGenres[mgID]
+			if !ok {
+				continue
+			}
+			names[mgID] = make(map[string]string)
+			for _, locale := range chain {
+				if t, ok := m.genreTranslations[translationKey{mg.GenreID, locale}]; ok {
+					names[mgID][locale] = t.GenreName
+				}
+			}
+		}
+		applyGenreTranslations(movie, names, chain)
+	}
+
+	return nil
+}
+
+func (m *MemoryModel) LocalizeGenres(genres []*Genre, locales []string) error {
+	chain := translationChain(locales)
+	if len(chain) == 0 {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, g := range genres {
+		for _, locale := range chain {
+			if t, ok := m.genreTranslations[translationKey{g.ID, locale}]; ok {
+				g.GenreName = t.GenreName
+				break
+			}
+		}
+	}
+
+	return nil
+}
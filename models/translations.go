@@ -0,0 +1,124 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultLocale is the language of the title, description and genre_name columns themselves
+const DefaultLocale = "en"
+
+// SupportedLocales is the list of locales translations can be stored for
+var SupportedLocales = []string{"en", "ja"}
+
+var (
+	// ErrUnsupportedLocale is returned for a locale other than the supported ones
+	ErrUnsupportedLocale = fmt.Errorf("unsupported locale (allowed: %s)", strings.Join(SupportedLocales, ", "))
+	// ErrDefaultLocale is returned when saving a translation in the default locale
+	ErrDefaultLocale = errors.New("the default locale is stored on the movie or genre itself; edit it directly")
+)
+
+// MovieTranslation is a movie's title and description in one locale
+type MovieTranslation struct {
+	MovieID int `json:"movie_id"`
+	Locale string `json:"locale"`
+	Title string `json:"title"`
+	// 空の場合は元のdescriptionを使う
+	Description string `json:"description"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GenreTranslation is a genre's name in one locale
+type GenreTranslation struct {
+	GenreID int `json:"genre_id"`
+	Locale string `json:"locale"`
+	GenreName string `json:"genre_name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 翻訳を保存できるlocaleかを確認する
+func validateTranslationLocale(locale string) error {
+	if locale == DefaultLocale {
+		return ErrDefaultLocale
+	}
+	if !containsString(SupportedLocales, locale) {
+		return ErrUnsupportedLocale
+	}
+	return nil
+}
+
+// Validate checks the translation before it is saved
+func (t *MovieTranslation) Validate() error {
+	err := validateTranslationLocale(t.Locale)
+	if err != nil {
+		return err
+	}
+
+	t.Title = strings.TrimSpace(t.Title)
+	if t.Title == "" {
+		return errors.New("title must not be empty")
+	}
+	if len(t.Title) > 512 {
+		return errors.New("title must not be longer than 512 bytes")
+	}
+	return nil
+}
+
+// Validate checks the translation before it is saved
+func (t *GenreTranslation) Validate() error {
+	err := validateTranslationLocale(t.Locale)
+	if err != nil {
+		return err
+	}
+
+	name, err := normalizeGenreName(t.GenreName)
+	if err != nil {
+		return err
+	}
+	t.GenreName = name
+	return nil
+}
+
+// 翻訳を探すlocaleの順序(DefaultLocaleより後ろは元の値を使うので除く)
+func translationChain(locales []string) []string {
+	var chain []string
+	for _, l := range locales {
+		if l == DefaultLocale {
+			break
+		}
+		chain = append(chain, l)
+	}
+	return chain
+}
+
+// chainの順に最初に見つかった翻訳をmovieに適用する
+func applyMovieTranslation(movie *Movie, translations map[string]MovieTranslation, chain []string) {
+	for _, locale := range chain {
+		t, ok := translations[locale]
+		if !ok {
+			continue
+		}
+		movie.Title = t.Title
+		if t.Description != "" {
+			movie.Description = t.Description
+		}
+		movie.Locale = locale
+		return
+	}
+}
+
+// movieのgenre名(movies_genresのidごと)を翻訳する
+func applyGenreTranslations(movie *Movie, names map[int]map[string]string, chain []string) {
+	for mgID := range movie.MovieGenre {
+		for _, locale := range chain {
+			if name, ok := names[mgID][locale]; ok {
+				movie.MovieGenre[mgID] = name
+				break
+			}
+		}
+	}
+}
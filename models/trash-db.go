@@ -0,0 +1,131 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ゴミ箱のmovieを削除日時の新しい順に返すメソッド
+func (m *DBModel) Trash() ([]*Movie, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select id, title, description, year, release_date, runtime, rating, mpaa_rating,
+							created_at, updated_at, version, review_count, review_score, coalesce(poster, ''), deleted_at from movies where deleted_at is not null
+							order by deleted_at desc, id`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+		var deletedAt time.Time
+		err := rows.Scan(
+			&movie.ID,
+			&movie.Title,
+			&movie.Description,
+			&movie.Year,
+			&movie.ReleaseDate,
+			&movie.Runtime,
+			&movie.Rating,
+			&movie.MPAARating,
+			&movie.CreatedAt,
+			&movie.UpdatedAt,
+			&movie.Version,
+			&movie.ReviewCount,
+			&movie.ReviewScore,
+			&movie.Poster,
+			&deletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movie.DeletedAt = &deletedAt
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	err = m.loadGenresBatch(ctx, movies)
+	if err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
+
+// ゴミ箱のmovieを元に戻す(userIDの履歴を記録する)
+func (m *DBModel) RestoreMovie(id int, userID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `update movies set deleted_at = null, version = version + 1 where id = $1 and deleted_at is not null`
+
+	res, err := tx.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	err = insertRevision(ctx, tx, id, RevisionRestore, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// before より前にゴミ箱に移したmovieを完全に削除して、件数とポスター画像のキーを返す
+// (ポスター画像はストレージに残るので、呼び出し元で削除する)
+func (m *DBModel) PurgeDeleted(before time.Time) (int, []string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stmt := `delete from movies where deleted_at is not null and deleted_at < $1 returning coalesce(poster, '')`
+
+	rows, err := m.DB.QueryContext(ctx, stmt, before)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	n := 0
+	var posters []string
+	for rows.Next() {
+		var poster string
+		err := rows.Scan(&poster)
+		if err != nil {
+			return 0, nil, err
+		}
+		n++
+		if poster != "" {
+			posters = append(posters, poster)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return n, posters, nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+func (m *MemoryModel) Trash() ([]*Movie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	movies := []*Movie{}
+
+	for _, movie := range m.movies {
+		if movie.DeletedAt != nil {
+			movies = append(movies, m.copyMovie(movie))
+		}
+	}
+
+	// 削除日時の新しい順に並べる
+	sort.Slice(movies, func(i, j int) bool {
+		if !movies[i].DeletedAt.Equal(*movies[j].DeletedAt) {
+			return movies[i].DeletedAt.After(*movies[j].DeletedAt)
+		}
+		return movies[i].ID < movies[j].ID
+	})
+
+	return movies, nil
+}
+
+func (m *MemoryModel) RestoreMovie(id int, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	movie, ok := m.movies[id]
+	if !ok || movie.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+
+	movie.DeletedAt = nil
+	movie.Version++
+	m.movies[id] = movie
+
+	m.recordRevision(id, RevisionRestore, userID)
+
+	return nil
+}
+
+func (m *MemoryModel) PurgeDeleted(before time.Time) (int, []string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purged := 0
+	var posters []string
+
+	for id, movie := range m.movies {
+		if movie.DeletedAt == nil || !movie.DeletedAt.Before(before) {
+			continue
+		}
+
+		delete(m.movies, id)
+		purged++
+		if movie.Poster != "" {
+			posters = append(posters, movie.Poster)
+		}
+
+		// movies_genresのon delete cascadeに合わせて紐づくgenreも削除する
+		for mgID, mg := range m.movieGenres {
+			if mg.MovieID == id {
+				delete(m.movieGenres, mgID)
+			}
+		}
+		for creditID, c := range m.credits {
+			if c.MovieID == id {
+				delete(m.credits, creditID)
+			}
+		}
+		for reviewID, r := range m.reviews {
+			if r.MovieID == id {
+				delete(m.reviews, reviewID)
+				m.deleteReviewVotes(reviewID)
+			}
+		}
+		for entryID, e := range m.listEntries {
+			if e.MovieID == id {
+				delete(m.listEntries, entryID)
+			}
+		}
+		for suggestionID, s := range m.suggestions {
+			if s.MovieID == id {
+				delete(m.suggestions, suggestionID)
+			}
+		}
+		for key := range m.movieTranslations {
+			if key.ID == id {
+				delete(m.movieTranslations, key)
+			}
+		}
+		m.removeFromCollections(id)
+		m.deleteReleases(id)
+		delete(m.movieTags, id)
+	}
+
+	return purged, posters, nil
+}
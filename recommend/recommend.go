@@ -0,0 +1,232 @@
+package recommend
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Item is what the index knows about one movie
+type Item struct {
+	ID int
+	Year int
+	GenreIDs []int
+	// 監督・出演者などのperson id
+	PersonIDs []int
+	TagIDs []int
+	// TF-IDFで比べる文章(titleとdescription)
+	Text string
+}
+
+// Weights sets how much each signal counts towards the score (the sum should be 1)
+type Weights struct {
+	Genres float64
+	// 共通のcreditとタグ
+	People float64
+	Era float64
+	Text float64
+}
+
+// DefaultWeights is used by NewIndex
+var DefaultWeights = Weights{Genres: 0.35, People: 0.25, Era: 0.1, Text: 0.3}
+
+// 公開年の差がこの年数で類似度が1/eになる
+const eraScale = 10.0
+
+// Match is a similar movie and its score between 0 and 1
+type Match struct {
+	ID int `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// Index holds the precomputed features of every movie
+type Index struct {
+	weights Weights
+	items map[int]*entry
+}
+
+type entry struct {
+	item Item
+	genres map[int]bool
+	// creditは"p", タグは"t"を先頭につけてまとめて比べる
+	people map[string]bool
+	// 正規化したTF-IDFのベクトル
+	vector map[string]float64
+}
+
+// NewIndex computes the TF-IDF vectors of the items with DefaultWeights
+func NewIndex(items []Item) *Index {
+	return NewIndexWithWeights(items, DefaultWeights)
+}
+
+// NewIndexWithWeights computes the TF-IDF vectors of the items
+func NewIndexWithWeights(items []Item, weights Weights) *Index {
+	idx := &Index{weights: weights, items: make(map[int]*entry)}
+
+	// 各語が出てくる文章の数
+	df := make(map[string]int)
+	terms := make(map[int]map[string]int)
+	for _, item := range items {
+		tf := make(map[string]int)
+		for _, t := range Tokenize(item.Text) {
+			tf[t]++
+		}
+		for t := range tf {
+			df[t]++
+		}
+		terms[item.ID] = tf
+	}
+
+	n := float64(len(items))
+	for _, item := range items {
+		e := &entry{
+			item: item,
+			genres: make(map[int]bool),
+			people: make(map[string]bool),
+			vector: make(map[string]float64),
+		}
+		for _, id := range item.GenreIDs {
+			e.genres[id] = true
+		}
+		for _, id := range item.PersonIDs {
+			e.people["p"+strconv.Itoa(id)] = true
+		}
+		for _, id := range item.TagIDs {
+			e.people["t"+strconv.Itoa(id)] = true
+		}
+
+		var norm float64
+		for t, count := range terms[item.ID] {
+			// 1つの文章にしか出てこない語も0にならないように1を足す
+			w := (1 + math.Log(float64(count))) * math.Log(1+n/float64(df[t]))
+			e.vector[t] = w
+			norm += w * w
+		}
+		norm = math.Sqrt(norm)
+		for t := range e.vector {
+			e.vector[t] /= norm
+		}
+
+		idx.items[item.ID] = e
+	}
+
+	return idx
+}
+
+// Len returns the number of indexed movies
+func (idx *Index) Len() int {
+	return len(idx.items)
+}
+
+// Similar returns up to limit movies ranked by similarity to id (nil if id is not indexed)
+func (idx *Index) Similar(id int, limit int) []Match {
+	source, ok := idx.items[id]
+	if !ok {
+		return nil
+	}
+
+	matches := []Match{}
+	for otherID, other := range idx.items {
+		if otherID == id {
+			continue
+		}
+		score := idx.score(source, other)
+		if score > 0 {
+			matches = append(matches, Match{ID: otherID, Score: math.Round(score*1000) / 1000})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches
+}
+
+// 0から1の類似度(公開年だけが近いmovieは0にする)
+func (idx *Index) score(a, b *entry) float64 {
+	genres := jaccardInts(a.genres, b.genres)
+	people := jaccardStrings(a.people, b.people)
+	text := cosine(a.vector, b.vector)
+	if genres == 0 && people == 0 && text == 0 {
+		return 0
+	}
+
+	era := 0.0
+	if a.item.Year > 0 && b.item.Year > 0 {
+		era = math.Exp(-math.Abs(float64(a.item.Year-b.item.Year)) / eraScale)
+	}
+
+	w := idx.weights
+	return w.Genres*genres + w.People*people + w.Era*era + w.Text*text
+}
+
+func jaccardInts(a, b map[int]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func jaccardStrings(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func cosine(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var dot float64
+	for t, w := range a {
+		dot += w * b[t]
+	}
+	return dot
+}
+
+// 類似度に関係しない語
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "he": true, "her": true, "his": true, "in": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "she": true, "that": true, "the": true,
+	"their": true, "they": true, "this": true, "to": true, "was": true, "who": true, "with": true,
+}
+
+// Tokenize lower-cases the text and splits it into words, dropping stop words and single letters
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var tokens []string
+	for _, w := range words {
+		if len([]rune(w)) < 2 || stopWords[w] {
+			continue
+		}
+		tokens = append(tokens, w)
+	}
+	return tokens
+}
@@ -0,0 +1,48 @@
+package recommend
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("The Joker wreaks havoc on Gotham-City, a 2nd time!")
+	want := []string{"joker", "wreaks", "havoc", "gotham", "city", "2nd", "time"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize = %v, want %v", got, want)
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	items := []Item{
+		{ID: 1, Year: 1994, GenreIDs: []int{1, 2}, Text: "Two imprisoned men bond over a number of years"},
+		{ID: 2, Year: 1972, GenreIDs: []int{1, 2}, Text: "An organized crime dynasty"},
+		{ID: 3, Year: 1995, GenreIDs: []int{3}, Text: "Imprisoned men escape"},
+		{ID: 4, Year: 1994, GenreIDs: []int{4}, Text: "A cooking competition"},
+	}
+	idx := NewIndex(items)
+
+	matches := idx.Similar(1, 10)
+	if len(matches) != 2 {
+		t.Fatalf("Similar(1) = %+v, want 2 matches", matches)
+	}
+	// 公開年だけが近いmovie(4)は含めない
+	if matches[0].ID != 2 || matches[1].ID != 3 {
+		t.Errorf("Similar(1) = %+v, want ids 2, 3", matches)
+	}
+	for _, m := range matches {
+		if m.Score <= 0 || m.Score > 1 {
+			t.Errorf("score of %d = %v, want (0, 1]", m.ID, m.Score)
+		}
+	}
+
+	// 文章だけが似ている場合はTF-IDFで決まる
+	text := NewIndexWithWeights(items, Weights{Text: 1})
+	if got := text.Similar(3, 1); len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("text-only Similar(3) = %+v, want id 1", got)
+	}
+
+	if got := idx.Similar(99, 10); got != nil {
+		t.Errorf("Similar of unknown id = %+v, want nil", got)
+	}
+}